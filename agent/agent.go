@@ -35,7 +35,11 @@
 // the optional agent.json data file — no handler code.
 package agent
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/baditaflorin/go-common/openapi"
+)
 
 // Tool is the agent-facing contract for one callable unit of a service.
 // It is intentionally close to the Model Context Protocol (MCP) "tool"
@@ -58,6 +62,7 @@ type Tool struct {
 	// InputSchema is a JSON Schema (draft-07 subset) describing the
 	// parameters an agent must supply. The service's primary target
 	// param (url/target/domain/…) should be marked required.
+	// InputSchemaFor derives it from the Go input type.
 	InputSchema map[string]any `json:"input_schema"`
 	// Auth describes what the gateway needs to authorize the call.
 	Auth Auth `json:"auth"`
@@ -153,6 +158,17 @@ func DefaultTool(service, version string) Tool {
 	}
 }
 
+// InputSchemaFor derives a Tool.InputSchema from the Go type a service
+// binds its input into, so the contract cannot drift from the handler:
+// properties follow the `json` tags and the `validate` tags become
+// required, length, range, enum, pattern and format constraints (see
+// openapi.SchemaOf).
+//
+//	tool.InputSchema = agent.InputSchemaFor[ScanRequest]()
+func InputSchemaFor[T any]() map[string]any {
+	return openapi.SchemaFor[T]().JSONSchema()
+}
+
 // DefaultContract builds a Contract with a single DefaultTool.
 func DefaultContract(service, version string) Contract {
 	return Contract{
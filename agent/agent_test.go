@@ -61,3 +61,44 @@ func TestContractJSONRoundTrips(t *testing.T) {
 		t.Fatalf("round-trip lost data: %+v", got)
 	}
 }
+
+func TestInputSchemaFor(t *testing.T) {
+	type scanRequest struct {
+		Target string  `json:"target" validate:"required,url"`
+		Depth  *int    `json:"depth,omitempty" validate:"min=1,max=5"`
+		Mode   string  `json:"mode" validate:"oneof=fast|full"`
+		Ports  []int32 `json:"ports"`
+	}
+	s := InputSchemaFor[scanRequest]()
+	if s["type"] != "object" {
+		t.Fatalf("type = %v, want object", s["type"])
+	}
+	if req, _ := s["required"].([]any); len(req) != 1 || req[0] != "target" {
+		t.Fatalf("required = %v, want [target]", s["required"])
+	}
+	props := s["properties"].(map[string]any)
+	if target := props["target"].(map[string]any); target["format"] != "uri" {
+		t.Fatalf("target = %v", target)
+	}
+	depth := props["depth"].(map[string]any)
+	if typ, _ := depth["type"].([]any); len(typ) != 2 || typ[1] != "null" || depth["nullable"] != nil {
+		t.Fatalf("depth = %v, want a nullable integer in draft-07 form", depth)
+	}
+	if depth["minimum"] != float64(1) || depth["maximum"] != float64(5) {
+		t.Fatalf("depth bounds = %v", depth)
+	}
+	if enum, _ := props["mode"].(map[string]any)["enum"].([]any); len(enum) != 2 {
+		t.Fatalf("mode = %v", props["mode"])
+	}
+
+	// A generated schema survives the contract's JSON round trip.
+	c := Contract{Tools: []Tool{{Name: "scan", InputSchema: s}}}
+	b, err := c.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromJSON(b)
+	if err != nil || back.Tools[0].InputSchema["properties"].(map[string]any)["ports"] == nil {
+		t.Fatalf("round trip: %v, %v", err, back)
+	}
+}
@@ -25,10 +25,10 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"os"
-	"strings"
 	"sync/atomic"
 	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
 )
 
 const (
@@ -44,6 +44,12 @@ type Client struct {
 	BaseURL string
 	HTTP    *http.Client
 
+	// APIKey and SigningSecret authenticate /backoff the same way every
+	// coordinator client does (see coordauth). Both optional: the public
+	// coordinator accepts unauthenticated consults.
+	APIKey        string
+	SigningSecret string
+
 	// MaxWait caps how long any single Consult call will block on the
 	// coordinator's advice. Defaults to 5s; values <= 0 use the default.
 	MaxWait time.Duration
@@ -54,15 +60,16 @@ type Client struct {
 // New constructs a client from env vars. Safe at package init.
 //
 //	BACKOFF_COORDINATOR_URL  default https://backoff-coordinator.0exec.com
+//	BACKOFF_COORDINATOR_API_KEY  optional; never FLEET_API_KEY
+//	BACKOFF_COORDINATOR_SIGNING_SECRET  optional
 //	BACKOFF_COORDINATOR_MAX_WAIT_MS  optional; default 5000
 func New() *Client {
-	base := os.Getenv("BACKOFF_COORDINATOR_URL")
-	if base == "" {
-		base = DefaultURL
-	}
+	ep := coordauth.FromEnv("BACKOFF_COORDINATOR", DefaultURL)
 	maxWait := defaultMaxWait
 	return &Client{
-		BaseURL: strings.TrimRight(base, "/"),
+		BaseURL:       ep.URL,
+		APIKey:        ep.Key,
+		SigningSecret: ep.Secret,
 		HTTP: &http.Client{
 			Timeout: defaultConnectTimeout + defaultReadTimeout,
 			Transport: &http.Transport{
@@ -149,6 +156,11 @@ func (c *Client) Consult(ctx context.Context, host string, fail Failure) (*Resul
 		return res, nil
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if err := (coordauth.Credentials{Key: c.APIKey, Secret: c.SigningSecret}).Sign(req, body); err != nil {
+		res.FellOpen = true
+		res.Reason = "sign error"
+		return res, nil
+	}
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
 		res.FellOpen = true
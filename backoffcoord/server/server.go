@@ -0,0 +1,206 @@
+// Package server is an in-process implementation of the backoff
+// coordinator that backoffcoord.Client and safehttp's
+// WithBackoffCoordinator consult: the same /backoff and /health
+// endpoints, backed by a per-host failure streak. Mount it inside a
+// service for a small deployment, or start it on an httptest.Server to
+// run real end-to-end tests instead of mocking the consult call:
+//
+//	coord := server.New(server.Options{})
+//	ts := httptest.NewServer(coord)
+//	defer ts.Close()
+//	c := backoffcoord.New()
+//	c.BaseURL = ts.URL
+//
+// Wire protocol:
+//
+//	POST /backoff {"host": "h", "last_response": {"status": 503,
+//	              "retry_after_header": 2, "ts": "<RFC 3339>"}}
+//	              200 {"wait_ms": 2000, "classification": "retry_after"}
+//	              401 {"error": "..."}  bad key or signature (only when
+//	                                    APIKey / SigningSecret is set)
+//	GET  /health  200 {"status": "ok"}
+//
+// Advice, per host:
+//
+//   - a 429 or 503 carrying retry_after_header: wait exactly that long
+//     ("retry_after");
+//   - status 0 (network error) or 5xx: exponential backoff on the
+//     host's failure streak, Base·2^(n-1) capped at Max ("backoff");
+//   - anything else: the host is healthy, its streak resets, wait 0
+//     ("healthy").
+//
+// A streak also resets when the host has reported no failure for
+// ResetAfter.
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
+)
+
+// Options configures a Server. Zero values fall back to the defaults
+// noted on each field.
+type Options struct {
+	// Base is the wait after the first failure. Default 250ms.
+	Base time.Duration
+	// Max caps both the exponential wait and a Retry-After. Default 30s.
+	Max time.Duration
+	// ResetAfter forgets a host's streak after this long without a
+	// failure. Default 1m.
+	ResetAfter time.Duration
+	// APIKey and SigningSecret, when set, require a matching X-API-Key
+	// and a valid coordauth signature on /backoff. safehttp's
+	// WithBackoffCoordinator sends neither, so leave both empty for a
+	// coordinator it consults.
+	APIKey        string
+	SigningSecret string
+}
+
+// Server is the in-process coordinator. It is an http.Handler; the
+// zero value is not usable — construct with New.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+	auth *coordauth.Verifier
+	now  func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	calls map[string]int
+}
+
+type hostState struct {
+	streak   int
+	lastFail time.Time
+}
+
+// New constructs a Server.
+func New(opts Options) *Server {
+	if opts.Base <= 0 {
+		opts.Base = 250 * time.Millisecond
+	}
+	if opts.Max <= 0 {
+		opts.Max = 30 * time.Second
+	}
+	if opts.ResetAfter <= 0 {
+		opts.ResetAfter = time.Minute
+	}
+	s := &Server{
+		opts:  opts,
+		mux:   http.NewServeMux(),
+		auth:  coordauth.NewVerifier(opts.APIKey, opts.SigningSecret),
+		now:   time.Now,
+		hosts: map[string]*hostState{},
+		calls: map[string]int{},
+	}
+	s.mux.HandleFunc("POST /backoff", s.handleBackoff)
+	s.mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+// Consults returns how many /backoff calls host has made.
+func (s *Server) Consults(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[host]
+}
+
+// Streak returns host's current consecutive-failure count.
+func (s *Server) Streak(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h := s.hosts[host]; h != nil {
+		return h.streak
+	}
+	return 0
+}
+
+// Reset forgets every host.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.hosts = map[string]*hostState{}
+	s.calls = map[string]int{}
+	s.mu.Unlock()
+}
+
+type backoffRequest struct {
+	Host         string `json:"host"`
+	LastResponse struct {
+		Status           int `json:"status"`
+		RetryAfterHeader int `json:"retry_after_header"`
+	} `json:"last_response"`
+}
+
+type backoffResponse struct {
+	WaitMS         int64  `json:"wait_ms"`
+	Classification string `json:"classification"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (s *Server) handleBackoff(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<14))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, backoffResponse{Error: "body too large"})
+		return
+	}
+	if err := s.auth.Verify(r, body); err != nil {
+		writeJSON(w, http.StatusUnauthorized, backoffResponse{Error: err.Error()})
+		return
+	}
+	var req backoffRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Host == "" {
+		writeJSON(w, http.StatusBadRequest, backoffResponse{Error: "body must be {host, last_response}"})
+		return
+	}
+	wait, class := s.advise(req.Host, req.LastResponse.Status, req.LastResponse.RetryAfterHeader)
+	writeJSON(w, http.StatusOK, backoffResponse{WaitMS: wait.Milliseconds(), Classification: class})
+}
+
+// advise updates host's streak with one reported response and returns
+// the wait and its classification.
+func (s *Server) advise(host string, status, retryAfterSec int) (time.Duration, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[host]++
+	now := s.now()
+	h := s.hosts[host]
+	if h == nil {
+		h = &hostState{}
+		s.hosts[host] = h
+	}
+	if h.streak > 0 && now.Sub(h.lastFail) > s.opts.ResetAfter {
+		h.streak = 0
+	}
+
+	failed := status == 0 || status >= 500 || status == http.StatusTooManyRequests
+	if !failed {
+		h.streak = 0
+		return 0, "healthy"
+	}
+	h.streak++
+	h.lastFail = now
+	if retryAfterSec > 0 && (status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable) {
+		return min(time.Duration(retryAfterSec)*time.Second, s.opts.Max), "retry_after"
+	}
+	wait := s.opts.Base
+	for i := 1; i < h.streak && wait < s.opts.Max; i++ {
+		wait *= 2
+	}
+	return min(wait, s.opts.Max), "backoff"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,114 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/backoffcoord"
+	"github.com/baditaflorin/go-common/backoffcoord/server"
+	"github.com/baditaflorin/go-common/safehttp"
+)
+
+func startCoordinator(t *testing.T, opts server.Options) (*server.Server, *backoffcoord.Client) {
+	t.Helper()
+	coord := server.New(opts)
+	ts := httptest.NewServer(coord)
+	t.Cleanup(ts.Close)
+	c := backoffcoord.New()
+	c.BaseURL = ts.URL
+	return coord, c
+}
+
+func TestClientAgainstServer_ExponentialStreak(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{Base: 100 * time.Millisecond, Max: 350 * time.Millisecond})
+	ctx := context.Background()
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 350 * time.Millisecond}
+	for i, w := range want {
+		res, err := c.Consult(ctx, "flaky.example", backoffcoord.Failure{Status: 502, At: time.Now()})
+		if err != nil || res.FellOpen {
+			t.Fatalf("consult %d: res=%+v err=%v", i, res, err)
+		}
+		if res.Wait != w {
+			t.Fatalf("consult %d: wait = %v, want %v", i, res.Wait, w)
+		}
+	}
+	if coord.Streak("flaky.example") != 3 {
+		t.Fatalf("streak = %d, want 3", coord.Streak("flaky.example"))
+	}
+
+	res, _ := c.Consult(ctx, "flaky.example", backoffcoord.Failure{Status: 200, At: time.Now()})
+	if res.Wait != 0 || coord.Streak("flaky.example") != 0 {
+		t.Fatalf("a healthy response must reset the streak: wait=%v streak=%d", res.Wait, coord.Streak("flaky.example"))
+	}
+}
+
+func TestClientAgainstServer_RetryAfterWins(t *testing.T) {
+	_, c := startCoordinator(t, server.Options{})
+	c.MaxWait = 10 * time.Second
+	res, err := c.Consult(context.Background(), "busy.example", backoffcoord.Failure{Status: 429, RetryAfterSec: 2, At: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Wait != 2*time.Second {
+		t.Fatalf("wait = %v, want the upstream's 2s Retry-After", res.Wait)
+	}
+}
+
+func TestClientAgainstServer_Signed(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{APIKey: "k", SigningSecret: "s3cret"})
+	fail := backoffcoord.Failure{Status: 502, At: time.Now()}
+
+	c.APIKey, c.SigningSecret = "", ""
+	if _, err := c.Consult(context.Background(), "signed.example", fail); err != nil {
+		t.Fatal(err)
+	}
+	if coord.Consults("signed.example") != 0 {
+		t.Fatal("an unsigned consult must be rejected before it counts")
+	}
+
+	c.APIKey, c.SigningSecret = "k", "s3cret"
+	res, err := c.Consult(context.Background(), "signed.example", fail)
+	if err != nil || res.Wait == 0 {
+		t.Fatalf("signed consult: res=%+v err=%v", res, err)
+	}
+	if coord.Consults("signed.example") != 1 {
+		t.Fatalf("consults = %d, want 1", coord.Consults("signed.example"))
+	}
+}
+
+func TestSafehttpAgainstServer(t *testing.T) {
+	safehttp.SetAllowedPrivateIPs([]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")})
+	t.Cleanup(func() { safehttp.SetAllowedPrivateIPs(nil) })
+
+	coord := server.New(server.Options{Base: time.Millisecond})
+	ts := httptest.NewServer(coord)
+	defer ts.Close()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+	host, _, _ := net.SplitHostPort(upstream.Listener.Addr().String())
+
+	c := safehttp.NewClient(
+		safehttp.WithUserAgent("e2e/1.0"),
+		safehttp.WithBackoffCoordinator(ts.URL),
+		safehttp.WithoutFetchCache(),
+	)
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(upstream.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	// The first call has no failure to report; the second consults.
+	if got := coord.Consults(host); got != 1 {
+		t.Fatalf("coordinator consults for %s = %d, want 1", host, got)
+	}
+}
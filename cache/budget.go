@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// countBound returns the entry-count limit, or 0 for none. A cost
+// budget with maxSize <= 0 means "bounded by cost only"; without a
+// cost budget maxSize is always enforced (as it always was).
+func (c *Cache[K, V]) countBound() (int, bool) {
+	if c.maxCost > 0 && c.maxSize <= 0 {
+		return 0, false
+	}
+	return max(c.maxSize, 0), true
+}
+
+// overBudget reports whether the cache as a whole exceeds its count or
+// cost bound. Must be called with c.mu held.
+func (c *Cache[K, V]) overBudget() bool {
+	if n, ok := c.countBound(); ok && c.lru.Len()+c.window.Len() > n {
+		return true
+	}
+	return c.maxCost > 0 && c.cost > c.maxCost
+}
+
+// windowOver reports whether the W-TinyLFU window exceeds its 1% share
+// of the budget. The window always holds at least one entry so a cache
+// smaller than 100 still admits through it. Must be called with c.mu
+// held.
+func (c *Cache[K, V]) windowOver() bool {
+	if c.window.Len() <= 1 {
+		return false
+	}
+	if n, ok := c.countBound(); ok && c.window.Len() > max(n/100, 1) {
+		return true
+	}
+	return c.maxCost > 0 && c.windowCost > c.maxCost/100
+}
+
+// enforceBudget evicts until the cache fits its bounds after a store.
+// Must be called with c.mu held.
+//
+// Plain LRU: drop main-segment tails. W-TinyLFU: entries overflowing
+// the window become admission candidates against the main segment's
+// LRU victim; the one the sketch has seen less often is evicted.
+func (c *Cache[K, V]) enforceBudget() {
+	if c.sketch != nil {
+		for c.windowOver() {
+			cand := c.promote(c.window.Back())
+			candFreq := c.sketch.estimate(c.hash(cand.Value.(*item[K, V]).key))
+			for c.overBudget() {
+				victim := c.lru.Back()
+				if victim == cand || candFreq <= c.sketch.estimate(c.hash(victim.Value.(*item[K, V]).key)) {
+					c.evictWithReason(cand, EvictRejected)
+					break
+				}
+				c.evictWithReason(victim, EvictCapacity)
+			}
+		}
+	}
+	for c.overBudget() {
+		tail := c.lru.Back()
+		if tail == nil {
+			tail = c.window.Back()
+		}
+		if tail == nil {
+			return
+		}
+		c.evictWithReason(tail, EvictCapacity)
+	}
+}
+
+// promote moves a window entry to the front of the main segment and
+// returns its new element (el is dead afterwards). Must be called with
+// c.mu held.
+func (c *Cache[K, V]) promote(el *list.Element) *list.Element {
+	it := c.window.Remove(el).(*item[K, V])
+	c.windowCost -= it.cost
+	it.inWindow = false
+	nel := c.lru.PushFront(it)
+	c.items[it.key] = nel
+	return nel
+}
+
+// hash maps key into the sketch's hash space.
+func (c *Cache[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(c.sketch.seed, key)
+}
+
+// touchSketch records one access of key. No-op without TinyLFU. Must
+// be called with c.mu held.
+func (c *Cache[K, V]) touchSketch(key K) {
+	if c.sketch != nil {
+		c.sketch.increment(c.hash(key))
+	}
+}
+
+// sketch is a count-min frequency estimator with 4 rows of saturating
+// 4-bit-range counters, a doorkeeper bloom filter, and periodic
+// halving ("reset"), as in the TinyLFU paper. The doorkeeper absorbs
+// the first sighting of every key, so a scan of one-off keys never
+// touches the counters; halving ages out keys that were hot an hour
+// ago so the cache adapts when the working set moves.
+type sketch struct {
+	seed      maphash.Seed
+	rows      [4][]uint8
+	mask      uint64
+	door      []uint64
+	doorMask  uint64
+	additions int
+	resetAt   int
+}
+
+// sketchMaxCount is the counter ceiling (4-bit range).
+const sketchMaxCount = 15
+
+// init sizes the sketch for roughly capacity distinct hot keys.
+// capacity 0 (cost-only caches) falls back to a 4096-wide sketch.
+func (s *sketch) init(capacity int, seed maphash.Seed) {
+	if capacity <= 0 {
+		capacity = 4096
+	}
+	width := 64
+	for width < capacity {
+		width <<= 1
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	s.seed = seed
+	s.mask = uint64(width - 1)
+	s.resetAt = 10 * width
+	// ~10 bits per key seen between resets keeps the doorkeeper's
+	// false-positive rate near 1% with 4 probes.
+	doorBits := 64
+	for doorBits < 10*s.resetAt {
+		doorBits <<= 1
+	}
+	s.door = make([]uint64, doorBits/64)
+	s.doorMask = uint64(doorBits - 1)
+}
+
+// index derives probe i's slot from h by double hashing.
+func index(h uint64, i int, mask uint64) uint64 {
+	lo, hi := h&0xffffffff, h>>32
+	return (lo + uint64(i)*hi) & mask
+}
+
+// admitDoor records h in the doorkeeper. Returns true if h was already
+// there (i.e. this is at least the second sighting).
+func (s *sketch) admitDoor(h uint64) bool {
+	seen := true
+	for i := 0; i < 4; i++ {
+		bit := index(h, i+4, s.doorMask)
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if s.door[word]&mask == 0 {
+			seen = false
+			s.door[word] |= mask
+		}
+	}
+	return seen
+}
+
+func (s *sketch) inDoor(h uint64) bool {
+	for i := 0; i < 4; i++ {
+		bit := index(h, i+4, s.doorMask)
+		if s.door[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sketch) increment(h uint64) {
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+	if !s.admitDoor(h) {
+		return
+	}
+	for i := range s.rows {
+		idx := index(h, i, s.mask)
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+}
+
+// reset halves every counter and clears the doorkeeper.
+func (s *sketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	clear(s.door)
+	s.additions /= 2
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+	m := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if v := s.rows[i][index(h, i, s.mask)]; v < m {
+			m = v
+		}
+	}
+	if m < sketchMaxCount && s.inDoor(h) {
+		m++
+	}
+	return m
+}
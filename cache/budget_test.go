@@ -0,0 +1,135 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/cache"
+)
+
+func byteCost(b []byte) int64 { return int64(len(b)) }
+
+func TestMaxCostEvictsLRU(t *testing.T) {
+	c := cache.New[string, []byte](0, time.Minute,
+		cache.WithClock[string, []byte](newMock()),
+		cache.WithMaxCost[string, []byte](100, byteCost),
+	)
+	c.Set("a", make([]byte, 40))
+	c.Set("b", make([]byte, 40))
+	c.Get("a") // b is now LRU
+	c.Set("c", make([]byte, 40))
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted to fit the cost budget")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a was recently used and should survive")
+	}
+	if got := c.Cost(); got != 80 {
+		t.Fatalf("Cost() = %d, want 80", got)
+	}
+	if got := c.Evictions(); got != 1 {
+		t.Fatalf("Evictions() = %d, want 1", got)
+	}
+}
+
+func TestMaxCostRejectsOversizedValue(t *testing.T) {
+	c := cache.New[string, []byte](0, time.Minute, cache.WithMaxCost[string, []byte](100, byteCost))
+	c.Set("k", make([]byte, 10))
+	c.Set("k", make([]byte, 500))
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("oversized replacement must not be stored, nor leave the old value behind")
+	}
+	if c.Cost() != 0 || c.Len() != 0 {
+		t.Fatalf("cost=%d len=%d after rejection, want 0/0", c.Cost(), c.Len())
+	}
+}
+
+func TestMaxCostTracksOverwrite(t *testing.T) {
+	c := cache.New[string, []byte](0, time.Minute, cache.WithMaxCost[string, []byte](100, byteCost))
+	c.Set("k", make([]byte, 10))
+	c.Set("k", make([]byte, 30))
+	c.Delete("k")
+	if got := c.Cost(); got != 0 {
+		t.Fatalf("Cost() after overwrite+delete = %d, want 0", got)
+	}
+}
+
+func TestCountAndCostBothApply(t *testing.T) {
+	c := cache.New[int, []byte](2, time.Minute, cache.WithMaxCost[int, []byte](1000, byteCost))
+	for i := 0; i < 5; i++ {
+		c.Set(i, []byte{1})
+	}
+	if c.Len() != 2 {
+		t.Fatalf("count bound ignored with a cost budget: len=%d", c.Len())
+	}
+}
+
+func TestTinyLFUProtectsHotSetFromScan(t *testing.T) {
+	const size = 100
+	c := cache.New[string, int](size, time.Minute, cache.WithTinyLFU[string, int]())
+
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprintf("hot-%d", i)
+		c.Set(hot[i], i)
+	}
+	// Build up frequency for the hot set.
+	for round := 0; round < 5; round++ {
+		for _, k := range hot {
+			c.Get(k)
+		}
+	}
+	// A one-off scan of many cold keys.
+	for i := 0; i < 10*size; i++ {
+		c.Set(fmt.Sprintf("scan-%d", i), i)
+	}
+
+	survivors := 0
+	for _, k := range hot {
+		if _, ok := c.Get(k); ok {
+			survivors++
+		}
+	}
+	if survivors < len(hot)*9/10 {
+		t.Fatalf("scan flushed the hot set: %d/%d hot keys survived", survivors, len(hot))
+	}
+	if c.Len() > size {
+		t.Fatalf("len %d exceeds size %d", c.Len(), size)
+	}
+}
+
+func TestLRUWithoutTinyLFUIsFlushedByScan(t *testing.T) {
+	// Control for the test above: plain LRU loses the hot set.
+	const size = 100
+	c := cache.New[string, int](size, time.Minute)
+	for i := 0; i < 50; i++ {
+		c.Set(fmt.Sprintf("hot-%d", i), i)
+	}
+	for i := 0; i < 10*size; i++ {
+		c.Set(fmt.Sprintf("scan-%d", i), i)
+	}
+	if _, ok := c.Get("hot-0"); ok {
+		t.Fatal("plain LRU should have evicted hot-0 during the scan")
+	}
+}
+
+func TestTinyLFUAdmitsNewHotKey(t *testing.T) {
+	c := cache.New[string, int](10, time.Minute, cache.WithTinyLFU[string, int]())
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("k-%d", i), i)
+	}
+	// A key that keeps being requested must eventually get in.
+	for i := 0; i < 20; i++ {
+		if _, ok := c.Get("newcomer"); !ok {
+			c.Set("newcomer", 1)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprintf("cold-%d", i), i)
+	}
+	if _, ok := c.Get("newcomer"); !ok {
+		t.Fatal("frequently requested key was never admitted")
+	}
+}
@@ -9,11 +9,23 @@
 //   - Generics: New[K, V] works with any comparable key and any value.
 //   - LRU eviction: when maxSize is exceeded, the least-recently-used
 //     entry is evicted.
+//   - Optional cost budget (WithMaxCost): entries are weighed by a
+//     caller-supplied cost function (typically bytes) and evicted LRU
+//     until the total fits, so caching bodies cannot outgrow the
+//     container memory limit runtimetune tunes GC against.
+//   - Optional W-TinyLFU admission (WithTinyLFU): a one-off scan of
+//     cold keys cannot flush the hot set.
 //   - Per-entry TTL: entries older than ttl are treated as misses and
 //     evicted lazily on next access.
 //   - Optional stale-while-revalidate window (WithStaleWindow): entries
 //     past their TTL but within stale window are returned with a
 //     Stale=true flag so callers can serve them while refreshing.
+//   - Read-through loading (GetOrLoad): concurrent misses for one key
+//     share a single loader call; stale entries are served while one
+//     background refresh runs; loader errors may be cached for their
+//     own TTL (WithNegativeTTL).
+//   - Observable: hit/miss/load/evict events go to the process-wide
+//     Observer (see observer.go), wired to Prometheus by promx.AutoWire.
 //   - Clock-injectable: pass a clock.Clock for deterministic tests.
 //
 // Usage:
@@ -27,10 +39,16 @@
 //	    cache.WithStaleWindow[string, *MyVal](15*time.Minute))
 //	entry, ok := c2.GetEntry("key")
 //	if ok && entry.Stale { /* serve stale, trigger background refresh */ }
+//
+//	// read-through (stale refresh handled for you):
+//	v, err := c2.GetOrLoad(ctx, "key", func(ctx context.Context, k string) (*MyVal, error) {
+//	    return fetch(ctx, k)
+//	})
 package cache
 
 import (
 	"container/list"
+	"hash/maphash"
 	"sync"
 	"time"
 
@@ -48,19 +66,45 @@ type Entry[V any] struct {
 // Cache is a generic LRU+TTL cache.
 type Cache[K comparable, V any] struct {
 	mu          sync.Mutex
+	name        string
 	maxSize     int
 	ttl         time.Duration
 	staleWindow time.Duration
+	negTTL      time.Duration
+	negCache    func(error) bool
 	clk         clock.Clock
 
+	// Cost budget (WithMaxCost). maxCost == 0 disables cost tracking.
+	costFn  func(V) int64
+	maxCost int64
+	cost    int64
+
+	// W-TinyLFU (WithTinyLFU). sketch == nil disables admission and
+	// window is unused.
+	sketch     *sketch
+	window     *list.List
+	windowCost int64
+
+	evictions uint64
+
 	items map[K]*list.Element
-	lru   *list.List
+	lru   *list.List // main segment; the only segment without TinyLFU
+
+	loadMu   sync.Mutex
+	inflight map[K]*loadCall[V]
 }
 
 type item[K comparable, V any] struct {
 	key     K
 	value   V
 	expires time.Time
+	cost    int64
+	// inWindow marks an entry in the W-TinyLFU admission window rather
+	// than the main LRU.
+	inWindow bool
+	// err is non-nil for a negative entry cached by GetOrLoad. Negative
+	// entries are invisible to Get/GetEntry and never served stale.
+	err error
 }
 
 // Option configures a Cache at construction time.
@@ -79,19 +123,79 @@ func WithStaleWindow[K comparable, V any](d time.Duration) Option[K, V] {
 	return func(cache *Cache[K, V]) { cache.staleWindow = d }
 }
 
+// WithName labels the cache in observer events ("cache" label in
+// metrics). Unnamed caches report as "_unknown".
+func WithName[K comparable, V any](name string) Option[K, V] {
+	return func(cache *Cache[K, V]) { cache.name = name }
+}
+
+// WithNegativeTTL makes GetOrLoad cache loader errors for d, so a key
+// that is genuinely absent upstream is not re-fetched on every call.
+// cacheable selects which errors are remembered; nil caches every
+// error except context cancellation and deadline expiry (which say
+// nothing about the key). Keep d short — a cached transient error is
+// an outage you extended yourself.
+func WithNegativeTTL[K comparable, V any](d time.Duration, cacheable func(error) bool) Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.negTTL = d
+		cache.negCache = cacheable
+	}
+}
+
+// WithMaxCost bounds the cache by total cost instead of (or as well
+// as) entry count. cost weighs one value — usually its size in bytes,
+// e.g. func(b []byte) int64 { return int64(len(b)) } — and is called
+// once per Set. LRU entries are evicted until the total is at most
+// maxCost; a single value costing more than maxCost is not stored.
+//
+// With a cost budget, New's maxSize may be 0 to mean "no count bound".
+// A sensible budget is a fraction of the container limit runtimetune
+// reports (runtimetune.Applied), never the whole of it.
+func WithMaxCost[K comparable, V any](maxCost int64, cost func(V) int64) Option[K, V] {
+	return func(cache *Cache[K, V]) {
+		cache.maxCost = maxCost
+		cache.costFn = cost
+	}
+}
+
+// WithTinyLFU enables W-TinyLFU admission. New entries land in a small
+// LRU window (1% of the budget); an entry leaving the window only
+// enters the main LRU if a frequency sketch says it is accessed more
+// often than the main segment's eviction victim. A burst of one-off
+// keys therefore churns through the window instead of evicting the
+// hot set. Costs a frequency sketch of roughly 16 bytes per slot and
+// a hash per access.
+func WithTinyLFU[K comparable, V any]() Option[K, V] {
+	return func(cache *Cache[K, V]) { cache.sketch = &sketch{} }
+}
+
 // New creates a new Cache with the given max size and TTL.
-// maxSize must be > 0; ttl must be > 0.
+// maxSize must be > 0 unless WithMaxCost is set; ttl must be > 0.
 func New[K comparable, V any](maxSize int, ttl time.Duration, opts ...Option[K, V]) *Cache[K, V] {
 	c := &Cache[K, V]{
-		maxSize: maxSize,
-		ttl:     ttl,
-		items:   make(map[K]*list.Element, maxSize),
-		lru:     list.New(),
-		clk:     clock.Real(),
+		maxSize:  maxSize,
+		ttl:      ttl,
+		items:    make(map[K]*list.Element, max(maxSize, 0)),
+		lru:      list.New(),
+		window:   list.New(),
+		clk:      clock.Real(),
+		inflight: make(map[K]*loadCall[V]),
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.costFn == nil || c.maxCost <= 0 {
+		c.costFn, c.maxCost = nil, 0
+	}
+	if c.sketch != nil {
+		c.sketch.init(max(c.maxSize, 0), maphash.MakeSeed())
+	}
+	if c.name == "" {
+		c.name = "_unknown"
+	}
+	if c.negCache == nil {
+		c.negCache = defaultNegativeCacheable
+	}
 	return c
 }
 
@@ -102,20 +206,49 @@ func New[K comparable, V any](maxSize int, ttl time.Duration, opts ...Option[K,
 func (c *Cache[K, V]) Set(key K, value V) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	now := c.clk.Now()
-	expires := now.Add(c.ttl)
+	c.touchSketch(key)
+	c.storeLocked(key, value, nil, c.clk.Now().Add(c.ttl))
+}
 
+// storeLocked inserts or refreshes key. err != nil stores a negative
+// entry. Must be called with c.mu held.
+func (c *Cache[K, V]) storeLocked(key K, value V, err error, expires time.Time) {
+	var cost int64
+	if c.costFn != nil && err == nil {
+		cost = c.costFn(value)
+		if cost > c.maxCost {
+			// Can never fit. Drop any previous value for key too — a
+			// caller that just replaced it must not keep reading the
+			// old one.
+			if el, ok := c.items[key]; ok {
+				c.evict(el)
+			}
+			c.countEviction(EvictRejected)
+			return
+		}
+	}
 	if el, ok := c.items[key]; ok {
-		c.lru.MoveToFront(el)
-		el.Value.(*item[K, V]).value = value
-		el.Value.(*item[K, V]).expires = expires
+		it := el.Value.(*item[K, V])
+		c.adjustCost(it, cost-it.cost)
+		it.value = value
+		it.err = err
+		it.expires = expires
+		it.cost = cost
+		c.moveToFront(el)
+		c.enforceBudget()
 		return
 	}
-	if c.lru.Len() >= c.maxSize {
-		c.evictOldest()
+	it := &item[K, V]{key: key, value: value, err: err, expires: expires, cost: cost}
+	var el *list.Element
+	if c.sketch != nil {
+		it.inWindow = true
+		el = c.window.PushFront(it)
+	} else {
+		el = c.lru.PushFront(it)
 	}
-	el := c.lru.PushFront(&item[K, V]{key: key, value: value, expires: expires})
 	c.items[key] = el
+	c.adjustCost(it, cost)
+	c.enforceBudget()
 }
 
 // Get retrieves the value for key. Returns the value and true if the
@@ -136,28 +269,61 @@ func (c *Cache[K, V]) Get(key K) (V, bool) {
 func (c *Cache[K, V]) GetEntry(key K) (Entry[V], bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	it, res := c.lookupLocked(key)
+	switch res {
+	case lookupFresh:
+		c.emit(Event{Phase: PhaseHit})
+		return Entry[V]{Value: it.value, Expires: it.expires}, true
+	case lookupStale:
+		c.emit(Event{Phase: PhaseStaleHit})
+		return Entry[V]{Value: it.value, Stale: true, Expires: it.expires}, true
+	default:
+		// Negative entries only mean something to GetOrLoad.
+		c.emit(Event{Phase: PhaseMiss})
+		return Entry[V]{}, false
+	}
+}
+
+// lookupResult classifies an entry for the read paths.
+type lookupResult int
+
+const (
+	lookupMiss lookupResult = iota
+	lookupFresh
+	lookupStale
+	lookupNegative
+)
+
+// lookupLocked finds key, touching it in the LRU on any hit and
+// hard-evicting it once past TTL + stale window (negative entries
+// have no stale window). Must be called with c.mu held.
+func (c *Cache[K, V]) lookupLocked(key K) (*item[K, V], lookupResult) {
+	c.touchSketch(key)
 	el, ok := c.items[key]
 	if !ok {
-		return Entry[V]{}, false
+		return nil, lookupMiss
 	}
 	it := el.Value.(*item[K, V])
 	now := c.clk.Now()
 
 	if now.Before(it.expires) {
 		// Fresh hit.
-		c.lru.MoveToFront(el)
-		return Entry[V]{Value: it.value, Expires: it.expires}, true
+		c.moveToFront(el)
+		if it.err != nil {
+			return it, lookupNegative
+		}
+		return it, lookupFresh
 	}
 
 	// Expired. Check stale window.
-	if c.staleWindow > 0 && now.Before(it.expires.Add(c.staleWindow)) {
-		c.lru.MoveToFront(el)
-		return Entry[V]{Value: it.value, Stale: true, Expires: it.expires}, true
+	if it.err == nil && c.staleWindow > 0 && now.Before(it.expires.Add(c.staleWindow)) {
+		c.moveToFront(el)
+		return it, lookupStale
 	}
 
 	// Beyond stale window — hard evict.
-	c.evict(el)
-	return Entry[V]{}, false
+	c.evictWithReason(el, EvictExpired)
+	return nil, lookupMiss
 }
 
 // Delete removes key from the cache. No-op if absent.
@@ -174,26 +340,79 @@ func (c *Cache[K, V]) Delete(key K) {
 func (c *Cache[K, V]) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.lru.Len()
+	return c.lru.Len() + c.window.Len()
+}
+
+// Cost returns the summed cost of all entries. Always 0 without
+// WithMaxCost.
+func (c *Cache[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cost
+}
+
+// Evictions returns the number of entries dropped by the cache itself
+// since construction: capacity and cost evictions, TTL expiry, and
+// entries refused admission (TinyLFU or over budget). Explicit Delete
+// and Purge are not counted.
+func (c *Cache[K, V]) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
 }
 
 // Purge removes all entries.
 func (c *Cache[K, V]) Purge() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.items = make(map[K]*list.Element, c.maxSize)
+	c.items = make(map[K]*list.Element, max(c.maxSize, 0))
 	c.lru.Init()
+	c.window.Init()
+	c.cost, c.windowCost = 0, 0
 }
 
-// evict removes el from both the map and LRU list. Must be called with c.mu held.
+// evict removes el from the map and its LRU segment. Must be called
+// with c.mu held.
 func (c *Cache[K, V]) evict(el *list.Element) {
-	it := c.lru.Remove(el).(*item[K, V])
+	it := el.Value.(*item[K, V])
+	if it.inWindow {
+		c.window.Remove(el)
+	} else {
+		c.lru.Remove(el)
+	}
+	c.adjustCost(it, -it.cost)
 	delete(c.items, it.key)
 }
 
-// evictOldest removes the tail element (LRU). Must be called with c.mu held.
-func (c *Cache[K, V]) evictOldest() {
-	if tail := c.lru.Back(); tail != nil {
-		c.evict(tail)
+// evictWithReason evicts el and records the eviction. Must be called
+// with c.mu held.
+func (c *Cache[K, V]) evictWithReason(el *list.Element, reason string) {
+	c.evict(el)
+	c.countEviction(reason)
+}
+
+// countEviction bumps the eviction counter and notifies the observer.
+// Must be called with c.mu held.
+func (c *Cache[K, V]) countEviction(reason string) {
+	c.evictions++
+	c.emit(Event{Phase: PhaseEvict, Reason: reason})
+}
+
+// moveToFront marks el most-recently-used within its segment. Must be
+// called with c.mu held.
+func (c *Cache[K, V]) moveToFront(el *list.Element) {
+	if el.Value.(*item[K, V]).inWindow {
+		c.window.MoveToFront(el)
+	} else {
+		c.lru.MoveToFront(el)
+	}
+}
+
+// adjustCost applies delta to the cost totals for it's segment. Must
+// be called with c.mu held.
+func (c *Cache[K, V]) adjustCost(it *item[K, V], delta int64) {
+	c.cost += delta
+	if it.inWindow {
+		c.windowCost += delta
 	}
 }
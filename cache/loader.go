@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// Loader fetches the value for key on a cache miss. It receives a
+// context detached from any single caller's cancellation (several
+// callers may be waiting on one load), so it should apply its own
+// timeout.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// PanicError is a loader panic recovered by GetOrLoad and returned to
+// every caller waiting on that load. Value is what was passed to panic;
+// Stack is the loader goroutine's stack.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cache: loader panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap exposes the panic value when it is itself an error, so
+// errors.Is / errors.As see through a panic(err).
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// loadCall is one in-flight loader invocation shared by every caller
+// that missed on the same key.
+type loadCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss
+// and storing the result.
+//
+//   - Fresh hit: returned without calling load.
+//   - Stale hit (inside WithStaleWindow): the stale value is returned
+//     immediately and one background refresh is started; a failed
+//     refresh keeps serving stale until the window closes.
+//   - Negative hit (WithNegativeTTL): the cached loader error is
+//     returned without calling load.
+//   - Miss: concurrent callers for the same key share one load call.
+//     Each caller stops waiting when its own ctx is done; the load
+//     itself keeps running and still populates the cache.
+//
+// A panicking loader does not crash the process: it runs on a goroutine
+// the cache owns, out of reach of any caller's recover, so the panic is
+// returned to the waiting callers as a *PanicError instead.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load Loader[K, V]) (V, error) {
+	c.mu.Lock()
+	it, res := c.lookupLocked(key)
+	switch res {
+	case lookupFresh:
+		v := it.value
+		c.emit(Event{Phase: PhaseHit})
+		c.mu.Unlock()
+		return v, nil
+	case lookupStale:
+		v := it.value
+		c.emit(Event{Phase: PhaseStaleHit})
+		c.mu.Unlock()
+		c.startLoad(ctx, key, load, true)
+		return v, nil
+	case lookupNegative:
+		err := it.err
+		c.emit(Event{Phase: PhaseNegativeHit})
+		c.mu.Unlock()
+		var zero V
+		return zero, err
+	}
+	c.emit(Event{Phase: PhaseMiss})
+	c.mu.Unlock()
+
+	call := c.startLoad(ctx, key, load, false)
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// startLoad joins the in-flight load for key or starts a new one.
+// refresh marks a stale-entry background refresh, whose errors are
+// never cached (the stale value is still better than an error).
+func (c *Cache[K, V]) startLoad(ctx context.Context, key K, load Loader[K, V], refresh bool) *loadCall[V] {
+	c.loadMu.Lock()
+	if call, ok := c.inflight[key]; ok {
+		c.loadMu.Unlock()
+		return call
+	}
+	call := &loadCall[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.loadMu.Unlock()
+
+	go func() {
+		start := c.clk.Now()
+		call.val, call.err = runLoader(context.WithoutCancel(ctx), key, load)
+		d := c.clk.Since(start)
+
+		c.mu.Lock()
+		switch {
+		case call.err == nil:
+			c.storeLocked(key, call.val, nil, c.clk.Now().Add(c.ttl))
+			c.emit(Event{Phase: PhaseLoad, Duration: d})
+		case !refresh && c.negTTL > 0 && c.negCache(call.err):
+			var zero V
+			c.storeLocked(key, zero, call.err, c.clk.Now().Add(c.negTTL))
+			c.emit(Event{Phase: PhaseLoadError, Duration: d})
+		default:
+			c.emit(Event{Phase: PhaseLoadError, Duration: d})
+		}
+		c.mu.Unlock()
+
+		c.loadMu.Lock()
+		delete(c.inflight, key)
+		c.loadMu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// runLoader calls load, recovering a panic into a *PanicError.
+func runLoader[K comparable, V any](ctx context.Context, key K, load Loader[K, V]) (v V, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			var zero V
+			v, err = zero, &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+	return load(ctx, key)
+}
+
+// defaultNegativeCacheable caches every loader error except a caller
+// giving up, which says nothing about the key itself.
+func defaultNegativeCacheable(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,248 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/cache"
+)
+
+func TestGetOrLoadCoalescesConcurrentMisses(t *testing.T) {
+	c := cache.New[string, int](10, time.Minute, cache.WithClock[string, int](newMock()))
+	var calls int32
+	release := make(chan struct{})
+	load := func(_ context.Context, k string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return len(k), nil
+	}
+
+	const n = 16
+	var wg sync.WaitGroup
+	results := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "abc", load)
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader called %d times, want 1", got)
+	}
+	for i, v := range results {
+		if v != 3 {
+			t.Fatalf("caller %d got %d, want 3", i, v)
+		}
+	}
+	if v, ok := c.Get("abc"); !ok || v != 3 {
+		t.Fatalf("loaded value not cached: (%v, %v)", v, ok)
+	}
+}
+
+func TestGetOrLoadServesStaleAndRefreshes(t *testing.T) {
+	mc := newMock()
+	c := cache.New[string, int](10, time.Minute,
+		cache.WithClock[string, int](mc),
+		cache.WithStaleWindow[string, int](10*time.Minute),
+	)
+	c.Set("k", 1)
+	mc.Advance(2 * time.Minute)
+
+	refreshed := make(chan struct{})
+	v, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		defer close(refreshed)
+		return 2, nil
+	})
+	if err != nil || v != 1 {
+		t.Fatalf("stale read: got (%v, %v), want (1, nil)", v, err)
+	}
+	<-refreshed
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, ok := c.Get("k"); ok && v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh never landed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetOrLoadStaleRefreshFailureKeepsStale(t *testing.T) {
+	mc := newMock()
+	c := cache.New[string, int](10, time.Minute,
+		cache.WithClock[string, int](mc),
+		cache.WithStaleWindow[string, int](10*time.Minute),
+		cache.WithNegativeTTL[string, int](time.Minute, nil),
+	)
+	c.Set("k", 1)
+	mc.Advance(2 * time.Minute)
+
+	done := make(chan struct{})
+	_, _ = c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		defer close(done)
+		return 0, errors.New("upstream down")
+	})
+	<-done
+	time.Sleep(5 * time.Millisecond)
+	entry, ok := c.GetEntry("k")
+	if !ok || !entry.Stale || entry.Value != 1 {
+		t.Fatalf("failed refresh must keep the stale entry: (%+v, %v)", entry, ok)
+	}
+}
+
+func TestGetOrLoadNegativeCaching(t *testing.T) {
+	mc := newMock()
+	errGone := errors.New("not found upstream")
+	c := cache.New[string, int](10, time.Minute,
+		cache.WithClock[string, int](mc),
+		cache.WithNegativeTTL[string, int](10*time.Second, func(err error) bool { return errors.Is(err, errGone) }),
+	)
+	var calls int32
+	load := func(context.Context, string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, errGone
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "k", load); !errors.Is(err, errGone) {
+			t.Fatalf("call %d: err = %v, want errGone", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("negative result should be cached; loader ran %d times", got)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("negative entry must be a miss for Get")
+	}
+
+	mc.Advance(11 * time.Second)
+	_, _ = c.GetOrLoad(context.Background(), "k", load)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("negative TTL expired; want 2 loader calls, got %d", got)
+	}
+
+	// Errors the predicate rejects are not cached.
+	other := errors.New("timeout")
+	var otherCalls int32
+	for i := 0; i < 2; i++ {
+		_, _ = c.GetOrLoad(context.Background(), "j", func(context.Context, string) (int, error) {
+			atomic.AddInt32(&otherCalls, 1)
+			return 0, other
+		})
+	}
+	if otherCalls != 2 {
+		t.Fatalf("non-cacheable error was cached (%d calls)", otherCalls)
+	}
+}
+
+func TestGetOrLoadRecoversLoaderPanic(t *testing.T) {
+	c := cache.New[string, int](10, time.Minute, cache.WithClock[string, int](newMock()))
+	errBoom := errors.New("boom")
+	_, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		panic(errBoom)
+	})
+	var pe *cache.PanicError
+	if !errors.As(err, &pe) || !errors.Is(err, errBoom) || len(pe.Stack) == 0 {
+		t.Fatalf("err = %v, want a *PanicError wrapping errBoom", err)
+	}
+
+	// The in-flight entry is cleared, so the next miss loads again.
+	v, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		return 7, nil
+	})
+	if err != nil || v != 7 {
+		t.Fatalf("after a panic: %v, %v; want 7", v, err)
+	}
+}
+
+func TestGetOrLoadCallerCancelStillPopulates(t *testing.T) {
+	c := cache.New[string, int](10, time.Minute)
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetOrLoad(ctx, "k", func(context.Context, string) (int, error) {
+		<-release
+		return 7, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller: err = %v", err)
+	}
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, ok := c.Get("k"); ok && v == 7 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("load should complete and populate despite the caller leaving")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []cache.Event
+}
+
+func (r *recordingObserver) ObserveCache(ev cache.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *recordingObserver) count(p cache.Phase) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, ev := range r.events {
+		if ev.Phase == p {
+			n++
+		}
+	}
+	return n
+}
+
+func TestObserverEvents(t *testing.T) {
+	obs := &recordingObserver{}
+	cache.SetDefaultObserver(obs)
+	defer cache.SetDefaultObserver(nil)
+
+	c := cache.New[string, int](1, time.Minute, cache.WithName[string, int]("users"))
+	load := func(context.Context, string) (int, error) { return 1, nil }
+	_, _ = c.GetOrLoad(context.Background(), "a", load) // miss + load
+	_, _ = c.GetOrLoad(context.Background(), "a", load) // hit
+	c.Set("b", 2)                                       // evicts a
+
+	for p, want := range map[cache.Phase]int{
+		cache.PhaseMiss:  1,
+		cache.PhaseLoad:  1,
+		cache.PhaseHit:   1,
+		cache.PhaseEvict: 1,
+	} {
+		if got := obs.count(p); got != want {
+			t.Errorf("%s events = %d, want %d", p, got, want)
+		}
+	}
+	for _, ev := range obs.events {
+		if ev.Cache != "users" {
+			t.Fatalf("event not labelled with cache name: %+v", ev)
+		}
+	}
+}
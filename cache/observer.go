@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Phase buckets the observer event types.
+type Phase string
+
+const (
+	PhaseHit         Phase = "hit"          // fresh entry returned
+	PhaseStaleHit    Phase = "stale_hit"    // entry past TTL but inside the stale window returned
+	PhaseNegativeHit Phase = "negative_hit" // cached loader error returned by GetOrLoad
+	PhaseMiss        Phase = "miss"         // key absent or expired
+	PhaseLoad        Phase = "load"         // GetOrLoad loader returned a value
+	PhaseLoadError   Phase = "load_error"   // GetOrLoad loader returned an error
+	PhaseEvict       Phase = "evict"        // entry removed by capacity, expiry, or admission
+)
+
+// Eviction reasons carried on PhaseEvict events.
+const (
+	EvictCapacity = "capacity" // LRU tail dropped to fit the count or cost budget
+	EvictExpired  = "expired"  // past TTL (and stale window) on access
+	EvictRejected = "rejected" // refused admission: TinyLFU lost, or costs more than the whole budget
+)
+
+// Observer receives one event per cache operation. Implementations
+// MUST NOT block and MUST NOT call back into the cache — events for
+// lookups and evictions are emitted with the cache mutex held. The
+// canonical implementation lives in go-common/promx.
+type Observer interface {
+	ObserveCache(Event)
+}
+
+// Event is the per-operation payload handed to an Observer.
+//
+// Duration is set on PhaseLoad / PhaseLoadError (the loader's wall
+// time, which for a coalesced load is reported once, not per waiter).
+// Reason is set on PhaseEvict.
+type Event struct {
+	Cache    string
+	Phase    Phase
+	Duration time.Duration
+	Reason   string
+}
+
+var defaultObserver atomic.Pointer[Observer]
+
+// SetDefaultObserver installs a process-wide observer. Pass nil to
+// disable. Wired by promx.AutoWire.
+func SetDefaultObserver(o Observer) {
+	if o == nil {
+		defaultObserver.Store(nil)
+		return
+	}
+	defaultObserver.Store(&o)
+}
+
+// DefaultObserver returns the current process-wide observer or nil.
+func DefaultObserver() Observer {
+	p := defaultObserver.Load()
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// emit stamps the cache name and forwards ev to the default observer.
+func (c *Cache[K, V]) emit(ev Event) {
+	if obs := DefaultObserver(); obs != nil {
+		ev.Cache = c.name
+		obs.ObserveCache(ev)
+	}
+}
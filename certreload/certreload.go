@@ -0,0 +1,198 @@
+// Package certreload keeps TLS material loaded from PEM files current.
+//
+// A KeyPair (certificate + private key) or CAPool (trust bundle)
+// re-reads its files when their modification time or size changes. The
+// check runs on use — at most once per interval, from the TLS handshake
+// callbacks — so a rotated certificate (cert-manager, a SPIRE agent, an
+// operator's `cp`) is picked up without a restart and without a watcher
+// goroutine to leak.
+//
+// A reload that fails (a half-written file, a key that no longer
+// matches) keeps serving the last good material and logs a warning; it
+// is retried on the next check. Only the initial load returns an error.
+//
+//	kp, err := certreload.LoadKeyPair("tls.crt", "tls.key", 0)
+//	cfg := &tls.Config{GetCertificate: kp.GetCertificate}
+//
+// server.WithTLS and safehttp.WithClientCert are built on this package.
+package certreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often the files are checked for change when
+// the interval passed to a constructor is <= 0.
+const DefaultInterval = 10 * time.Second
+
+// stamp identifies one version of a set of files.
+type stamp struct {
+	mod  [2]time.Time
+	size [2]int64
+}
+
+func statFiles(files ...string) (stamp, error) {
+	var s stamp
+	for i, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return s, err
+		}
+		s.mod[i], s.size[i] = fi.ModTime(), fi.Size()
+	}
+	return s, nil
+}
+
+// watcher re-runs load when the stamp of files changes.
+type watcher struct {
+	files    []string
+	interval time.Duration
+	load     func() error
+	now      func() time.Time
+
+	mu      sync.Mutex
+	stamp   stamp
+	checked time.Time
+}
+
+func newWatcher(interval time.Duration, load func() error, files ...string) (*watcher, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	w := &watcher{files: files, interval: interval, load: load, now: time.Now}
+	st, err := statFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	if err := load(); err != nil {
+		return nil, err
+	}
+	w.stamp, w.checked = st, w.now()
+	return w, nil
+}
+
+// check reloads when the interval has passed and the files changed.
+func (w *watcher) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if now.Sub(w.checked) < w.interval {
+		return
+	}
+	w.checked = now
+	st, err := statFiles(w.files...)
+	if err != nil || st == w.stamp {
+		return // a missing file mid-rotation: keep the current material
+	}
+	if err := w.load(); err != nil {
+		slog.Warn("certreload: reload failed, keeping previous", "files", w.files, "error", err)
+		return
+	}
+	w.stamp = st
+}
+
+// KeyPair is a certificate and private key loaded from PEM files and
+// reloaded when they change. Safe for concurrent use.
+type KeyPair struct {
+	w    *watcher
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// LoadKeyPair loads certFile and keyFile, checking them for change at
+// most once per interval (DefaultInterval when <= 0).
+func LoadKeyPair(certFile, keyFile string, interval time.Duration) (*KeyPair, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("certreload: certificate and key files required")
+	}
+	k := &KeyPair{}
+	w, err := newWatcher(interval, func() error {
+		c, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("certreload: load key pair: %w", err)
+		}
+		if c.Leaf == nil && len(c.Certificate) > 0 {
+			c.Leaf, _ = x509.ParseCertificate(c.Certificate[0])
+		}
+		k.mu.Lock()
+		k.cert = &c
+		k.mu.Unlock()
+		return nil
+	}, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	k.w = w
+	return k, nil
+}
+
+// Certificate returns the current certificate, reloading it first if
+// the files changed.
+func (k *KeyPair) Certificate() *tls.Certificate {
+	k.w.check()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.cert
+}
+
+// GetCertificate is a tls.Config.GetCertificate callback.
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return k.Certificate(), nil
+}
+
+// GetClientCertificate is a tls.Config.GetClientCertificate callback.
+func (k *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return k.Certificate(), nil
+}
+
+// CAPool is a bundle of PEM CA certificates reloaded when its file
+// changes. Safe for concurrent use.
+type CAPool struct {
+	w    *watcher
+	mu   sync.RWMutex
+	pool *x509.CertPool
+}
+
+// LoadCAPool loads the PEM bundle in file, checking it for change at
+// most once per interval (DefaultInterval when <= 0).
+func LoadCAPool(file string, interval time.Duration) (*CAPool, error) {
+	if file == "" {
+		return nil, errors.New("certreload: CA file required")
+	}
+	p := &CAPool{}
+	w, err := newWatcher(interval, func() error {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("certreload: read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("certreload: no certificates in %s", file)
+		}
+		p.mu.Lock()
+		p.pool = pool
+		p.mu.Unlock()
+		return nil
+	}, file)
+	if err != nil {
+		return nil, err
+	}
+	p.w = w
+	return p, nil
+}
+
+// Pool returns the current pool, reloading it first if the file
+// changed. The returned pool must not be modified.
+func (p *CAPool) Pool() *x509.CertPool {
+	p.w.check()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pool
+}
@@ -0,0 +1,95 @@
+package certreload
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/testhelpers/testca"
+)
+
+func TestKeyPairReloadsOnChange(t *testing.T) {
+	ca := testca.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.Issue(t, "spiffe://fleet.test/a").Write(t, dir, "tls")
+
+	kp, err := LoadKeyPair(certFile, keyFile, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	kp.w.now = func() time.Time { return now }
+	first := kp.Certificate()
+	if got := first.Leaf.URIs[0].String(); got != "spiffe://fleet.test/a" {
+		t.Fatalf("leaf URI = %q", got)
+	}
+
+	ca.Issue(t, "spiffe://fleet.test/b").Write(t, dir, "tls")
+	bump(t, certFile, keyFile)
+	if kp.Certificate() != first {
+		t.Fatal("files must not be re-checked within the interval")
+	}
+	now = now.Add(time.Minute)
+	if got := kp.Certificate().Leaf.URIs[0].String(); got != "spiffe://fleet.test/b" {
+		t.Fatalf("after rotation leaf URI = %q", got)
+	}
+
+	// A broken rotation keeps the last good pair.
+	os.WriteFile(keyFile, []byte("garbage"), 0o600)
+	bump(t, keyFile)
+	now = now.Add(time.Minute)
+	if got := kp.Certificate().Leaf.URIs[0].String(); got != "spiffe://fleet.test/b" {
+		t.Fatalf("a failed reload should keep the previous pair, got %q", got)
+	}
+}
+
+func TestCAPoolReloadsOnChange(t *testing.T) {
+	ca1, ca2 := testca.New(t), testca.New(t)
+	dir := t.TempDir()
+	bundle := ca1.WriteBundle(t, dir)
+
+	p, err := LoadCAPool(bundle, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	p.w.now = func() time.Time { return now }
+	if !p.Pool().Equal(ca1.Pool()) {
+		t.Fatal("pool should trust ca1")
+	}
+	os.WriteFile(bundle, bytes.Join([][]byte{ca1.CertPEM, ca2.CertPEM}, nil), 0o600)
+	bump(t, bundle)
+	now = now.Add(time.Second)
+	want := ca1.Pool()
+	want.AddCert(ca2.Cert)
+	if !p.Pool().Equal(want) {
+		t.Fatal("pool should trust both CAs after reload")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := LoadKeyPair("", "", 0); err == nil {
+		t.Fatal("empty paths should fail")
+	}
+	if _, err := LoadKeyPair("/nonexistent.crt", "/nonexistent.key", 0); err == nil {
+		t.Fatal("missing files should fail")
+	}
+	empty := t.TempDir() + "/empty.pem"
+	os.WriteFile(empty, nil, 0o600)
+	if _, err := LoadCAPool(empty, 0); err == nil {
+		t.Fatal("a bundle without certificates should fail")
+	}
+}
+
+// bump moves the files' modification time forward so a rewrite within
+// the filesystem's timestamp granularity still reads as a change.
+func bump(t *testing.T, files ...string) {
+	t.Helper()
+	future := time.Now().Add(time.Hour)
+	for _, f := range files {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -14,16 +14,20 @@
 // Semantics:
 //
 //   - closed:    requests pass through; failures increment the counter.
-//     When ≥ FailureThreshold consecutive failures happen,
-//     the breaker opens.
+//     When ≥ FailureThreshold consecutive failures happen —
+//     or, in error-rate mode, the failure rate over a rolling
+//     Window reaches FailureRateThreshold with at least
+//     MinRequests calls — the breaker opens. Calls slower
+//     than SlowCallThreshold count as failures.
 //   - open:      requests fail-fast with ErrOpen until OpenFor elapses,
 //     at which point it flips to half-open.
-//   - half-open: exactly one probe request is allowed through. Success
-//     resets to closed; failure flips back to open.
+//   - half-open: up to HalfOpenProbes probe requests are allowed
+//     through. All succeeding resets to closed; any failure
+//     flips back to open.
 //
 // Use Allow() as a pre-check on the hot path, then Success() or
-// Failure() on the outcome. The breaker neither retries nor sleeps;
-// callers do.
+// Failure() — or Record(failed, latency) for slow-call detection — on
+// the outcome. The breaker neither retries nor sleeps; callers do.
 package circuitbreaker
 
 import (
@@ -65,11 +69,39 @@ type Options struct {
 	// "third-party-api"). Becomes the "upstream" label in metrics.
 	Upstream string
 	// FailureThreshold is the number of consecutive failures required
-	// to trip from closed to open. Default 5.
+	// to trip from closed to open. Default 5. Negative disables the
+	// consecutive rule when FailureRateThreshold is set, leaving the
+	// rate as the only trip condition; without it, negative means the
+	// default, as it always has.
 	FailureThreshold int
 	// OpenFor is how long the breaker stays open before allowing a
 	// half-open probe. Default 30s.
 	OpenFor time.Duration
+
+	// FailureRateThreshold enables error-rate mode: the breaker also
+	// trips when the fraction of failed (or slow) calls in the rolling
+	// Window reaches this value (0.5 = 50%). Catches an upstream that
+	// fails 40% of requests but never FailureThreshold in a row.
+	// 0 disables rate mode.
+	FailureRateThreshold float64
+	// Window is the rolling period the failure rate is computed over.
+	// It is tracked in 10 buckets, so rates move in Window/10 steps.
+	// Default 60s.
+	Window time.Duration
+	// MinRequests is the call volume the Window must hold before the
+	// failure rate is evaluated — 1 failure out of 2 calls is not a
+	// 50% outage. Default 20.
+	MinRequests int
+
+	// SlowCallThreshold makes calls reported via Record with a latency
+	// above it count as failures, for upstreams that degrade by
+	// crawling rather than erroring. 0 disables slow-call detection.
+	SlowCallThreshold time.Duration
+
+	// HalfOpenProbes is how many concurrent probe calls are admitted
+	// in half-open. All of them must succeed to close the breaker; any
+	// failure reopens it. Default 1.
+	HalfOpenProbes int
 }
 
 // Breaker is a single named circuit breaker. Safe for concurrent use.
@@ -77,17 +109,30 @@ type Breaker struct {
 	upstream         string
 	failureThreshold int
 	openFor          time.Duration
+	rateThreshold    float64
+	minRequests      int
+	slowCall         time.Duration
+	halfOpenProbes   int
+
+	// now is the clock; tests swap it to step through windows.
+	now func() time.Time
 
 	mu              sync.Mutex
 	state           State
 	consecutiveFail int
 	openedAt        time.Time
+	window          *rollingWindow
+
+	// Half-open bookkeeping, reset on every entry to half-open.
+	halfOpenAt      time.Time
+	probesInFlight  int
+	probesSucceeded int
 }
 
 // New constructs a breaker. Defaults: 5 consecutive failures to trip,
-// 30s open window.
+// 30s open window, rate mode off, one half-open probe.
 func New(opts Options) *Breaker {
-	if opts.FailureThreshold <= 0 {
+	if opts.FailureThreshold == 0 || (opts.FailureThreshold < 0 && opts.FailureRateThreshold <= 0) {
 		opts.FailureThreshold = 5
 	}
 	if opts.OpenFor <= 0 {
@@ -96,11 +141,26 @@ func New(opts Options) *Breaker {
 	if opts.Upstream == "" {
 		opts.Upstream = "_unknown"
 	}
+	if opts.Window <= 0 {
+		opts.Window = 60 * time.Second
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 20
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
 	return &Breaker{
 		upstream:         opts.Upstream,
 		failureThreshold: opts.FailureThreshold,
 		openFor:          opts.OpenFor,
+		rateThreshold:    opts.FailureRateThreshold,
+		minRequests:      opts.MinRequests,
+		slowCall:         opts.SlowCallThreshold,
+		halfOpenProbes:   opts.HalfOpenProbes,
+		now:              time.Now,
 		state:            StateClosed,
+		window:           newRollingWindow(opts.Window),
 	}
 }
 
@@ -116,77 +176,141 @@ func (b *Breaker) State() State {
 
 // Allow returns nil if a request is allowed through, ErrOpen if the
 // breaker is open. When called in the open state and the open window
-// has elapsed, Allow transitions to half-open and returns nil
-// (allowing exactly one probe).
+// has elapsed, Allow transitions to half-open and returns nil; in
+// half-open, up to HalfOpenProbes callers are admitted and the rest
+// get ErrOpen until the probes resolve.
 func (b *Breaker) Allow() error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	now := b.now()
 	switch b.state {
 	case StateClosed:
 		return nil
 	case StateHalfOpen:
-		// The first caller to land here while half-open is the probe.
-		// Subsequent concurrent callers are blocked until the probe
-		// resolves and either resets to closed or trips back to open.
-		// Implementation: flip to open until the probe finishes.
-		b.transitionLocked(StateOpen, "probe_in_flight")
-		b.openedAt = time.Now()
+		if b.probesInFlight >= b.halfOpenProbes {
+			// Probes that never reported back (a caller that forgot
+			// Success/Failure) must not wedge the breaker: after
+			// another OpenFor, admit a fresh set.
+			if now.Sub(b.halfOpenAt) < b.openFor {
+				return ErrOpen
+			}
+			b.halfOpenAt = now
+			b.probesInFlight = 0
+		}
+		b.probesInFlight++
 		return nil
 	default: // StateOpen
-		if time.Since(b.openedAt) >= b.openFor {
-			b.transitionLocked(StateHalfOpen, "open_window_elapsed")
+		if now.Sub(b.openedAt) >= b.openFor {
+			b.transitionLocked(StateHalfOpen, "open_window_elapsed", now)
+			b.probesInFlight = 1
 			return nil
 		}
 		return ErrOpen
 	}
 }
 
-// Success records a successful call. From half-open this closes the
-// breaker; from closed it resets the failure counter; from open it's
-// a no-op (the probe path goes through half-open).
-func (b *Breaker) Success() {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	switch b.state {
-	case StateHalfOpen:
-		b.transitionLocked(StateClosed, "probe_ok")
-		b.consecutiveFail = 0
-	case StateClosed:
-		b.consecutiveFail = 0
-	}
-}
+// Success records a successful call. From half-open this counts one
+// probe success (closing once HalfOpenProbes succeed); from closed it
+// resets the consecutive-failure counter; from open it's a no-op (the
+// probe path goes through half-open).
+func (b *Breaker) Success() { b.Record(false, 0) }
 
 // Failure records a failed call. From closed, FailureThreshold
-// consecutive failures trip the breaker open. From half-open, a
-// single failure trips back to open.
-func (b *Breaker) Failure() {
+// consecutive failures — or a failure rate at FailureRateThreshold —
+// trip the breaker open. From half-open, a single failure trips back
+// to open.
+func (b *Breaker) Failure() { b.Record(true, 0) }
+
+// Record reports the outcome of one call admitted by Allow. failed
+// marks an error; a latency above SlowCallThreshold also counts as a
+// failure. Pass latency 0 when it is unknown.
+func (b *Breaker) Record(failed bool, latency time.Duration) {
+	slow := b.slowCall > 0 && latency > b.slowCall
+	bad := failed || slow
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	now := b.now()
 	switch b.state {
 	case StateClosed:
+		b.window.add(now, bad, slow)
+		if !bad {
+			b.consecutiveFail = 0
+			return
+		}
 		b.consecutiveFail++
-		if b.consecutiveFail >= b.failureThreshold {
-			b.openedAt = time.Now()
-			b.transitionLocked(StateOpen, "threshold_reached")
+		if b.failureThreshold > 0 && b.consecutiveFail >= b.failureThreshold {
+			b.tripLocked("threshold_reached", now)
+			return
+		}
+		if b.rateThreshold > 0 {
+			st := b.window.stats(now)
+			if st.requests >= b.minRequests && st.failureRate() >= b.rateThreshold {
+				b.tripLocked("failure_rate_exceeded", now)
+			}
 		}
 	case StateHalfOpen:
-		b.openedAt = time.Now()
-		b.transitionLocked(StateOpen, "probe_failed")
+		if b.probesInFlight > 0 {
+			b.probesInFlight--
+		}
+		if bad {
+			b.tripLocked("probe_failed", now)
+			return
+		}
+		b.probesSucceeded++
+		if b.probesSucceeded >= b.halfOpenProbes {
+			b.transitionLocked(StateClosed, "probe_ok", now)
+		}
 	}
 }
 
-func (b *Breaker) transitionLocked(to State, reason string) {
+// release gives back an admission that ends with no verdict — the
+// caller cancelled before the upstream answered. In half-open it frees
+// the probe slot without counting a success or a failure; otherwise
+// it is a no-op.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+}
+
+// tripLocked opens the breaker. Must be called with b.mu held.
+func (b *Breaker) tripLocked(reason string, now time.Time) {
+	b.openedAt = now
+	b.transitionLocked(StateOpen, reason, now)
+}
+
+func (b *Breaker) transitionLocked(to State, reason string, now time.Time) {
 	from := b.state
 	if from == to {
 		return
 	}
+	// Snapshot the window before a reset so the event carries the
+	// rate that caused (or ended) this transition.
+	st := b.window.stats(now)
 	b.state = to
+	switch to {
+	case StateClosed:
+		// Start clean: failures from before the outage must not
+		// count against the recovered upstream.
+		b.consecutiveFail = 0
+		b.window.reset()
+	case StateHalfOpen:
+		b.halfOpenAt = now
+		b.probesInFlight = 0
+		b.probesSucceeded = 0
+	}
 	if obs := DefaultObserver(); obs != nil {
 		obs.ObserveCircuit(Event{
-			Upstream: b.upstream,
-			From:     from,
-			To:       to,
-			Reason:   reason,
+			Upstream:     b.upstream,
+			From:         from,
+			To:           to,
+			Reason:       reason,
+			FailureRate:  st.failureRate(),
+			SlowCallRate: st.slowRate(),
+			Requests:     st.requests,
 		})
 	}
 }
@@ -199,11 +323,19 @@ type Observer interface {
 }
 
 // Event is the per-transition payload handed to an Observer.
+//
+// FailureRate, SlowCallRate, and Requests describe the rolling window
+// at the moment of the transition — for a trip, the rate that caused
+// it. Reason is one of threshold_reached, failure_rate_exceeded,
+// probe_failed, open_window_elapsed, probe_ok.
 type Event struct {
-	Upstream string
-	From     State
-	To       State
-	Reason   string
+	Upstream     string
+	From         State
+	To           State
+	Reason       string
+	FailureRate  float64 // failed-or-slow calls / Requests, 0..1
+	SlowCallRate float64 // slow calls / Requests, 0..1
+	Requests     int     // calls recorded in the window
 }
 
 var defaultObserver atomic.Pointer[Observer]
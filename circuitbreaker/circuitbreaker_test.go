@@ -22,6 +22,16 @@ func TestTripsAfterThreshold(t *testing.T) {
 	}
 }
 
+func TestNegativeThresholdWithoutRateModeKeepsDefault(t *testing.T) {
+	b := New(Options{Upstream: "x", FailureThreshold: -1})
+	for i := 0; i < 5; i++ {
+		b.Failure()
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v after 5 failures, want open (default threshold)", b.State())
+	}
+}
+
 func TestHalfOpenProbeSuccessCloses(t *testing.T) {
 	b := New(Options{Upstream: "x", FailureThreshold: 1, OpenFor: 20 * time.Millisecond})
 	if err := b.Allow(); err != nil {
@@ -72,3 +82,140 @@ func TestObserverFires(t *testing.T) {
 type observerFunc func(Event)
 
 func (f observerFunc) ObserveCircuit(ev Event) { f(ev) }
+
+// stepClock is a manually advanced clock for window tests.
+type stepClock struct{ t time.Time }
+
+func (c *stepClock) now() time.Time      { return c.t }
+func (c *stepClock) add(d time.Duration) { c.t = c.t.Add(d) }
+func newStepped(opts Options) (*Breaker, *stepClock) {
+	clk := &stepClock{t: time.Unix(1_700_000_000, 0)}
+	b := New(opts)
+	b.now = clk.now
+	return b, clk
+}
+
+func TestFailureRateTripsWithoutConsecutiveRun(t *testing.T) {
+	var events []Event
+	SetDefaultObserver(observerFunc(func(ev Event) { events = append(events, ev) }))
+	defer SetDefaultObserver(nil)
+
+	b, _ := newStepped(Options{
+		Upstream:             "flaky",
+		FailureThreshold:     -1,
+		FailureRateThreshold: 0.4,
+		MinRequests:          10,
+	})
+	// Alternate fail/ok: never two failures in a row, 50% error rate.
+	for i := 0; i < 20 && b.State() == StateClosed; i++ {
+		_ = b.Allow()
+		b.Record(i%2 == 0, 0)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v, want open on 50%% error rate", b.State())
+	}
+	if len(events) != 1 || events[0].Reason != "failure_rate_exceeded" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if events[0].FailureRate < 0.4 || events[0].Requests < 10 {
+		t.Fatalf("event should carry the trip rate: %+v", events[0])
+	}
+}
+
+func TestFailureRateRespectsMinRequests(t *testing.T) {
+	b, _ := newStepped(Options{FailureThreshold: -1, FailureRateThreshold: 0.5, MinRequests: 20})
+	for i := 0; i < 5; i++ {
+		_ = b.Allow()
+		b.Failure()
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("5 calls is below MinRequests; state = %v", b.State())
+	}
+}
+
+func TestFailureRateWindowAgesOut(t *testing.T) {
+	b, clk := newStepped(Options{
+		FailureThreshold:     -1,
+		FailureRateThreshold: 0.5,
+		MinRequests:          10,
+		Window:               10 * time.Second,
+	})
+	for i := 0; i < 9; i++ {
+		b.Failure()
+	}
+	// The failures fall out of the window; fresh successes dominate.
+	clk.add(11 * time.Second)
+	for i := 0; i < 10; i++ {
+		b.Success()
+	}
+	b.Failure()
+	if b.State() != StateClosed {
+		t.Fatalf("stale failures should have aged out; state = %v", b.State())
+	}
+}
+
+func TestSlowCallsCountAsFailures(t *testing.T) {
+	var events []Event
+	SetDefaultObserver(observerFunc(func(ev Event) { events = append(events, ev) }))
+	defer SetDefaultObserver(nil)
+
+	b, _ := newStepped(Options{
+		FailureThreshold:     -1,
+		FailureRateThreshold: 0.5,
+		MinRequests:          4,
+		SlowCallThreshold:    time.Second,
+	})
+	b.Record(false, 10*time.Millisecond)
+	b.Record(false, 2*time.Second)
+	b.Record(false, 10*time.Millisecond)
+	b.Record(false, 3*time.Second)
+	if b.State() != StateOpen {
+		t.Fatalf("50%% slow calls should trip; state = %v", b.State())
+	}
+	if got := events[0].SlowCallRate; got != 0.5 {
+		t.Fatalf("SlowCallRate = %v, want 0.5", got)
+	}
+}
+
+func TestHalfOpenProbeQuota(t *testing.T) {
+	b, clk := newStepped(Options{FailureThreshold: 1, OpenFor: time.Second, HalfOpenProbes: 2})
+	_ = b.Allow()
+	b.Failure()
+	clk.add(time.Second)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe 1: %v", err)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe 2: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("probe 3 beyond quota: %v, want ErrOpen", err)
+	}
+	if b.State() != StateHalfOpen {
+		t.Fatalf("rejecting extra probes must not leave half-open; state = %v", b.State())
+	}
+	b.Success()
+	if b.State() != StateHalfOpen {
+		t.Fatalf("one of two probes succeeded; state = %v, want half_open", b.State())
+	}
+	b.Success()
+	if b.State() != StateClosed {
+		t.Fatalf("all probes succeeded; state = %v, want closed", b.State())
+	}
+}
+
+func TestHalfOpenStuckProbesReleased(t *testing.T) {
+	b, clk := newStepped(Options{FailureThreshold: 1, OpenFor: time.Second})
+	_ = b.Allow()
+	b.Failure()
+	clk.add(time.Second)
+	_ = b.Allow() // probe that never reports back
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second caller during probe: %v, want ErrOpen", err)
+	}
+	clk.add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("a lost probe must not wedge the breaker: %v", err)
+	}
+}
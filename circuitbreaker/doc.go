@@ -1,4 +1,6 @@
-// Package circuitbreaker implements a per-host circuit breaker state machine
-// (ok → backing_off → failed → ok) that protects services from cascading
-// failures when upstream dependencies are overloaded or down.
+// Package circuitbreaker implements a per-upstream circuit breaker state
+// machine (closed → open → half_open → closed) that protects services from
+// cascading failures when upstream dependencies are overloaded or down.
+// Breakers trip on consecutive failures or, optionally, on the failure and
+// slow-call rate over a rolling window.
 package circuitbreaker
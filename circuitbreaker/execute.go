@@ -0,0 +1,62 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Execute runs fn under the breaker, pairing Allow with exactly one
+// Success, Failure, or release — including on early returns and
+// panics, which is what hand-rolled Allow/Success/Failure call sites
+// kept getting wrong.
+//
+// It returns ErrOpen without calling fn while the breaker is open, and
+// ctx.Err() without touching the breaker when ctx is already done. A
+// non-nil error from fn counts as a failure, except when ctx itself was
+// cancelled: the caller giving up says nothing about the upstream. A
+// context.DeadlineExceeded from fn is a timeout and does count. The
+// call's latency is recorded, so SlowCallThreshold applies. A panic in
+// fn is recorded as a failure and re-raised.
+func (b *Breaker) Execute(ctx context.Context, fn func(context.Context) error) error {
+	_, err := Execute(ctx, b, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Execute is the value-returning form of Breaker.Execute. Go methods
+// cannot take type parameters, so it lives at package level:
+//
+//	user, err := circuitbreaker.Execute(ctx, br, func(ctx context.Context) (*User, error) {
+//		return api.GetUser(ctx, id)
+//	})
+func Execute[T any](ctx context.Context, b *Breaker, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if err := b.Allow(); err != nil {
+		return zero, err
+	}
+	start := time.Now()
+	done := false
+	defer func() {
+		if !done {
+			// fn panicked (or called runtime.Goexit): count it and let
+			// the panic continue.
+			b.Record(true, time.Since(start))
+		}
+	}()
+	v, err := fn(ctx)
+	done = true
+	switch {
+	case err == nil:
+		b.Record(false, time.Since(start))
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		b.release()
+	default:
+		b.Record(true, time.Since(start))
+	}
+	return v, err
+}
@@ -0,0 +1,75 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecuteRecordsOutcome(t *testing.T) {
+	b := New(Options{FailureThreshold: 2, OpenFor: time.Minute})
+	boom := errors.New("boom")
+	for i := 0; i < 2; i++ {
+		if err := b.Execute(context.Background(), func(context.Context) error { return boom }); !errors.Is(err, boom) {
+			t.Fatalf("Execute %d: %v, want boom", i, err)
+		}
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+	called := false
+	err := b.Execute(context.Background(), func(context.Context) error { called = true; return nil })
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker: err=%v called=%v, want ErrOpen without calling fn", err, called)
+	}
+}
+
+func TestExecuteGenericReturnsValue(t *testing.T) {
+	b := New(Options{})
+	v, err := Execute(context.Background(), b, func(context.Context) (int, error) { return 42, nil })
+	if err != nil || v != 42 {
+		t.Fatalf("Execute = %d, %v", v, err)
+	}
+}
+
+func TestExecuteCallerCancelIsNotAFailure(t *testing.T) {
+	b, clk := newStepped(Options{FailureThreshold: 1, OpenFor: time.Second})
+	b.Failure()
+	clk.add(time.Second)
+
+	// The half-open probe is cancelled by its caller: no verdict, and
+	// the probe slot must be free for the next caller.
+	ctx, cancel := context.WithCancel(context.Background())
+	err := b.Execute(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %v, want half_open", b.State())
+	}
+	if err := b.Execute(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("next probe should be admitted: %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state = %v, want closed", b.State())
+	}
+}
+
+func TestExecutePanicCountsAsFailure(t *testing.T) {
+	b := New(Options{FailureThreshold: 1})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic should propagate")
+			}
+		}()
+		_ = b.Execute(context.Background(), func(context.Context) error { panic("bad") })
+	}()
+	if b.State() != StateOpen {
+		t.Fatalf("state after panic = %v, want open", b.State())
+	}
+}
@@ -0,0 +1,147 @@
+package circuitbreaker
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions configures a Transport.
+type TransportOptions struct {
+	// Breaker is the template every per-host breaker is built from.
+	// Its Upstream is ignored: each breaker is labelled with the
+	// request host, so metrics read fleet_circuit_state{upstream="api.example.com"}.
+	Breaker Options
+	// MaxHosts bounds the breaker registry. When a new host would
+	// exceed it, the least recently used closed breaker is dropped
+	// (an open one only if every breaker is open), so a crawler
+	// touching millions of hosts cannot grow memory — or metric
+	// cardinality — without bound. Default 1024.
+	MaxHosts int
+	// IsFailure classifies a round-trip outcome. Default
+	// DefaultIsFailure: transport errors, 5xx, and 429.
+	IsFailure func(*http.Response, error) bool
+}
+
+// DefaultIsFailure reports transport errors (including timeouts),
+// 5xx responses, and 429 Too Many Requests as failures. 4xx other than
+// 429 are the caller's problem, not the upstream's, and count as
+// successes.
+func DefaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// Transport is an http.RoundTripper that guards each upstream host
+// with its own Breaker. While a host's breaker is open, requests to it
+// fail fast with an error wrapping ErrOpen and never reach inner.
+// Safe for concurrent use.
+type Transport struct {
+	inner     http.RoundTripper
+	template  Options
+	maxHosts  int
+	isFailure func(*http.Response, error) bool
+
+	mu    sync.Mutex
+	hosts map[string]*list.Element // of *hostBreaker
+	lru   *list.List               // front = most recently used
+}
+
+type hostBreaker struct {
+	host string
+	b    *Breaker
+}
+
+// NewTransport wraps inner (http.DefaultTransport when nil) with
+// per-host circuit breakers.
+func NewTransport(inner http.RoundTripper, opts TransportOptions) *Transport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	if opts.MaxHosts <= 0 {
+		opts.MaxHosts = 1024
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = DefaultIsFailure
+	}
+	return &Transport{
+		inner:     inner,
+		template:  opts.Breaker,
+		maxHosts:  opts.MaxHosts,
+		isFailure: opts.IsFailure,
+		hosts:     make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	b := t.Breaker(host)
+	if err := b.Allow(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, host)
+	}
+	start := time.Now()
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil && errors.Is(err, context.Canceled) && req.Context().Err() != nil {
+		b.release()
+		return resp, err
+	}
+	b.Record(t.isFailure(resp, err), time.Since(start))
+	return resp, err
+}
+
+// Breaker returns the breaker for host, creating it on first use.
+// Exposed so callers can inspect State or report outcomes the
+// transport cannot see (a 200 whose body turns out to be an error
+// page).
+func (t *Transport) Breaker(host string) *Breaker {
+	host = strings.ToLower(host)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.hosts[host]; ok {
+		t.lru.MoveToFront(el)
+		return el.Value.(*hostBreaker).b
+	}
+	if t.lru.Len() >= t.maxHosts {
+		t.evictLocked()
+	}
+	opts := t.template
+	opts.Upstream = host
+	hb := &hostBreaker{host: host, b: New(opts)}
+	t.hosts[host] = t.lru.PushFront(hb)
+	return hb.b
+}
+
+// Len returns the number of hosts currently tracked.
+func (t *Transport) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lru.Len()
+}
+
+// evictLocked drops the least recently used closed breaker, falling
+// back to the least recently used breaker of any state. Forgetting an
+// open breaker would let traffic through to a host we know is down,
+// so those go last.
+func (t *Transport) evictLocked() {
+	victim := t.lru.Back()
+	for el := t.lru.Back(); el != nil; el = el.Prev() {
+		if el.Value.(*hostBreaker).b.State() == StateClosed {
+			victim = el
+			break
+		}
+	}
+	if victim == nil {
+		return
+	}
+	t.lru.Remove(victim)
+	delete(t.hosts, victim.Value.(*hostBreaker).host)
+}
@@ -0,0 +1,73 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportTripsPerHost(t *testing.T) {
+	var hits atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	tr := NewTransport(nil, TransportOptions{Breaker: Options{FailureThreshold: 2, OpenFor: time.Minute}})
+	client := &http.Client{Transport: tr}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(down.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	_, err := client.Get(down.URL)
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("third call: %v, want ErrOpen", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("upstream hits = %d, want 2 (open breaker must not reach the wire)", got)
+	}
+	// A different host has its own breaker.
+	if tr.Breaker("other.example").State() != StateClosed {
+		t.Fatal("unrelated host should start closed")
+	}
+}
+
+func TestTransportClassification(t *testing.T) {
+	cases := []struct {
+		status int
+		fail   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, tc := range cases {
+		if got := DefaultIsFailure(&http.Response{StatusCode: tc.status}, nil); got != tc.fail {
+			t.Errorf("status %d: failure = %v, want %v", tc.status, got, tc.fail)
+		}
+	}
+	if !DefaultIsFailure(nil, errors.New("i/o timeout")) {
+		t.Error("transport error should be a failure")
+	}
+}
+
+func TestTransportRegistryBounded(t *testing.T) {
+	tr := NewTransport(nil, TransportOptions{MaxHosts: 2, Breaker: Options{FailureThreshold: 1}})
+	tr.Breaker("down.example").Failure() // open: kept over closed ones
+	tr.Breaker("a.example")
+	tr.Breaker("b.example")
+	if got := tr.Len(); got != 2 {
+		t.Fatalf("Len = %d, want 2", got)
+	}
+	if tr.Breaker("down.example").State() != StateOpen {
+		t.Fatal("open breaker should survive eviction while closed ones exist")
+	}
+}
@@ -0,0 +1,86 @@
+package circuitbreaker
+
+import "time"
+
+// windowBuckets is the rolling window's resolution: rates move in
+// Window/windowBuckets steps as buckets age out.
+const windowBuckets = 10
+
+// rollingWindow counts call outcomes over a trailing period using a
+// ring of fixed-width buckets. Not safe for concurrent use; the
+// Breaker's mutex guards it.
+type rollingWindow struct {
+	width   time.Duration
+	buckets [windowBuckets]bucket
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+	slow     int
+}
+
+// windowStats is a point-in-time sum over the live buckets.
+type windowStats struct {
+	requests int
+	failures int
+	slow     int
+}
+
+func (s windowStats) failureRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.failures) / float64(s.requests)
+}
+
+func (s windowStats) slowRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.slow) / float64(s.requests)
+}
+
+func newRollingWindow(d time.Duration) *rollingWindow {
+	w := d / windowBuckets
+	if w <= 0 {
+		w = time.Millisecond
+	}
+	return &rollingWindow{width: w}
+}
+
+// add records one call at now. failed includes slow calls.
+func (w *rollingWindow) add(now time.Time, failed, slow bool) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[(start.UnixNano()/int64(w.width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if slow {
+		b.slow++
+	}
+}
+
+// stats sums every bucket still inside the window at now.
+func (w *rollingWindow) stats(now time.Time) windowStats {
+	var s windowStats
+	oldest := now.Truncate(w.width).Add(-time.Duration(windowBuckets-1) * w.width)
+	for _, b := range w.buckets {
+		if b.start.Before(oldest) || b.start.After(now) {
+			continue
+		}
+		s.requests += b.requests
+		s.failures += b.failures
+		s.slow += b.slow
+	}
+	return s
+}
+
+func (w *rollingWindow) reset() {
+	w.buckets = [windowBuckets]bucket{}
+}
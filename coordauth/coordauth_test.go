@@ -0,0 +1,136 @@
+package coordauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/header"
+)
+
+func signedRequest(t *testing.T, c Credentials, body string, at time.Time) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/wait?x=1", strings.NewReader(body))
+	if err := c.signAt(r, []byte(body), at); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestSign_KeyInHeaderOnly(t *testing.T) {
+	r := signedRequest(t, Credentials{Key: "k"}, "{}", time.Now())
+	if r.Header.Get(header.APIKey) != "k" {
+		t.Fatalf("key header = %q", r.Header.Get(header.APIKey))
+	}
+	if r.Header.Get(header.FleetSignature) != "" {
+		t.Fatal("no secret, so no signature expected")
+	}
+}
+
+func TestVerify_AcceptsSigned(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := &Verifier{Key: "k", Secret: "s", now: func() time.Time { return now }}
+	r := signedRequest(t, Credentials{Key: "k", Secret: "s"}, `{"host":"h"}`, now)
+	if err := v.Verify(r, []byte(`{"host":"h"}`)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestVerify_Rejections(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	creds := Credentials{Key: "k", Secret: "s"}
+	body := `{"host":"h"}`
+	cases := []struct {
+		name string
+		req  func() (*http.Request, string)
+		want error
+	}{
+		{"wrong key", func() (*http.Request, string) {
+			return signedRequest(t, Credentials{Key: "x", Secret: "s"}, body, now), body
+		}, ErrBadKey},
+		{"unsigned", func() (*http.Request, string) {
+			return signedRequest(t, Credentials{Key: "k"}, body, now), body
+		}, ErrUnsigned},
+		{"wrong secret", func() (*http.Request, string) {
+			return signedRequest(t, Credentials{Key: "k", Secret: "x"}, body, now), body
+		}, ErrBadSignature},
+		{"swapped body", func() (*http.Request, string) {
+			return signedRequest(t, creds, body, now), `{"host":"evil"}`
+		}, ErrBadSignature},
+		{"swapped path", func() (*http.Request, string) {
+			r := signedRequest(t, creds, body, now)
+			r.URL.RawQuery = "x=2"
+			return r, body
+		}, ErrBadSignature},
+		{"stale", func() (*http.Request, string) {
+			return signedRequest(t, creds, body, now.Add(-10*time.Minute)), body
+		}, ErrStale},
+		{"future", func() (*http.Request, string) {
+			return signedRequest(t, creds, body, now.Add(10*time.Minute)), body
+		}, ErrStale},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &Verifier{Key: "k", Secret: "s", now: func() time.Time { return now }}
+			r, b := tc.req()
+			if err := v.Verify(r, []byte(b)); !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerify_RejectsReplay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := &Verifier{Secret: "s", now: func() time.Time { return now }}
+	r := signedRequest(t, Credentials{Secret: "s"}, "{}", now)
+	if err := v.Verify(r, []byte("{}")); err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	if err := v.Verify(r, []byte("{}")); !errors.Is(err, ErrReplay) {
+		t.Fatalf("replay: err = %v, want ErrReplay", err)
+	}
+	// An identical request signed separately carries a fresh nonce.
+	r2 := signedRequest(t, Credentials{Secret: "s"}, "{}", now)
+	if err := v.Verify(r2, []byte("{}")); err != nil {
+		t.Fatalf("identical but fresh request: %v", err)
+	}
+	// Once the timestamp leaves the window the replay is stale, and the
+	// next accepted request sweeps the expired nonces.
+	now = now.Add(DefaultMaxSkew + 2*time.Minute)
+	if err := v.Verify(r, []byte("{}")); !errors.Is(err, ErrStale) {
+		t.Fatalf("late replay: err = %v, want ErrStale", err)
+	}
+	if err := v.Verify(signedRequest(t, Credentials{Secret: "s"}, "{}", now), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.seen) != 1 {
+		t.Fatalf("expired nonces should be swept, %d held", len(v.seen))
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("FLEET_API_KEY", "fleet")
+	t.Setenv("FLEET_SIGNING_SECRET", "fleet-secret")
+	t.Setenv("COORD_URL", "https://coord.example/")
+	ep := FromEnv("COORD", "https://default.example")
+	if ep.URL != "https://coord.example" || ep.Key != "" || ep.Secret != "" {
+		t.Fatalf("fleet-wide credentials must not reach a coordinator: %+v", ep)
+	}
+
+	t.Setenv("COORD_API_KEY", "own")
+	t.Setenv("COORD_SIGNING_SECRET", "own-secret")
+	t.Setenv("LEGACY_KEY", "legacy")
+	ep = FromEnv("COORD", "", "LEGACY_KEY")
+	if ep.Key != "legacy" || ep.Secret != "own-secret" {
+		t.Fatalf("legacy key should win, then the prefixed vars: %+v", ep)
+	}
+
+	t.Setenv("COORD_URL", "")
+	if ep := FromEnv("COORD", "https://default.example"); ep.URL != "https://default.example" || ep.Key != "own" {
+		t.Fatalf("default URL: %+v", ep)
+	}
+}
@@ -0,0 +1,51 @@
+package coordauth
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// Endpoint is a coordinator's base URL plus the credentials to call it
+// with.
+type Endpoint struct {
+	URL string
+	Credentials
+}
+
+// FromEnv resolves an Endpoint from the fleet's env convention:
+//
+//	<prefix>_URL             base URL, trailing slash trimmed (default defaultURL)
+//	<prefix>_API_KEY         key sent as X-API-Key; empty sends none
+//	<prefix>_SIGNING_SECRET  HMAC secret; empty leaves requests unsigned
+//
+// There is deliberately no fallback to FLEET_API_KEY or a fleet-wide
+// secret: a coordinator only sees credentials that were configured for
+// it, so a public coordinator cannot collect the fleet key.
+//
+// legacyKeyVars are checked before <prefix>_API_KEY, for clients whose
+// key variable predates the convention (graph's GRAPH_API_KEY).
+func FromEnv(prefix, defaultURL string, legacyKeyVars ...string) Endpoint {
+	url := os.Getenv(prefix + "_URL")
+	if url == "" {
+		url = defaultURL
+	}
+	keyVars := append(slices.Clip(legacyKeyVars), prefix+"_API_KEY")
+	return Endpoint{
+		URL: strings.TrimRight(url, "/"),
+		Credentials: Credentials{
+			Key:    firstEnv(keyVars...),
+			Secret: os.Getenv(prefix + "_SIGNING_SECRET"),
+		},
+	}
+}
+
+// firstEnv returns the first non-empty value among names.
+func firstEnv(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
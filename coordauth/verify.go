@@ -0,0 +1,114 @@
+package coordauth
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go-common/header"
+)
+
+// Verification errors. All of them mean "answer 401"; they are distinct
+// so a server can log which check failed.
+var (
+	ErrBadKey       = errors.New("coordauth: missing or invalid api key")
+	ErrUnsigned     = errors.New("coordauth: request is not signed")
+	ErrBadSignature = errors.New("coordauth: signature mismatch")
+	ErrStale        = errors.New("coordauth: timestamp outside allowed skew")
+	ErrReplay       = errors.New("coordauth: nonce already used")
+)
+
+// DefaultMaxSkew is how far a signed request's timestamp may drift from
+// the verifier's clock when Verifier.MaxSkew is zero.
+const DefaultMaxSkew = 5 * time.Minute
+
+// Verifier is the server half of Credentials. Key, when set, must match
+// X-API-Key; Secret, when set, makes a valid signature mandatory. The
+// zero value accepts everything. Safe for concurrent use.
+type Verifier struct {
+	Key     string
+	Secret  string
+	MaxSkew time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // nonce -> expiry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewVerifier returns a Verifier for key and secret with DefaultMaxSkew.
+func NewVerifier(key, secret string) *Verifier {
+	return &Verifier{Key: key, Secret: secret}
+}
+
+// Verify checks r against the Verifier. body must be the request body
+// bytes the handler already read; Verify does not consume r.Body.
+func (v *Verifier) Verify(r *http.Request, body []byte) error {
+	if v == nil {
+		return nil
+	}
+	key := r.Header.Get(header.APIKey)
+	if v.Key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(v.Key)) != 1 {
+		return ErrBadKey
+	}
+	if v.Secret == "" {
+		return nil
+	}
+	ts, nonce, sig := r.Header.Get(header.FleetTimestamp), r.Header.Get(header.FleetNonce), r.Header.Get(header.FleetSignature)
+	if ts == "" || nonce == "" || sig == "" {
+		return ErrUnsigned
+	}
+	want := signature(v.Secret, r, key, ts, nonce, body)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return ErrBadSignature
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrStale
+	}
+	skew := v.MaxSkew
+	if skew <= 0 {
+		skew = DefaultMaxSkew
+	}
+	now := v.clock()
+	at := time.Unix(sec, 0)
+	if at.Before(now.Add(-skew)) || at.After(now.Add(skew)) {
+		return ErrStale
+	}
+	return v.remember(nonce, at.Add(skew), now)
+}
+
+// remember records nonce until expiry, failing if it is already held.
+// A nonce only needs remembering while its timestamp is still inside
+// the skew window; after that ErrStale rejects the replay instead.
+func (v *Verifier) remember(nonce string, expiry, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.seen == nil {
+		v.seen = map[string]time.Time{}
+	}
+	if !now.Before(v.nextSweep) {
+		for n, exp := range v.seen {
+			if !now.Before(exp) {
+				delete(v.seen, n)
+			}
+		}
+		v.nextSweep = now.Add(time.Minute)
+	}
+	if exp, ok := v.seen[nonce]; ok && now.Before(exp) {
+		return ErrReplay
+	}
+	v.seen[nonce] = expiry
+	return nil
+}
+
+func (v *Verifier) clock() time.Time {
+	if v.now != nil {
+		return v.now()
+	}
+	return time.Now()
+}
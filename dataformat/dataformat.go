@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"github.com/BurntSushi/toml"
 	yaml "go.yaml.in/yaml/v2"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -23,6 +25,9 @@ const (
 	YAML
 	// TOML is TOML v1.0.0 (via github.com/BurntSushi/toml).
 	TOML
+	// JSONLines is newline-delimited JSON (NDJSON): one JSON value per
+	// line, each line a record.
+	JSONLines
 )
 
 // Sentinel errors. Use errors.Is to test for them.
@@ -54,6 +59,8 @@ func ParseFormat(s string) (Format, error) {
 		return YAML, nil
 	case "toml", "application/toml":
 		return TOML, nil
+	case "jsonl", "ndjson", "jsonlines", "application/x-ndjson", "application/jsonl":
+		return JSONLines, nil
 	default:
 		return 0, fmt.Errorf("%w: %q", ErrUnknownFormat, s)
 	}
@@ -63,22 +70,50 @@ func ParseFormat(s string) (Format, error) {
 // heuristic sniffer, not a validator: the result is the most plausible
 // format, and a follow-up Decode is the authoritative check. Detection
 // order is chosen so that the most structurally distinctive formats win.
+//
+// CSV is reported for ';', tab and '|' delimiters too, but Decode still
+// assumes commas; use DetectFormatOptions to decode what was detected.
 func DetectFormat(b []byte) (Format, error) {
+	f, _, err := detectFormat(b)
+	return f, err
+}
+
+// DetectFormatOptions is DetectFormat plus the decode options the input
+// needs: for CSV, WithCSVDialect with the detected delimiter and header.
+//
+//	f, opts, err := dataformat.DetectFormatOptions(b)
+//	v, err := dataformat.Decode(f, b, opts...)
+func DetectFormatOptions(b []byte) (Format, []DecodeOption, error) {
+	f, d, err := detectFormat(b)
+	if err != nil || f != CSV {
+		return f, nil, err
+	}
+	return f, []DecodeOption{WithCSVDialect(d)}, nil
+}
+
+// detectFormat is DetectFormat, also returning the CSV dialect when the
+// format is CSV.
+func detectFormat(b []byte) (Format, CSVDialect, error) {
+	var none CSVDialect
 	trimmed := bytes.TrimSpace(b)
 	if len(trimmed) == 0 {
-		return 0, ErrEmptyInput
+		return 0, none, ErrEmptyInput
 	}
 
 	// XML: starts with a declaration or an element tag.
 	if trimmed[0] == '<' {
-		return XML, nil
+		return XML, none, nil
 	}
 
-	// JSON: object or array literal, and it must actually parse.
+	// JSON: object or array literal, and it must actually parse. Several
+	// such literals one per line are JSON Lines instead.
 	if trimmed[0] == '{' || trimmed[0] == '[' {
 		var js any
 		if json.Unmarshal(trimmed, &js) == nil {
-			return JSON, nil
+			return JSON, none, nil
+		}
+		if looksLikeJSONLines(trimmed) {
+			return JSONLines, none, nil
 		}
 	}
 
@@ -88,14 +123,14 @@ func DetectFormat(b []byte) (Format, error) {
 	if looksLikeTOML(trimmed) {
 		var tm map[string]any
 		if _, err := toml.Decode(string(trimmed), &tm); err == nil {
-			return TOML, nil
+			return TOML, none, nil
 		}
 	}
 
-	// CSV: at least two comma-separated columns on the first line and the
+	// CSV: at least two columns split on ',', ';', tab or '|', and the
 	// reader accepts a consistent record shape.
-	if looksLikeCSV(trimmed) {
-		return CSV, nil
+	if d, ok := DetectCSVDialect(trimmed); ok {
+		return CSV, d, nil
 	}
 
 	// YAML: last resort for the remaining "key: value" shape. It must parse
@@ -104,24 +139,24 @@ func DetectFormat(b []byte) (Format, error) {
 	if yaml.Unmarshal(trimmed, &yv) == nil {
 		switch yv.(type) {
 		case map[any]any, map[string]any, []any:
-			return YAML, nil
+			return YAML, none, nil
 		}
 	}
 
-	return 0, fmt.Errorf("%w", ErrDetectFailed)
+	return 0, none, fmt.Errorf("%w", ErrDetectFailed)
 }
 
 // Decode parses b in format f into a generic Go value composed of
 // map[string]any, []any and scalars, suitable for re-encoding into any
 // other format. Parse failures are returned as *DecodeError.
-func Decode(f Format, b []byte) (any, error) {
+func Decode(f Format, b []byte, opts ...DecodeOption) (any, error) {
 	if len(bytes.TrimSpace(b)) == 0 {
 		return nil, &DecodeError{Format: f, Err: ErrEmptyInput}
 	}
 	switch f {
 	case JSON:
-		var v any
-		if err := json.Unmarshal(b, &v); err != nil {
+		v, err := decodeJSON(b, newDecodeOpts(opts).useNumber)
+		if err != nil {
 			return nil, &DecodeError{Format: f, Err: err}
 		}
 		return v, nil
@@ -141,11 +176,7 @@ func Decode(f Format, b []byte) (any, error) {
 		return v, nil
 
 	case CSV:
-		v, err := decodeCSV(b)
-		if err != nil {
-			return nil, &DecodeError{Format: f, Err: err}
-		}
-		return v, nil
+		return decodeAll(f, bytes.NewReader(b), opts...)
 
 	case XML:
 		v, err := decodeXML(b)
@@ -154,15 +185,37 @@ func Decode(f Format, b []byte) (any, error) {
 		}
 		return v, nil
 
+	case JSONLines:
+		return decodeAll(f, bytes.NewReader(b), opts...)
+
 	default:
 		return nil, &DecodeError{Format: f, Err: fmt.Errorf("%w: %s", ErrUnknownFormat, f)}
 	}
 }
 
+// decodeJSON unmarshals a single JSON document, keeping numbers as
+// json.Number when useNumber is set.
+func decodeJSON(b []byte, useNumber bool) (any, error) {
+	var v any
+	if !useNumber {
+		err := json.Unmarshal(b, &v)
+		return v, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return v, nil
+}
+
 // Encode serializes v into format f. Shape mismatches (e.g. a bare array to
 // TOML) are returned as *EncodeError wrapping ErrUnsupportedShape or
 // ErrNotTabular.
-func Encode(f Format, v any) ([]byte, error) {
+func Encode(f Format, v any, opts ...EncodeOption) ([]byte, error) {
 	switch f {
 	case JSON:
 		out, err := json.Marshal(v)
@@ -190,6 +243,9 @@ func Encode(f Format, v any) ([]byte, error) {
 		return buf.Bytes(), nil
 
 	case CSV:
+		if newEncodeOpts(opts).flatten {
+			v = flattenRows(v)
+		}
 		out, err := encodeCSV(v)
 		if err != nil {
 			return nil, &EncodeError{Format: f, Err: err}
@@ -203,6 +259,13 @@ func Encode(f Format, v any) ([]byte, error) {
 		}
 		return out, nil
 
+	case JSONLines:
+		var buf bytes.Buffer
+		if err := encodeAll(f, &buf, v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
 	default:
 		return nil, &EncodeError{Format: f, Err: fmt.Errorf("%w: %s", ErrUnknownFormat, f)}
 	}
@@ -210,9 +273,10 @@ func Encode(f Format, v any) ([]byte, error) {
 
 // Convert decodes b from the "from" format and re-encodes it as "to". It is
 // a thin composition of Decode and Encode; lossy edges (see package docs)
-// apply to the encode step.
-func Convert(from, to Format, b []byte) ([]byte, error) {
-	v, err := Decode(from, b)
+// apply to the encode step. For inputs too large to hold in memory use
+// ConvertStream. opts apply to the decode step.
+func Convert(from, to Format, b []byte, opts ...DecodeOption) ([]byte, error) {
+	v, err := Decode(from, b, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -349,6 +413,8 @@ func scalarToString(v any) string {
 		return strconv.Itoa(val)
 	case int64:
 		return strconv.FormatInt(val, 10)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
 	default:
 		b, err := json.Marshal(v)
 		if err != nil {
@@ -0,0 +1,102 @@
+package dataformat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// CSVDialect describes the layout of a delimited text file.
+type CSVDialect struct {
+	// Comma is the field delimiter: ',', ';', '\t' or '|'.
+	Comma rune
+	// Header reports whether the first row names the columns. Without a
+	// header, columns are named col1, col2, ...
+	Header bool
+}
+
+// csvDelimiters are the delimiters DetectCSVDialect tries, in order of
+// preference when several split the input equally well.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// csvSniffSize is how much of a stream the Decoder reads ahead to sniff
+// its dialect.
+const csvSniffSize = 64 << 10
+
+// DetectCSVDialect guesses the delimiter and header of delimited text.
+// A delimiter qualifies when every row splits into the same number (at
+// least two) of fields; the one giving the most fields wins. The first
+// row is a header unless one of its cells is a number, bool or RFC 3339
+// time — column names are not data. ok is false when no delimiter
+// qualifies; the dialect is then comma-separated, with the header still
+// guessed from the first row.
+func DetectCSVDialect(b []byte) (d CSVDialect, ok bool) {
+	return sniffCSV(b, true)
+}
+
+// sniffCSV is DetectCSVDialect over a prefix of the input when complete
+// is false: the trailing partial line is ignored.
+func sniffCSV(b []byte, complete bool) (CSVDialect, bool) {
+	if !complete {
+		if nl := bytes.LastIndexByte(b, '\n'); nl >= 0 {
+			b = b[:nl+1]
+		}
+	}
+	first := b
+	if nl := bytes.IndexByte(b, '\n'); nl >= 0 {
+		first = b[:nl]
+	}
+	d, ok, best := CSVDialect{Comma: ','}, false, 0
+	for _, c := range csvDelimiters {
+		if !bytes.ContainsRune(first, c) {
+			continue
+		}
+		if cols := csvColumns(b, c); cols >= 2 && cols > best {
+			d.Comma, ok, best = c, true, cols
+		}
+	}
+	d.Header = true
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = d.Comma
+	r.FieldsPerRecord = -1
+	if rec, err := r.Read(); err == nil {
+		for _, cell := range rec {
+			if t := classifyText(cell); t != ColumnString && t != ColumnNull {
+				d.Header = false
+				break
+			}
+		}
+	}
+	return d, ok
+}
+
+// csvColumns returns the field count of b split on comma, or 0 when the
+// rows disagree or do not parse.
+func csvColumns(b []byte, comma rune) int {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = comma
+	r.FieldsPerRecord = 0 // enforce consistent column count after first row
+	cols := 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return cols
+		}
+		if err != nil {
+			return 0
+		}
+		if cols == 0 {
+			cols = len(rec)
+		}
+	}
+}
+
+// csvColumnNames names the columns of a headerless file.
+func csvColumnNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = "col" + strconv.Itoa(i+1)
+	}
+	return names
+}
@@ -0,0 +1,45 @@
+package dataformat
+
+// flattenRows prepares v for WithFlatten CSV encoding: object rows are
+// flattened to dotted columns and a lone object becomes a single row.
+// Anything else is left for encodeCSV to accept or reject.
+func flattenRows(v any) any {
+	if m, ok := asStringMap(v); ok {
+		return []any{flattenObject(m)}
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	out := make([]any, len(arr))
+	for i, row := range arr {
+		if m, ok := asStringMap(row); ok {
+			out[i] = flattenObject(m)
+		} else {
+			out[i] = row
+		}
+	}
+	return out
+}
+
+// flattenObject returns m with nested objects folded into its top level
+// under dotted keys: {"a":{"b":1}} becomes {"a.b":1}. An empty nested
+// object keeps its key, so the column is not lost.
+func flattenObject(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			if nested, ok := asStringMap(v); ok && len(nested) > 0 {
+				walk(k, nested)
+				continue
+			}
+			out[k] = v
+		}
+	}
+	walk("", m)
+	return out
+}
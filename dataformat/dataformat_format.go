@@ -20,6 +20,8 @@ func (f Format) String() string {
 		return "yaml"
 	case TOML:
 		return "toml"
+	case JSONLines:
+		return "jsonl"
 	default:
 		return fmt.Sprintf("Format(%d)", int(f))
 	}
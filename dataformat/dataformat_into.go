@@ -0,0 +1,529 @@
+package dataformat
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	fleetErrors "github.com/baditaflorin/go-common/errors"
+	"github.com/baditaflorin/go-common/validate"
+)
+
+// ErrFieldType is returned when a decoded value does not fit its
+// destination type: a DecodeInto field (e.g. the CSV cell "abc" into an
+// int) or a WithSchema column.
+var ErrFieldType = errors.New("dataformat: value does not fit field type")
+
+// DecodeInto parses b in format f and stores the result in dst, which
+// must be a non-nil pointer — typically to a struct, or to a slice of
+// structs for CSV and JSON Lines, whose documents are lists of records.
+//
+// Fields are matched the way encoding/json matches them: by the `json`
+// tag name, else the field name, case-insensitively, with embedded
+// structs flattened. A format-specific tag (`csv`, `xml`, `yaml`, `toml`)
+// overrides the json name when it sets one; `xml:"name,attr"` and
+// `xml:",attr"` read an attribute and `xml:",chardata"` the element text.
+// The XML root element itself is unwrapped. A "-" name skips the field.
+//
+// Values are coerced leniently, since CSV and XML carry every value as
+// text: strings convert to ints, uints, floats, bools, time.Duration and
+// time.Time (RFC 3339, or a bare 2006-01-02 date), numbers and bools
+// convert to strings, and an empty string leaves a non-string field
+// zero. Types implementing encoding.TextUnmarshaler decode from text. A
+// single value decoding into a slice becomes a one-element slice (XML
+// has no arrays, only repeated elements). Unknown keys are ignored.
+// JSON numbers bind exactly: an integer above 2^53 is not rounded
+// through float64.
+//
+// A value that cannot be converted fails with a *DecodeError wrapping
+// ErrFieldType and naming the field path. With WithValidation, a
+// validation failure is returned as the *errors.Error from validate.
+func DecodeInto(f Format, b []byte, dst any, opts ...DecodeOption) error {
+	o := newDecodeOpts(opts)
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &DecodeError{Format: f, Err: fmt.Errorf("DecodeInto needs a non-nil pointer, got %T", dst)}
+	}
+	v, err := Decode(f, b, append(opts, func(o *decodeOpts) { o.useNumber = true })...)
+	if err != nil {
+		return err
+	}
+	if f == XML {
+		v = unwrapXMLRoot(v)
+	}
+	if err := (&binder{f: f}).assign(rv.Elem(), v, ""); err != nil {
+		return &DecodeError{Format: f, Err: err}
+	}
+	if o.validate {
+		if verr := validateInto(rv.Elem()); verr != nil {
+			return verr
+		}
+	}
+	return nil
+}
+
+// unwrapXMLRoot drops the root element name Decode keys an XML document
+// by: a struct describes the root element's content, not the document.
+func unwrapXMLRoot(v any) any {
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		for _, inner := range m {
+			return inner
+		}
+	}
+	return v
+}
+
+// validateInto validates a struct, or each element of a slice with its
+// failures prefixed by row index.
+func validateInto(v reflect.Value) *fleetErrors.Error {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return validate.Struct(v.Addr().Interface())
+	}
+	var msgs []string
+	for i := 0; i < v.Len(); i++ {
+		if err := validate.Struct(v.Index(i).Addr().Interface()); err != nil {
+			msgs = append(msgs, fmt.Sprintf("row %d: %s", i, err.Msg))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fleetErrors.New(http.StatusBadRequest, "bad_request.validation", strings.Join(msgs, "; "))
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// binder stores generic decoded values into typed Go values.
+type binder struct {
+	f Format
+}
+
+func (d *binder) assign(dst reflect.Value, src any, path string) error {
+	if src == nil {
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.assign(dst.Elem(), src, path)
+	}
+	if dst.Type() == timeType {
+		return d.assignTime(dst, src, path)
+	}
+	if s, ok := src.(string); ok && dst.Kind() != reflect.String && reflect.PointerTo(dst.Type()).Implements(textUnmarshalerType) {
+		if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrFieldType, pathName(path), err)
+		}
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return mismatch(path, src, dst.Type())
+		}
+		dst.Set(reflect.ValueOf(plainNumbers(src)))
+		return nil
+
+	case reflect.Struct:
+		m, ok := asStringMap(src)
+		if !ok {
+			return mismatch(path, src, dst.Type())
+		}
+		for _, fi := range d.fields(dst.Type()) {
+			val, ok := lookupKey(m, fi.key)
+			if !ok {
+				continue
+			}
+			if err := d.assign(fieldByIndex(dst, fi.index), val, joinPath(path, fi.key)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		m, ok := asStringMap(src)
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch(path, src, dst.Type())
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+		}
+		for k, val := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := d.assign(elem, val, joinPath(path, k)); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Slice:
+		arr, ok := src.([]any)
+		if sv := reflect.ValueOf(src); !ok && sv.Kind() == reflect.Slice {
+			// TOML decodes an array of tables as []map[string]any.
+			arr = make([]any, sv.Len())
+			for i := range arr {
+				arr[i] = sv.Index(i).Interface()
+			}
+		} else if !ok {
+			arr = []any{src}
+		}
+		out := reflect.MakeSlice(dst.Type(), len(arr), len(arr))
+		for i, val := range arr {
+			if err := d.assign(out.Index(i), val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+
+	case reflect.String:
+		switch src.(type) {
+		case map[string]any, map[any]any, []any:
+			return mismatch(path, src, dst.Type())
+		}
+		dst.SetString(scalarToString(src))
+		return nil
+
+	case reflect.Bool:
+		switch s := src.(type) {
+		case bool:
+			dst.SetBool(s)
+			return nil
+		case string:
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return mismatch(path, src, dst.Type())
+			}
+			dst.SetBool(b)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := src.(string); ok && dst.Type() == durationType {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			dur, err := time.ParseDuration(s)
+			if err != nil {
+				return mismatch(path, src, dst.Type())
+			}
+			dst.SetInt(int64(dur))
+			return nil
+		}
+		n, ok, err := toInt(src)
+		if err != nil || (ok && dst.OverflowInt(n)) {
+			return mismatch(path, src, dst.Type())
+		}
+		if ok {
+			dst.SetInt(n)
+		}
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok, err := toUint(src)
+		if err != nil || (ok && dst.OverflowUint(n)) {
+			return mismatch(path, src, dst.Type())
+		}
+		if ok {
+			dst.SetUint(n)
+		}
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var x float64
+		switch s := src.(type) {
+		case float64:
+			x = s
+		case json.Number:
+			var err error
+			if x, err = strconv.ParseFloat(string(s), 64); err != nil {
+				return mismatch(path, src, dst.Type())
+			}
+		case int:
+			x = float64(s)
+		case int64:
+			x = float64(s)
+		case string:
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			var err error
+			if x, err = strconv.ParseFloat(s, 64); err != nil {
+				return mismatch(path, src, dst.Type())
+			}
+		default:
+			return mismatch(path, src, dst.Type())
+		}
+		if dst.OverflowFloat(x) {
+			return mismatch(path, src, dst.Type())
+		}
+		dst.SetFloat(x)
+		return nil
+	}
+	return mismatch(path, src, dst.Type())
+}
+
+// toInt converts a decoded scalar to an integer. ok is false for an
+// empty string, which leaves the field zero.
+func toInt(src any) (n int64, ok bool, err error) {
+	switch s := src.(type) {
+	case int:
+		return int64(s), true, nil
+	case int64:
+		return s, true, nil
+	case float64:
+		if s != float64(int64(s)) {
+			return 0, false, errors.New("not an integer")
+		}
+		return int64(s), true, nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(s), 10, 64); err == nil {
+			return n, true, nil
+		}
+		// 1.0 or 1e3: integral, but not written as an integer.
+		f, err := s.Float64()
+		if err != nil {
+			return 0, false, err
+		}
+		return toInt(f)
+	case string:
+		if s = strings.TrimSpace(s); s == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		return n, err == nil, err
+	}
+	return 0, false, errors.New("not a number")
+}
+
+// toUint is toInt for unsigned fields, which can hold values above
+// math.MaxInt64.
+func toUint(src any) (n uint64, ok bool, err error) {
+	switch s := src.(type) {
+	case json.Number:
+		if n, err := strconv.ParseUint(string(s), 10, 64); err == nil {
+			return n, true, nil
+		}
+	case string:
+		if s = strings.TrimSpace(s); s == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.ParseUint(s, 10, 64)
+		return n, err == nil, err
+	}
+	i, ok, err := toInt(src)
+	if err == nil && i < 0 {
+		err = errors.New("negative")
+	}
+	return uint64(i), ok && err == nil, err
+}
+
+// plainNumbers turns the json.Numbers in v back into float64, the type
+// Decode gives JSON numbers, for values stored in an interface field.
+func plainNumbers(v any) any {
+	switch s := v.(type) {
+	case json.Number:
+		f, err := s.Float64()
+		if err != nil {
+			return string(s)
+		}
+		return f
+	case map[string]any:
+		for k, e := range s {
+			s[k] = plainNumbers(e)
+		}
+	case []any:
+		for i, e := range s {
+			s[i] = plainNumbers(e)
+		}
+	}
+	return v
+}
+
+// timeLayouts are tried in order for string values bound to time.Time.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly}
+
+func (d *binder) assignTime(dst reflect.Value, src any, path string) error {
+	switch s := src.(type) {
+	case time.Time: // TOML has a native datetime
+		dst.Set(reflect.ValueOf(s))
+		return nil
+	case string:
+		if s = strings.TrimSpace(s); s == "" {
+			return nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				dst.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+	}
+	return mismatch(path, src, dst.Type())
+}
+
+func mismatch(path string, src any, t reflect.Type) error {
+	if s, ok := src.(string); ok {
+		return fmt.Errorf("%w: %s: cannot use %q as %s", ErrFieldType, pathName(path), s, t)
+	}
+	return fmt.Errorf("%w: %s: cannot use %T as %s", ErrFieldType, pathName(path), src, t)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+// lookupKey finds key in m exactly, then case-insensitively.
+func lookupKey(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, allocating nil embedded
+// struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+type fieldInfo struct {
+	key   string
+	index []int
+}
+
+type fieldCacheKey struct {
+	t reflect.Type
+	f Format
+}
+
+var fieldCache sync.Map // fieldCacheKey -> []fieldInfo
+
+// fields lists the settable fields of struct type t and the decoded key
+// each reads from in format d.f.
+func (d *binder) fields(t reflect.Type) []fieldInfo {
+	ck := fieldCacheKey{t, d.f}
+	if cached, ok := fieldCache.Load(ck); ok {
+		return cached.([]fieldInfo)
+	}
+	var out []fieldInfo
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			idx := append(index[:len(index):len(index)], i)
+			key, ok := d.fieldKey(sf)
+			if !ok {
+				continue
+			}
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && key == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if key == "" {
+				key = sf.Name
+			}
+			out = append(out, fieldInfo{key: key, index: idx})
+		}
+	}
+	walk(t, nil)
+	fieldCache.Store(ck, out)
+	return out
+}
+
+// fieldKey resolves the decoded key sf reads from: the format's own tag,
+// then the json tag. An empty key means "use the field name" (or flatten,
+// for an embedded struct); ok is false for a "-" field.
+func (d *binder) fieldKey(sf reflect.StructField) (key string, ok bool) {
+	if tag, has := sf.Tag.Lookup(formatTag(d.f)); has && d.f != JSON && d.f != JSONLines {
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" && opts == "" {
+			return "", false
+		}
+		if d.f == XML {
+			name, _, _ = strings.Cut(name, ">") // nested paths are not supported; read the first element
+			for opt := range strings.SplitSeq(opts, ",") {
+				switch opt {
+				case "attr":
+					if name == "" {
+						name = sf.Name
+					}
+					return "-" + name, true
+				case "chardata":
+					return "#text", true
+				}
+			}
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	}
+	return name, true
+}
+
+func formatTag(f Format) string {
+	switch f {
+	case CSV:
+		return "csv"
+	case XML:
+		return "xml"
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	default:
+		return "json"
+	}
+}
@@ -0,0 +1,150 @@
+package dataformat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	fleetErrors "github.com/baditaflorin/go-common/errors"
+)
+
+type intoBase struct {
+	ID int `json:"id"`
+}
+
+type intoRow struct {
+	intoBase
+	Name    string        `json:"name" validate:"required"`
+	Age     int           `json:"age" csv:"years"`
+	Score   float64       `json:"score"`
+	Active  bool          `json:"active"`
+	Born    time.Time     `json:"born"`
+	Timeout time.Duration `json:"timeout"`
+	Nick    *string       `json:"nick"`
+	Skip    string        `json:"-"`
+}
+
+func TestDecodeIntoCSVCoercesCells(t *testing.T) {
+	in := "id,name,years,score,active,born,timeout,nick,skip\n" +
+		"1,florin,30,9.5,true,2024-03-01,2s,flo,x\n" +
+		"2,alice,,,,,,,\n"
+	var rows []intoRow
+	if err := DecodeInto(CSV, []byte(in), &rows); err != nil {
+		t.Fatal(err)
+	}
+	nick := "flo"
+	want := []intoRow{
+		{intoBase: intoBase{ID: 1}, Name: "florin", Age: 30, Score: 9.5, Active: true,
+			Born: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Timeout: 2 * time.Second, Nick: &nick},
+		{intoBase: intoBase{ID: 2}, Name: "alice", Nick: new(string)},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %+v\nwant  %+v", rows, want)
+	}
+}
+
+func TestDecodeIntoFormats(t *testing.T) {
+	type item struct {
+		ID    string `json:"id" xml:",attr"`
+		Label string `json:"label" xml:",chardata"`
+	}
+	type doc struct {
+		Name  string         `json:"name" yaml:"title" toml:"title"`
+		Count int            `json:"count"`
+		Items []item         `json:"items" xml:"item"`
+		Meta  map[string]any `json:"meta"`
+	}
+	want := doc{Name: "x", Count: 2, Items: []item{{ID: "a", Label: "A"}}}
+	cases := []struct {
+		f  Format
+		in string
+	}{
+		{JSON, `{"name":"x","count":2,"items":[{"id":"a","label":"A"}]}`},
+		{YAML, "title: x\ncount: 2\nitems:\n  - id: a\n    label: A\n"},
+		{TOML, "title = \"x\"\ncount = 2\n[[items]]\nid = \"a\"\nlabel = \"A\"\n"},
+		{XML, `<doc><NAME>x</NAME><count>2</count><item id="a">A</item></doc>`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.f.String(), func(t *testing.T) {
+			var got doc
+			if err := DecodeInto(tc.f, []byte(tc.in), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeIntoFieldTypeError(t *testing.T) {
+	var rows []intoRow
+	err := DecodeInto(CSV, []byte("name,years\nbob,old\n"), &rows)
+	var de *DecodeError
+	if !errors.Is(err, ErrFieldType) || !errors.As(err, &de) || de.Format != CSV {
+		t.Fatalf("err = %v, want *DecodeError wrapping ErrFieldType", err)
+	}
+	if !strings.Contains(err.Error(), "[0].years") {
+		t.Fatalf("error should name the field path: %v", err)
+	}
+	if err := DecodeInto(JSON, []byte(`{}`), intoRow{}); err == nil {
+		t.Fatal("a non-pointer destination should fail")
+	}
+}
+
+func TestDecodeIntoWithValidation(t *testing.T) {
+	var rows []intoRow
+	in := []byte("name,years\nbob,3\n,4\n")
+	if err := DecodeInto(CSV, in, &rows); err != nil {
+		t.Fatalf("validation is opt-in: %v", err)
+	}
+	err := DecodeInto(CSV, in, &rows, WithValidation())
+	var fe *fleetErrors.Error
+	if !errors.As(err, &fe) || fe.Code != "bad_request.validation" || fe.HTTPStatus() != 400 {
+		t.Fatalf("err = %v, want a validation *errors.Error", err)
+	}
+	if !strings.Contains(fe.Msg, "row 1") || strings.Contains(fe.Msg, "row 0") {
+		t.Fatalf("message should point at row 1 only: %q", fe.Msg)
+	}
+
+	var one intoRow
+	if err := DecodeInto(JSON, []byte(`{"age":1}`), &one, WithValidation()); !errors.As(err, &fe) {
+		t.Fatalf("struct validation: err = %v", err)
+	}
+}
+
+func TestDecodeIntoKeepsLargeIntegersExact(t *testing.T) {
+	type rec struct {
+		ID    int64          `json:"id"`
+		Big   uint64         `json:"big"`
+		Ratio float64        `json:"ratio"`
+		Round int            `json:"round"`
+		Text  string         `json:"text"`
+		Any   map[string]any `json:"any"`
+	}
+	in := `{"id": 9007199254740993, "big": 18446744073709551615, "ratio": 0.25, "round": 1e3,` +
+		` "text": 9007199254740993, "any": {"n": 2}}`
+	var got rec
+	if err := DecodeInto(JSON, []byte(in), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := rec{ID: 9007199254740993, Big: 18446744073709551615, Ratio: 0.25, Round: 1000,
+		Text: "9007199254740993", Any: map[string]any{"n": float64(2)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	var rows []rec
+	if err := DecodeInto(JSONLines, []byte(`{"id": 9007199254740993}`+"\n"), &rows); err != nil || rows[0].ID != 9007199254740993 {
+		t.Fatalf("JSON Lines: %+v, %v", rows, err)
+	}
+
+	var small struct {
+		ID int32 `json:"id"`
+	}
+	if err := DecodeInto(JSON, []byte(`{"id": 9007199254740993}`), &small); !errors.Is(err, ErrFieldType) {
+		t.Fatalf("overflowing int32: err = %v, want ErrFieldType", err)
+	}
+}
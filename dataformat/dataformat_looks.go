@@ -2,8 +2,7 @@ package dataformat
 
 import (
 	"bytes"
-	"encoding/csv"
-	"io"
+	"encoding/json"
 	"strings"
 )
 
@@ -29,28 +28,19 @@ func looksLikeTOML(b []byte) bool {
 	return false
 }
 
-func looksLikeCSV(b []byte) bool {
-	first := b
-	if nl := bytes.IndexByte(b, '\n'); nl >= 0 {
-		first = b[:nl]
-	}
-	if !bytes.ContainsRune(first, ',') {
-		return false
-	}
-	r := csv.NewReader(bytes.NewReader(b))
-	r.FieldsPerRecord = 0 // enforce consistent column count after first row
-	cols := 0
-	for {
-		rec, err := r.Read()
-		if err == io.EOF {
-			break
+// looksLikeJSONLines reports whether b holds at least two lines and every
+// non-blank line is a JSON object or array on its own.
+func looksLikeJSONLines(b []byte) bool {
+	lines := 0
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
 		}
-		if err != nil {
+		if line[0] != '{' && line[0] != '[' || !json.Valid(line) {
 			return false
 		}
-		if cols == 0 {
-			cols = len(rec)
-		}
+		lines++
 	}
-	return cols >= 2
+	return lines >= 2
 }
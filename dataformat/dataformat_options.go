@@ -0,0 +1,85 @@
+package dataformat
+
+// DecodeOption configures Decode, NewDecoder and DecodeInto. Options
+// that concern one format are ignored by the others.
+type DecodeOption func(*decodeOpts)
+
+type decodeOpts struct {
+	validate bool
+	schema   Schema
+	dialect  *CSVDialect
+	sniff    bool
+
+	// useNumber decodes JSON numbers as json.Number, so DecodeInto can
+	// bind integers beyond 2^53 exactly.
+	useNumber bool
+}
+
+func newDecodeOpts(opts []DecodeOption) decodeOpts {
+	var o decodeOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithValidation runs validate.Struct on the decoded value (on every
+// element when dst is a slice) once DecodeInto succeeds, so an uploaded
+// file fails with the same *errors.Error a JSON body bound through
+// validate.Bind would.
+func WithValidation() DecodeOption {
+	return func(o *decodeOpts) { o.validate = true }
+}
+
+// WithSchema types CSV cells by column instead of leaving every cell a
+// string: ints decode as int64, floats as float64, bools as bool and
+// times as time.Time, and an empty cell in a typed column as nil.
+// Columns the schema does not name stay strings. A cell its column's
+// type cannot hold fails with a *DecodeError wrapping ErrFieldType.
+// The schema usually comes from InferSchema.
+func WithSchema(s Schema) DecodeOption {
+	return func(o *decodeOpts) { o.schema = s }
+}
+
+// WithCSVDialect sets the CSV delimiter and header handling. Without
+// it (or WithCSVSniffing) CSV is comma-separated with a header row. The
+// dialect usually comes from DetectCSVDialect.
+func WithCSVDialect(d CSVDialect) DecodeOption {
+	return func(o *decodeOpts) { o.dialect = &d }
+}
+
+// WithCSVSniffing detects the CSV delimiter and header from the first
+// 64 KiB of input, as DetectCSVDialect does, instead of assuming comma
+// and a header row. Detection is a guess: a header whose cells look
+// like data, such as "2023,2024", is read as a data row. WithCSVDialect
+// takes precedence.
+func WithCSVSniffing() DecodeOption {
+	return func(o *decodeOpts) { o.sniff = true }
+}
+
+// EncodeOption configures Encode and NewEncoder. Options that concern
+// one format are ignored by the others.
+type EncodeOption func(*encodeOpts)
+
+type encodeOpts struct {
+	flatten bool
+}
+
+func newEncodeOpts(opts []EncodeOption) encodeOpts {
+	var o encodeOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithFlatten lets CSV encode objects that are not flat, uniform rows:
+// nested objects become dotted columns ({"a":{"b":1}} is column "a.b"),
+// the header is the sorted union of every row's columns with missing
+// cells left empty, and a single top-level object is written as one
+// row. Without it a nested object is written as a JSON cell, a lone
+// object is ErrNotTabular, and the streaming Encoder rejects a record
+// with keys outside its first record's header.
+func WithFlatten() EncodeOption {
+	return func(o *encodeOpts) { o.flatten = true }
+}
@@ -0,0 +1,246 @@
+package dataformat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the inferred type of a column.
+type ColumnType int
+
+const (
+	// ColumnNull means every sampled value was empty or null.
+	ColumnNull ColumnType = iota
+	// ColumnBool holds true/false (case-insensitive).
+	ColumnBool
+	// ColumnInt holds base-10 integers.
+	ColumnInt
+	// ColumnFloat holds decimal numbers; a column mixing ints and floats
+	// is a float column.
+	ColumnFloat
+	// ColumnTime holds RFC 3339 timestamps.
+	ColumnTime
+	// ColumnString holds anything else.
+	ColumnString
+)
+
+// String returns the type's lower-case name.
+func (t ColumnType) String() string {
+	switch t {
+	case ColumnNull:
+		return "null"
+	case ColumnBool:
+		return "bool"
+	case ColumnInt:
+		return "int"
+	case ColumnFloat:
+		return "float"
+	case ColumnTime:
+		return "time"
+	case ColumnString:
+		return "string"
+	default:
+		return "ColumnType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// Column is one column of a Schema.
+type Column struct {
+	Name string
+	Type ColumnType
+	// Nullable records that some sampled value was empty or null.
+	Nullable bool
+}
+
+// Schema lists the columns of tabular data in order.
+type Schema []Column
+
+// Column returns the column called name.
+func (s Schema) Column(name string) (Column, bool) {
+	for _, c := range s {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// InferSchema samples the first sample records of b (all of them when
+// sample <= 0) and infers a type per column: the narrowest of int,
+// float, bool and RFC 3339 time that fits every non-empty value, else
+// string. The records must be objects, as CSV rows and most JSON Lines
+// are; for CSV the columns keep header order, for other formats they
+// are sorted by name. Numbers with a leading zero, such as "007", are
+// strings: they are usually codes whose zero matters.
+//
+// The result feeds WithSchema; a value past the sample that does not
+// fit its column's type then fails the decode, so sample generously.
+// Pass the CSV dialect options the decode will use.
+func InferSchema(f Format, b []byte, sample int, opts ...DecodeOption) (Schema, error) {
+	dec, err := NewDecoder(f, bytes.NewReader(b), opts...)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]*Column{}
+	var order []string
+	for n := 0; sample <= 0 || n < sample; n++ {
+		rec, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, ok := asStringMap(rec)
+		if !ok {
+			return nil, &DecodeError{Format: f, Err: fmt.Errorf("%w: records must be objects", ErrNotTabular)}
+		}
+		for k, v := range m {
+			c := types[k]
+			if c == nil {
+				c = &Column{Name: k, Type: ColumnNull}
+				types[k] = c
+				order = append(order, k)
+			}
+			t := classifyValue(v)
+			if t == ColumnNull {
+				c.Nullable = true
+			}
+			c.Type = widenColumn(c.Type, t)
+		}
+	}
+	if dec.header != nil {
+		order = dec.header
+	} else {
+		sort.Strings(order)
+	}
+	s := make(Schema, 0, len(order))
+	for _, k := range order {
+		if c := types[k]; c != nil {
+			s = append(s, *c)
+		} else { // a CSV file with a header and no rows
+			s = append(s, Column{Name: k, Type: ColumnNull})
+		}
+	}
+	return s, nil
+}
+
+// widenColumn returns the narrowest type holding values of both a and b.
+func widenColumn(a, b ColumnType) ColumnType {
+	switch {
+	case a == b || b == ColumnNull:
+		return a
+	case a == ColumnNull:
+		return b
+	case (a == ColumnInt && b == ColumnFloat) || (a == ColumnFloat && b == ColumnInt):
+		return ColumnFloat
+	default:
+		return ColumnString
+	}
+}
+
+// classifyValue types a decoded value.
+func classifyValue(v any) ColumnType {
+	switch val := v.(type) {
+	case nil:
+		return ColumnNull
+	case string:
+		return classifyText(val)
+	case bool:
+		return ColumnBool
+	case int, int64:
+		return ColumnInt
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return ColumnInt
+		}
+		return ColumnFloat
+	case time.Time:
+		return ColumnTime
+	default:
+		return ColumnString
+	}
+}
+
+// classifyText types a text cell.
+func classifyText(s string) ColumnType {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return ColumnNull
+	case strings.EqualFold(s, "true") || strings.EqualFold(s, "false"):
+		return ColumnBool
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if leadingZero(s) {
+			return ColumnString
+		}
+		return ColumnInt
+	}
+	if x, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(x, 0) && !math.IsNaN(x) && !strings.ContainsAny(s, "xXpP_") {
+		if leadingZero(s) {
+			return ColumnString
+		}
+		return ColumnFloat
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ColumnTime
+	}
+	return ColumnString
+}
+
+// leadingZero reports a number written with a redundant leading zero,
+// like "007" (but not "0" or "0.5").
+func leadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
+
+// parseBool accepts true and false in any casing, as classifyText does,
+// besides strconv.ParseBool's forms.
+func parseBool(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "true"):
+		return true, nil
+	case strings.EqualFold(s, "false"):
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// typeCell converts a CSV cell to its column's type.
+func typeCell(cell string, c Column) (any, error) {
+	s := strings.TrimSpace(cell)
+	if c.Type == ColumnString || (c.Type == ColumnNull && s != "") {
+		return cell, nil
+	}
+	if s == "" {
+		return nil, nil
+	}
+	var (
+		v   any
+		err error
+	)
+	switch c.Type {
+	case ColumnBool:
+		v, err = parseBool(s)
+	case ColumnInt:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case ColumnFloat:
+		v, err = strconv.ParseFloat(s, 64)
+	case ColumnTime:
+		v, err = time.Parse(time.RFC3339Nano, s)
+	default:
+		return cell, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: column %q: cannot use %q as %s", ErrFieldType, c.Name, cell, c.Type)
+	}
+	return v, nil
+}
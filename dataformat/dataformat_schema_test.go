@@ -0,0 +1,211 @@
+package dataformat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectCSVDialect(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want CSVDialect
+		ok   bool
+	}{
+		{"comma", "a,b\n1,2\n", CSVDialect{Comma: ',', Header: true}, true},
+		{"semicolon", "a;b;c\n1,5;2;3\n", CSVDialect{Comma: ';', Header: true}, true},
+		{"tab", "a\tb\n1\t2\n", CSVDialect{Comma: '\t', Header: true}, true},
+		{"pipe", "a|b\nx|y\n", CSVDialect{Comma: '|', Header: true}, true},
+		{"headerless", "1;florin\n2;alice\n", CSVDialect{Comma: ';', Header: false}, true},
+		{"ragged", "a;b\n1;2;3\n", CSVDialect{Comma: ',', Header: true}, false},
+		{"one-column", "42\n43\n", CSVDialect{Comma: ',', Header: false}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := DetectCSVDialect([]byte(c.in))
+			if got != c.want || ok != c.ok {
+				t.Fatalf("DetectCSVDialect = %+v, %v; want %+v, %v", got, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestDecodeCSVDefaultsToCommaAndHeader(t *testing.T) {
+	// Header cells that look like data are still a header unless the
+	// caller asks for sniffing.
+	for in, want := range map[string][]any{
+		"2023,2024\n1,2\n": {map[string]any{"2023": "1", "2024": "2"}},
+		"true,false\nx,y":  {map[string]any{"true": "x", "false": "y"}},
+		"a;b\n1;2\n":       {map[string]any{"a;b": "1;2"}},
+	} {
+		v, err := Decode(CSV, []byte(in))
+		if err != nil || !reflect.DeepEqual(v, want) {
+			t.Errorf("Decode(CSV, %q) = %v, %v; want %v", in, v, err, want)
+		}
+	}
+}
+
+func TestDecodeCSVSniffsDialect(t *testing.T) {
+	v, err := Decode(CSV, []byte("1;florin\n2;alice\n"), WithCSVSniffing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{
+		map[string]any{"col1": "1", "col2": "florin"},
+		map[string]any{"col1": "2", "col2": "alice"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("headerless ';' file = %v", v)
+	}
+
+	v, err = Decode(CSV, []byte("1;florin\n"), WithCSVSniffing(), WithCSVDialect(CSVDialect{Comma: ';', Header: true}))
+	if err != nil || !reflect.DeepEqual(v, []any{}) {
+		t.Fatalf("an explicit dialect wins over sniffing: %v, %v", v, err)
+	}
+}
+
+func TestDetectFormatOptionsDecodesDetectedDialect(t *testing.T) {
+	in := []byte("name;city\nflorin;Cluj\nalice;Iasi\n")
+	f, opts, err := DetectFormatOptions(in)
+	if err != nil || f != CSV {
+		t.Fatalf("DetectFormatOptions = %v, %v", f, err)
+	}
+	want := []any{
+		map[string]any{"name": "florin", "city": "Cluj"},
+		map[string]any{"name": "alice", "city": "Iasi"},
+	}
+	if v, err := Decode(f, in, opts...); err != nil || !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode of detected ';' CSV = %v, %v; want %v", v, err, want)
+	}
+	out, err := Convert(f, JSON, in, opts...)
+	if err != nil || string(out) != `[{"city":"Cluj","name":"florin"},{"city":"Iasi","name":"alice"}]` {
+		t.Fatalf("Convert of detected ';' CSV = %s, %v", out, err)
+	}
+
+	if f, opts, err := DetectFormatOptions([]byte(`{"a":1}`)); err != nil || f != JSON || opts != nil {
+		t.Fatalf("JSON needs no options: %v, %v, %v", f, opts, err)
+	}
+}
+
+const schemaCSV = "id,name,score,active,seen,zip,note\n" +
+	"1,florin,9.5,true,2024-03-01T10:00:00Z,01234,\n" +
+	"2,alice,7,false,,02110,\n" +
+	"3,bob,,TRUE,2024-03-02T11:30:00+02:00,10001,\n"
+
+func TestInferSchemaCSV(t *testing.T) {
+	s, err := InferSchema(CSV, []byte(schemaCSV), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Schema{
+		{Name: "id", Type: ColumnInt},
+		{Name: "name", Type: ColumnString},
+		{Name: "score", Type: ColumnFloat, Nullable: true},
+		{Name: "active", Type: ColumnBool},
+		{Name: "seen", Type: ColumnTime, Nullable: true},
+		{Name: "zip", Type: ColumnString},
+		{Name: "note", Type: ColumnNull, Nullable: true},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("schema = %+v\nwant     %+v", s, want)
+	}
+
+	// Sampling only the first row misses the empty score below it.
+	s, _ = InferSchema(CSV, []byte(schemaCSV), 1)
+	if c, _ := s.Column("score"); c.Type != ColumnFloat || c.Nullable {
+		t.Fatalf("sampled score = %+v", c)
+	}
+}
+
+func TestInferSchemaJSONLines(t *testing.T) {
+	s, err := InferSchema(JSONLines, []byte("{\"n\":1,\"x\":1.5}\n{\"n\":2,\"x\":null,\"t\":\"ok\"}\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Schema{
+		{Name: "n", Type: ColumnInt},
+		{Name: "t", Type: ColumnString},
+		{Name: "x", Type: ColumnFloat, Nullable: true},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("schema = %+v", s)
+	}
+	if _, err := InferSchema(JSON, []byte(`[1,2]`), 0); !errors.Is(err, ErrNotTabular) {
+		t.Fatalf("scalar records: err = %v, want ErrNotTabular", err)
+	}
+}
+
+func TestDecodeWithSchema(t *testing.T) {
+	s, _ := InferSchema(CSV, []byte(schemaCSV), 0)
+	v, err := Decode(CSV, []byte(schemaCSV), WithSchema(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := v.([]any)
+	first := rows[0].(map[string]any)
+	want := map[string]any{
+		"id": int64(1), "name": "florin", "score": 9.5, "active": true,
+		"seen": time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), "zip": "01234", "note": nil,
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("row 1 = %#v", first)
+	}
+	if got := rows[1].(map[string]any)["seen"]; got != nil {
+		t.Fatalf("an empty typed cell should be nil, got %#v", got)
+	}
+
+	// Typed cells survive conversion to JSON as JSON types.
+	out, err := Encode(JSON, rows[2])
+	if err != nil || !strings.Contains(string(out), `"id":3`) || !strings.Contains(string(out), `"active":true`) {
+		t.Fatalf("JSON = %s, %v", out, err)
+	}
+
+	// Every casing InferSchema calls a bool decodes as one.
+	mixed := []byte("ok\ntRUE\nfALSE\nTrue\n")
+	s, _ = InferSchema(CSV, mixed, 0)
+	v, err = Decode(CSV, mixed, WithSchema(s))
+	if err != nil {
+		t.Fatalf("mixed-case bools: %v", err)
+	}
+	if want := []any{map[string]any{"ok": true}, map[string]any{"ok": false}, map[string]any{"ok": true}}; !reflect.DeepEqual(v, want) {
+		t.Fatalf("mixed-case bools = %v", v)
+	}
+
+	_, err = Decode(CSV, []byte("id\n1\nx\n"), WithSchema(Schema{{Name: "id", Type: ColumnInt}}))
+	var de *DecodeError
+	if !errors.Is(err, ErrFieldType) || !errors.As(err, &de) || !strings.Contains(err.Error(), "record 2") {
+		t.Fatalf("err = %v, want a *DecodeError wrapping ErrFieldType for record 2", err)
+	}
+}
+
+func TestEncodeCSVWithFlatten(t *testing.T) {
+	rows := []any{
+		map[string]any{"id": 1, "user": map[string]any{"name": "florin", "geo": map[string]any{"city": "bucharest"}}},
+		map[string]any{"id": 2, "extra": "x"},
+	}
+	want := "extra,id,user.geo.city,user.name\n,1,bucharest,florin\nx,2,,\n"
+	out, err := Encode(CSV, rows, WithFlatten())
+	if err != nil || string(out) != want {
+		t.Fatalf("Encode = %q, %v; want %q", out, err, want)
+	}
+
+	out, err = Encode(CSV, map[string]any{"a": map[string]any{"b": "c"}}, WithFlatten())
+	if err != nil || string(out) != "a.b\nc\n" {
+		t.Fatalf("lone object = %q, %v", out, err)
+	}
+
+	// The streaming Encoder unions sparse keys instead of failing.
+	var buf strings.Builder
+	enc, _ := NewEncoder(CSV, &buf, WithFlatten())
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil || buf.String() != want {
+		t.Fatalf("stream = %q, %v", buf.String(), err)
+	}
+}
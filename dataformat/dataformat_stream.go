@@ -0,0 +1,498 @@
+package dataformat
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Decoder reads a document one record at a time. For the streaming
+// formats a record is:
+//
+//   - CSV: one data row, as a map[string]any keyed by the header row
+//     (every cell a string unless WithSchema types it, exactly as Decode
+//     produces). The file is comma-separated with a header row unless
+//     WithCSVDialect sets the dialect or WithCSVSniffing detects it;
+//   - JSONLines: one line's value;
+//   - JSON: one element of a top-level array, or the whole document
+//     when it is not an array.
+//
+// Only the current record is held in memory. YAML, TOML and XML have no
+// record boundary a reader can find without parsing the whole document,
+// so for them the Decoder reads the input fully on the first Next and
+// then yields the elements of a top-level array (or the single value).
+//
+// Parse failures are returned as *DecodeError; the end of input is io.EOF.
+type Decoder struct {
+	f      Format
+	next   func() (any, error)
+	header []string // CSV column names, once the first record is read
+}
+
+// NewDecoder returns a Decoder reading f-formatted records from r.
+func NewDecoder(f Format, r io.Reader, opts ...DecodeOption) (*Decoder, error) {
+	d := &Decoder{f: f}
+	switch f {
+	case CSV:
+		d.next = d.csvRecords(r, newDecodeOpts(opts))
+	case JSONLines:
+		d.next = jsonLinesRecords(r, newDecodeOpts(opts).useNumber)
+	case JSON:
+		d.next = jsonRecords(r)
+	case YAML, TOML, XML:
+		d.next = bufferedRecords(f, r)
+	default:
+		return nil, &DecodeError{Format: f, Err: fmt.Errorf("%w: %s", ErrUnknownFormat, f)}
+	}
+	return d, nil
+}
+
+// Next returns the next record, or io.EOF once the input is exhausted.
+func (d *Decoder) Next() (any, error) {
+	v, err := d.next()
+	if err != nil && err != io.EOF {
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			err = &DecodeError{Format: d.f, Err: err}
+		}
+		d.next = func() (any, error) { return nil, err }
+	}
+	return v, err
+}
+
+func (d *Decoder) csvRecords(r io.Reader, o decodeOpts) func() (any, error) {
+	var (
+		cr      *csv.Reader
+		pending []string  // the first row of a headerless file
+		cols    []*Column // schema column per header index, nil if untyped
+		n       int
+	)
+	return func() (any, error) {
+		if cr == nil {
+			br := bufio.NewReaderSize(r, csvSniffSize)
+			dialect := o.dialect
+			switch {
+			case dialect != nil:
+			case o.sniff:
+				sample, err := br.Peek(csvSniffSize)
+				sniffed, _ := sniffCSV(sample, err != nil)
+				dialect = &sniffed
+			default:
+				dialect = &CSVDialect{Comma: ',', Header: true}
+			}
+			cr = csv.NewReader(br)
+			cr.Comma = dialect.Comma
+			first, err := cr.Read()
+			if err != nil {
+				return nil, err
+			}
+			if dialect.Header {
+				d.header = first
+			} else {
+				d.header, pending = csvColumnNames(len(first)), first
+			}
+			cols = make([]*Column, len(d.header))
+			for i, h := range d.header {
+				if c, ok := o.schema.Column(h); ok {
+					cols[i] = &c
+				}
+			}
+		}
+		rec := pending
+		if rec != nil {
+			pending = nil
+		} else {
+			var err error
+			if rec, err = cr.Read(); err != nil {
+				return nil, err
+			}
+		}
+		n++
+		row := make(map[string]any, len(d.header))
+		for i, h := range d.header {
+			cell := ""
+			if i < len(rec) {
+				cell = rec[i]
+			}
+			if cols[i] == nil {
+				row[h] = cell
+				continue
+			}
+			v, err := typeCell(cell, *cols[i])
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %w", n, err)
+			}
+			row[h] = v
+		}
+		return row, nil
+	}
+}
+
+func jsonLinesRecords(r io.Reader, useNumber bool) func() (any, error) {
+	dec := json.NewDecoder(r)
+	if useNumber {
+		dec.UseNumber()
+	}
+	return func() (any, error) {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// jsonRecords streams the elements of a top-level JSON array, falling
+// back to a single record for any other document.
+func jsonRecords(r io.Reader) func() (any, error) {
+	br := bufio.NewReader(r)
+	var dec *json.Decoder
+	inArray, done := false, false
+	return func() (any, error) {
+		if done {
+			return nil, io.EOF
+		}
+		if dec == nil {
+			first, err := peekNonSpace(br)
+			if err != nil {
+				if err == io.EOF {
+					err = ErrEmptyInput
+				}
+				return nil, err
+			}
+			dec = json.NewDecoder(br)
+			if first == '[' {
+				if _, err := dec.Token(); err != nil {
+					return nil, err
+				}
+				inArray = true
+			}
+		}
+		if !inArray {
+			done = true
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+		if !dec.More() {
+			done = true
+			if _, err := dec.Token(); err != nil { // closing ']'
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// bufferedRecords decodes the whole document on first use and yields the
+// elements of a top-level array, or the value itself.
+func bufferedRecords(f Format, r io.Reader) func() (any, error) {
+	var recs []any
+	loaded := false
+	return func() (any, error) {
+		if !loaded {
+			loaded = true
+			b, err := io.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+			v, err := Decode(f, b)
+			if err != nil {
+				return nil, err
+			}
+			if arr, ok := v.([]any); ok {
+				recs = arr
+			} else {
+				recs = []any{v}
+			}
+		}
+		if len(recs) == 0 {
+			return nil, io.EOF
+		}
+		v := recs[0]
+		recs = recs[1:]
+		return v, nil
+	}
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, br.UnreadByte()
+	}
+}
+
+// Encoder writes a document one record at a time; Close finishes it.
+// For the streaming formats each Encode writes through immediately:
+//
+//   - CSV: the first record fixes the columns. An object's sorted keys
+//     become the header row, and a later object with a key outside it
+//     returns ErrNotTabular (a stream cannot go back and widen the
+//     header). Missing keys are written as empty cells. An array record
+//     is written as a raw row with no header.
+//   - JSONLines: one line per record.
+//   - JSON: the records become the elements of a top-level array.
+//
+// With WithFlatten the CSV Encoder buffers too, since a header that
+// unions every record's columns is only known once all are seen.
+//
+// YAML, TOML and XML are buffered and written by Close: a single record
+// as the document itself, several as one []any passed to Encode (which
+// TOML and XML reject with ErrUnsupportedShape).
+//
+// Encode failures are returned as *EncodeError.
+type Encoder struct {
+	f       Format
+	w       io.Writer
+	flatten bool
+	csv     *csv.Writer
+	header  []string
+	rawCSV  bool
+	n       int
+	recs    []any
+	closed  bool
+}
+
+// NewEncoder returns an Encoder writing f-formatted records to w.
+func NewEncoder(f Format, w io.Writer, opts ...EncodeOption) (*Encoder, error) {
+	switch f {
+	case CSV, JSONLines, JSON, YAML, TOML, XML:
+	default:
+		return nil, &EncodeError{Format: f, Err: fmt.Errorf("%w: %s", ErrUnknownFormat, f)}
+	}
+	e := &Encoder{f: f, w: w, flatten: newEncodeOpts(opts).flatten}
+	if f == CSV {
+		e.csv = csv.NewWriter(w)
+	}
+	return e, nil
+}
+
+// Encode writes one record.
+func (e *Encoder) Encode(v any) error {
+	if e.closed {
+		return &EncodeError{Format: e.f, Err: errors.New("encoder closed")}
+	}
+	var err error
+	switch e.f {
+	case CSV:
+		if e.flatten {
+			e.recs = append(e.recs, v)
+		} else {
+			err = e.encodeCSVRecord(v)
+		}
+	case JSONLines:
+		var b []byte
+		if b, err = json.Marshal(v); err == nil {
+			_, err = e.w.Write(append(b, '\n'))
+		}
+	case JSON:
+		var b []byte
+		if b, err = json.Marshal(v); err == nil {
+			sep := ","
+			if e.n == 0 {
+				sep = "["
+			}
+			if _, err = io.WriteString(e.w, sep); err == nil {
+				_, err = e.w.Write(b)
+			}
+		}
+	default:
+		e.recs = append(e.recs, v)
+	}
+	if err != nil {
+		return &EncodeError{Format: e.f, Err: err}
+	}
+	e.n++
+	return nil
+}
+
+func (e *Encoder) encodeCSVRecord(v any) error {
+	if cells, ok := v.([]any); ok {
+		if e.n > 0 && !e.rawCSV {
+			return fmt.Errorf("%w: mixed row types", ErrNotTabular)
+		}
+		e.rawCSV = true
+		rec := make([]string, len(cells))
+		for i, c := range cells {
+			rec[i] = scalarToString(c)
+		}
+		return e.writeCSV(rec)
+	}
+	m, ok := asStringMap(v)
+	if !ok || e.rawCSV {
+		return fmt.Errorf("%w: records must be objects or arrays, not mixed", ErrNotTabular)
+	}
+	if e.header == nil {
+		e.header = make([]string, 0, len(m))
+		for k := range m {
+			e.header = append(e.header, k)
+		}
+		sort.Strings(e.header)
+		if err := e.writeCSV(e.header); err != nil {
+			return err
+		}
+	}
+	rec := make([]string, len(e.header))
+	seen := 0
+	for i, h := range e.header {
+		if val, present := m[h]; present {
+			rec[i] = scalarToString(val)
+			seen++
+		}
+	}
+	if seen != len(m) {
+		return fmt.Errorf("%w: record has keys outside the header %v", ErrNotTabular, e.header)
+	}
+	return e.writeCSV(rec)
+}
+
+func (e *Encoder) writeCSV(rec []string) error { return e.csv.Write(rec) }
+
+// Close finishes the document: it flushes buffered CSV rows, closes a
+// JSON array (writing "[]" when no record was encoded) and writes the
+// buffered formats. It does not close the underlying writer. Idempotent.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	var err error
+	switch e.f {
+	case CSV:
+		if e.flatten {
+			var b []byte
+			if b, err = encodeCSV(flattenRows(e.recs)); err == nil {
+				_, err = e.w.Write(b)
+			}
+			break
+		}
+		e.csv.Flush()
+		err = e.csv.Error()
+	case JSON:
+		if e.n == 0 {
+			_, err = io.WriteString(e.w, "[]")
+		} else {
+			_, err = io.WriteString(e.w, "]")
+		}
+	case YAML, TOML, XML:
+		recs := e.recs
+		if recs == nil {
+			recs = []any{}
+		}
+		var v any = recs
+		if len(recs) == 1 {
+			v = recs[0]
+		}
+		var b []byte
+		if b, err = Encode(e.f, v); err != nil {
+			return err
+		}
+		_, err = e.w.Write(b)
+	}
+	if err != nil {
+		return &EncodeError{Format: e.f, Err: err}
+	}
+	return nil
+}
+
+// ConvertStream copies every record of r, read as from, to w as to,
+// holding only one record in memory. That needs both formats to stream
+// (CSV, JSONLines, JSON); with YAML, TOML or XML on either side the input
+// is read whole and passed through Convert. A JSON source that is not a
+// top-level array is a single record, so JSON to JSON wraps it in an
+// array. opts apply to the decode step.
+func ConvertStream(from, to Format, r io.Reader, w io.Writer, opts ...DecodeOption) error {
+	if !streams(from) || !streams(to) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return &DecodeError{Format: from, Err: err}
+		}
+		out, err := Convert(from, to, b, opts...)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(out); err != nil {
+			return &EncodeError{Format: to, Err: err}
+		}
+		return nil
+	}
+	dec, err := NewDecoder(from, r, opts...)
+	if err != nil {
+		return err
+	}
+	enc, err := NewEncoder(to, w)
+	if err != nil {
+		return err
+	}
+	for {
+		v, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+func streams(f Format) bool { return f == CSV || f == JSONLines || f == JSON }
+
+// decodeAll collects every record of r as an []any.
+func decodeAll(f Format, r io.Reader, opts ...DecodeOption) (any, error) {
+	dec, err := NewDecoder(f, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for {
+		v, err := dec.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+}
+
+// encodeAll writes v to w as f: the elements of an array as records, any
+// other value as a single record.
+func encodeAll(f Format, w io.Writer, v any, opts ...EncodeOption) error {
+	enc, err := NewEncoder(f, w, opts...)
+	if err != nil {
+		return err
+	}
+	recs, ok := v.([]any)
+	if !ok {
+		recs = []any{v}
+	}
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
@@ -0,0 +1,121 @@
+package dataformat
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderCSVOneRowAtATime(t *testing.T) {
+	dec, err := NewDecoder(CSV, strings.NewReader("name,age\nflorin,30\nalice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := dec.Next()
+	if err != nil || !reflect.DeepEqual(v, map[string]any{"name": "florin", "age": "30"}) {
+		t.Fatalf("first row = %v, %v", v, err)
+	}
+	// The short second row is a parse error, as in Decode.
+	var de *DecodeError
+	if _, err := dec.Next(); !errors.As(err, &de) || de.Format != CSV {
+		t.Fatalf("short row: err = %v, want *DecodeError", err)
+	}
+	if _, err := dec.Next(); !errors.As(err, &de) {
+		t.Fatalf("the error should stick: %v", err)
+	}
+}
+
+func TestDecoderJSONArrayElements(t *testing.T) {
+	dec, _ := NewDecoder(JSON, strings.NewReader(` [{"a":1}, {"a":2}] `))
+	var got []any
+	for {
+		v, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if want := []any{map[string]any{"a": 1.0}, map[string]any{"a": 2.0}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("records = %v", got)
+	}
+
+	dec, _ = NewDecoder(JSON, strings.NewReader(`{"only":true}`))
+	if v, err := dec.Next(); err != nil || !reflect.DeepEqual(v, map[string]any{"only": true}) {
+		t.Fatalf("non-array document = %v, %v", v, err)
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Fatalf("after the single record: %v, want io.EOF", err)
+	}
+}
+
+func TestJSONLinesDecodeEncode(t *testing.T) {
+	v, err := Decode(JSONLines, []byte("{\"a\":1}\n\n{\"a\":2}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{map[string]any{"a": 1.0}, map[string]any{"a": 2.0}}; !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode = %v", v)
+	}
+	out, err := Encode(JSONLines, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "{\"a\":1}\n{\"a\":2}\n" {
+		t.Fatalf("Encode = %q", out)
+	}
+}
+
+func TestConvertCSVToJSONLines(t *testing.T) {
+	out, err := Convert(CSV, JSONLines, []byte("name,age\nflorin,30\nalice,28\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"age\":\"30\",\"name\":\"florin\"}\n{\"age\":\"28\",\"name\":\"alice\"}\n"
+	if string(out) != want {
+		t.Fatalf("Convert = %q, want %q", out, want)
+	}
+}
+
+func TestConvertStream(t *testing.T) {
+	cases := []struct {
+		name     string
+		from, to Format
+		in, want string
+	}{
+		{"csv-json", CSV, JSON, "a,b\n1,2\n3,4\n", `[{"a":"1","b":"2"},{"a":"3","b":"4"}]`},
+		{"jsonl-csv", JSONLines, CSV, "{\"a\":1,\"b\":\"x\"}\n{\"a\":2}\n", "a,b\n1,x\n2,\n"},
+		{"json-jsonl", JSON, JSONLines, `[1,"two",null]`, "1\n\"two\"\nnull\n"},
+		{"empty-csv-json", CSV, JSON, "", "[]"},
+		{"buffered-yaml", JSONLines, YAML, "{\"a\":1}\n", "- a: 1\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ConvertStream(c.from, c.to, strings.NewReader(c.in), &buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != c.want {
+				t.Fatalf("got %q, want %q", buf.String(), c.want)
+			}
+		})
+	}
+}
+
+func TestEncoderCSVRejectsWidenedHeader(t *testing.T) {
+	var buf bytes.Buffer
+	enc, _ := NewEncoder(CSV, &buf)
+	if err := enc.Encode(map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	err := enc.Encode(map[string]any{"a": 2, "b": 3})
+	var ee *EncodeError
+	if !errors.Is(err, ErrNotTabular) || !errors.As(err, &ee) {
+		t.Fatalf("err = %v, want *EncodeError wrapping ErrNotTabular", err)
+	}
+}
@@ -39,6 +39,8 @@ func TestParseFormat(t *testing.T) {
 		{"yml", YAML, false},
 		{"toml", TOML, false},
 		{"application/toml", TOML, false},
+		{"ndjson", JSONLines, false},
+		{"application/x-ndjson", JSONLines, false},
 		{"protobuf", 0, true},
 		{"", 0, true},
 	}
@@ -74,6 +76,7 @@ func TestFormatString(t *testing.T) {
 		{XML, "xml"},
 		{YAML, "yaml"},
 		{TOML, "toml"},
+		{JSONLines, "jsonl"},
 		{Format(99), "Format(99)"},
 	}
 	for _, c := range cases {
@@ -94,11 +97,15 @@ func TestDetectFormat(t *testing.T) {
 	}{
 		{"json-object", `{"a":1,"b":"x"}`, JSON, nil},
 		{"json-array", `[1,2,3]`, JSON, nil},
+		{"jsonl", "{\"a\":1}\n{\"a\":2}\n", JSONLines, nil},
 		{"xml-decl", `<?xml version="1.0"?><root><a>1</a></root>`, XML, nil},
 		{"xml-bare", `<root><a>1</a></root>`, XML, nil},
 		{"toml-table", "[server]\nhost = \"x\"\nport = 80\n", TOML, nil},
 		{"toml-keyval", "name = \"florin\"\nage = 30\n", TOML, nil},
 		{"csv", "name,age\nflorin,30\nalice,28\n", CSV, nil},
+		{"csv-semicolon", "name;age\nflorin;30\n", CSV, nil},
+		{"tsv", "name\tage\nflorin\t30\n", CSV, nil},
+		{"csv-pipe", "name|age\nflorin|30\n", CSV, nil},
 		{"yaml-map", "name: florin\nage: 30\n", YAML, nil},
 		{"yaml-list", "- a\n- b\n- c\n", YAML, nil},
 		{"empty", "   \n  ", 0, ErrEmptyInput},
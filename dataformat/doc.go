@@ -1,17 +1,28 @@
 // Package dataformat provides bidirectional conversion primitives between
-// the structured data formats commonly seen across the fleet: JSON, CSV,
-// XML, YAML and TOML.
+// the structured data formats commonly seen across the fleet: JSON, JSON
+// Lines (NDJSON), CSV, XML, YAML and TOML.
 //
 // The package exposes a small, format-agnostic surface:
 //
 //	DetectFormat(b []byte) (Format, error)   // best-effort sniff
+//	DetectFormatOptions(b []byte) (Format, []DecodeOption, error)
 //	Decode(f Format, b []byte) (any, error)  // bytes  -> Go value
 //	Encode(f Format, v any) ([]byte, error)  // Go value -> bytes
 //	Convert(from, to Format, b []byte) ([]byte, error)
+//	DecodeInto(f Format, b []byte, dst any, ...DecodeOption) error // bytes -> struct
+//
+// and a streaming one over io.Reader/io.Writer for inputs too large to
+// hold in memory, one record at a time:
+//
+//	NewDecoder(f, r) / (*Decoder).Next() (any, error)
+//	NewEncoder(f, w) / (*Encoder).Encode(v) / Close()
+//	ConvertStream(from, to Format, r io.Reader, w io.Writer) error
 //
 // Decode always yields a "generic" Go value built from map[string]any,
 // []any and scalars (string/float64/bool/nil), so a value decoded from one
 // format can be re-encoded into any other without a concrete struct.
+// DecodeInto maps that value onto a typed destination instead, coercing
+// the text cells of CSV and XML to the field types.
 //
 // # Lossy edges
 //
@@ -22,8 +33,14 @@
 //     with consistent (string-keyed) fields, or an array of arrays. Decoding
 //     CSV infers column names from the header row and produces an
 //     []any of map[string]any rows; every cell value is a string (CSV has
-//     no type system). Converting a non-tabular value to CSV returns
-//     ErrNotTabular.
+//     no type system) unless WithSchema types the columns, typically with
+//     a schema from InferSchema. Decoding assumes commas and a header
+//     row; WithCSVDialect or WithCSVSniffing handle ';', tab or '|'
+//     delimiters and headerless files (DetectCSVDialect), and
+//     DetectFormatOptions returns the dialect it detected.
+//     Converting a non-tabular value to CSV returns ErrNotTabular;
+//     WithFlatten widens what counts as tabular by flattening nested
+//     objects into dotted columns.
 //
 //   - XML has no native arrays or a typed scalar model, and it distinguishes
 //     attributes from child elements. Decode unmarshals XML into a
@@ -38,6 +55,10 @@
 //     array or scalar as a document. Encoding such a value returns
 //     ErrUnsupportedShape.
 //
+//   - JSON Lines is a sequence of records: Decode yields an []any of the
+//     lines' values, and Encode writes each element of an array (or a
+//     non-array value) as one line.
+//
 // All shape/parse failures are reported through typed errors (see the
 // Err* sentinels and the *Error types) so callers can branch with
 // errors.Is / errors.As.
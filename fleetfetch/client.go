@@ -8,10 +8,9 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/baditaflorin/go-common/retry"
 	"github.com/baditaflorin/go-common/safehttp"
 )
 
@@ -165,28 +164,17 @@ func (e *RenderBusyError) Unwrap() error { return ErrRenderBusy }
 
 // RetryDelay converts Retry-After into a non-negative delay. Both forms
 // allowed by RFC 9110 are accepted: integer seconds and an HTTP date.
+// It satisfies retry.Delayer, so retry.Do waits for the renderer instead
+// of its own backoff.
 func (e *RenderBusyError) RetryDelay(now time.Time) (time.Duration, bool) {
-	if e == nil || strings.TrimSpace(e.RetryAfter) == "" {
-		return 0, false
-	}
-	value := strings.TrimSpace(e.RetryAfter)
-	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
-		if seconds < 0 {
-			seconds = 0
-		}
-		return time.Duration(seconds) * time.Second, true
-	}
-	when, err := http.ParseTime(value)
-	if err != nil {
+	if e == nil {
 		return 0, false
 	}
-	delay := when.Sub(now)
-	if delay < 0 {
-		delay = 0
-	}
-	return delay, true
+	return retry.ParseRetryAfter(e.RetryAfter, now)
 }
 
+var _ retry.Delayer = (*RenderBusyError)(nil)
+
 // Option configures a Client.
 type Option func(*Client)
 
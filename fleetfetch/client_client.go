@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/baditaflorin/go-common/header"
+	"github.com/baditaflorin/go-common/hedge"
 	"github.com/baditaflorin/go-common/loadshed"
 	"github.com/baditaflorin/go-common/safehttp"
 )
@@ -67,6 +68,10 @@ type Client struct {
 	// client (normally a process restart after credential rotation) starts
 	// with a closed circuit.
 	authRejected atomic.Int32
+
+	// hedger, when set via WithHedging, duplicates slow Get-family
+	// calls. nil = one call per Get.
+	hedger *hedge.Hedger
 }
 
 // Get fetches targetURL through the cache. Genuine cache-service/network
@@ -74,13 +79,13 @@ type Client struct {
 // are returned as-is, and typed load shedding for rendered modes returns
 // ErrRenderBusy rather than silently substituting unrendered bytes.
 func (c *Client) Get(ctx context.Context, targetURL string) (*Response, error) {
-	return c.fetch(ctx, targetURL, 0, nil, c.render)
+	return c.hedgedFetch(ctx, targetURL, 0, nil, c.render)
 }
 
 // GetWithMaxAge is Get with an explicit max-age override (in seconds
 // on the wire). maxAge=0 = use cache default (60s).
 func (c *Client) GetWithMaxAge(ctx context.Context, targetURL string, maxAge time.Duration) (*Response, error) {
-	return c.fetch(ctx, targetURL, maxAge, nil, c.render)
+	return c.hedgedFetch(ctx, targetURL, maxAge, nil, c.render)
 }
 
 // GetWithHeaders is Get with per-request headers forwarded to the
@@ -93,7 +98,7 @@ func (c *Client) GetWithMaxAge(ctx context.Context, targetURL string, maxAge tim
 // first; per-request headers win on collisions. Pass nil to get the
 // same behavior as Get.
 func (c *Client) GetWithHeaders(ctx context.Context, targetURL string, headers http.Header) (*Response, error) {
-	return c.fetch(ctx, targetURL, 0, headers, c.render)
+	return c.hedgedFetch(ctx, targetURL, 0, headers, c.render)
 }
 
 // GetRendered fetches targetURL with a per-call render override. Pass
@@ -101,7 +106,7 @@ func (c *Client) GetWithHeaders(ctx context.Context, targetURL string, headers h
 // default doesn't fit a particular call (e.g. a JS-rendering client
 // that needs a single cheap default-mode lookup).
 func (c *Client) GetRendered(ctx context.Context, targetURL string, mode string) (*Response, error) {
-	return c.fetch(ctx, targetURL, 0, nil, mode)
+	return c.hedgedFetch(ctx, targetURL, 0, nil, mode)
 }
 
 // FetchNetwork fetches targetURL with render=js-network and returns the
@@ -127,13 +132,23 @@ func (c *Client) GetRendered(ctx context.Context, targetURL string, mode string)
 // network log — the direct SSRF fetch can't capture one — so the slice is
 // nil. The error is non-nil only on a genuine fetch failure.
 func (c *Client) FetchNetwork(ctx context.Context, targetURL string) (*Response, []NetworkEntry, error) {
-	resp, err := c.fetch(ctx, targetURL, 0, nil, RenderJSNetwork)
+	resp, err := c.hedgedFetch(ctx, targetURL, 0, nil, RenderJSNetwork)
 	if err != nil {
 		return resp, nil, err
 	}
 	return resp, parseNetworkLog(resp.Header.Get(NetworkHeader)), nil
 }
 
+// hedgedFetch is fetch, hedged when the client was built WithHedging.
+func (c *Client) hedgedFetch(ctx context.Context, targetURL string, maxAge time.Duration, perReqHeaders http.Header, render string) (*Response, error) {
+	if c.hedger == nil {
+		return c.fetch(ctx, targetURL, maxAge, perReqHeaders, render)
+	}
+	return hedge.Do(ctx, c.hedger, hostOf(targetURL), func(ctx context.Context) (*Response, error) {
+		return c.fetch(ctx, targetURL, maxAge, perReqHeaders, render)
+	})
+}
+
 // fetch is the shared implementation behind Get/GetWithMaxAge/GetWithHeaders.
 func (c *Client) fetch(ctx context.Context, targetURL string, maxAge time.Duration, perReqHeaders http.Header, render string) (fetchRes *Response, retErr error) {
 	if targetURL == "" {
@@ -148,6 +163,10 @@ func (c *Client) fetch(ctx context.Context, targetURL string, maxAge time.Durati
 		// cache dashboard. "direct" = the direct fetch returned a response;
 		// "direct_error" = the direct fetch itself failed (DNS / transport /
 		// timeout to origin, common when probing speculative URLs).
+		// The other call of a hedged pair won; this one was cancelled
+		// on purpose and is neither an error nor cache traffic.
+		case retErr != nil && errors.Is(context.Cause(ctx), hedge.ErrLost):
+			ev.Result = "hedge_lost"
 		case c.noCache && retErr != nil:
 			ev.Result = "direct_error"
 		case c.noCache:
@@ -250,7 +269,9 @@ func (c *Client) fetch(ctx context.Context, targetURL string, maxAge time.Durati
 		// Caller's own context expired/cancelled — they gave up.
 		// Don't fall back or direct-fetch; just propagate.
 		if ctx.Err() != nil {
-			c.errs.Add(1)
+			if !errors.Is(context.Cause(ctx), hedge.ErrLost) {
+				c.errs.Add(1)
+			}
 			return nil, fmt.Errorf("fleetfetch: caller context done: %w", ctx.Err())
 		}
 		// Cache reachable but slow (our per-request deadline fired, not
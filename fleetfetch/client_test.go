@@ -7,11 +7,15 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/baditaflorin/go-common/header"
+	"github.com/baditaflorin/go-common/hedge"
 	"github.com/baditaflorin/go-common/safehttp"
 )
 
@@ -542,3 +546,62 @@ func TestWithoutCache_EmitsDirectResultLabels(t *testing.T) {
 		t.Errorf("failure event: got result=%q, want %q", obs.events[1].Result, "direct_error")
 	}
 }
+
+func TestGet_HedgedFetchTakesFasterReply(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-FetchCache-Hit", "true")
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var results []string
+	var mu sync.Mutex
+	SetDefaultObserver(observerFunc(func(ev Event) {
+		mu.Lock()
+		results = append(results, ev.Result)
+		mu.Unlock()
+	}))
+	defer SetDefaultObserver(nil)
+
+	c := NewClient(WithCacheURL(srv.URL), WithHedging(hedge.Options{Delay: 20 * time.Millisecond, MaxRate: 1}))
+	start := time.Now()
+	r, err := c.Get(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(r.Body) != "ok" || time.Since(start) > 2*time.Second {
+		t.Fatalf("body=%q after %v, want the hedge's reply", r.Body, time.Since(start))
+	}
+	// The cancelled loser reports hedge_lost, not error.
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(results)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 2 || !slices.Contains(results, "hedge_lost") || !slices.Contains(results, "hit") {
+		t.Fatalf("results = %v, want one hit and one hedge_lost", results)
+	}
+	if s := c.Stats(); s.Errors != 0 {
+		t.Fatalf("hedge loser must not count as an error: %+v", s)
+	}
+}
+
+type observerFunc func(Event)
+
+func (f observerFunc) ObserveFleetFetch(ev Event) { f(ev) }
@@ -3,6 +3,8 @@ package fleetfetch
 import (
 	"net/http"
 	"time"
+
+	"github.com/baditaflorin/go-common/hedge"
 )
 
 // WithCacheURL overrides the cache endpoint. Default order:
@@ -108,3 +110,15 @@ func WithDefaultHeaders(h http.Header) Option {
 		c.defaultHeaders = h.Clone()
 	}
 }
+
+// WithHedging hedges Get-family calls: when a fetch has not answered
+// within opts.Delay (or the host's observed p95), a second identical
+// fetch is sent and the first to succeed wins; the other is cancelled.
+// Fetches are GETs, so duplicating one is safe; the hedge rate is capped
+// by opts.MaxRate. The cancelled loser emits fleet_fetch_total with
+// result="hedge_lost" rather than "error", and is not counted in
+// Stats().Errors. Hedges issued/won are reported through the hedge
+// package's default observer.
+func WithHedging(opts hedge.Options) Option {
+	return func(c *Client) { c.hedger = hedge.New(opts) }
+}
@@ -47,6 +47,8 @@ type Observer interface {
 //	"direct_error" — WithoutCache client: the direct fetch itself failed
 //	              (DNS / transport / timeout to origin). Distinct from
 //	              "error" — the cache was never involved.
+//	"hedge_lost" — WithHedging client: the other fetch of a hedged pair
+//	              won and this one was cancelled on purpose.
 type Event struct {
 	Host       string // hostname of the targetURL (not the cache)
 	Result     string
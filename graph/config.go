@@ -5,12 +5,14 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
 )
 
 type config struct {
 	enabled       bool
 	collectorURL  string
-	apiKey        string
+	auth          coordauth.Credentials
 	sampleRate    float64
 	bufferSize    int
 	flushInterval time.Duration
@@ -19,10 +21,11 @@ type config struct {
 
 // loadConfig reads env vars once. Called from initOnce.
 func loadConfig() config {
+	ep := coordauth.FromEnv("GRAPH_COLLECTOR", "", "GRAPH_API_KEY")
 	c := config{
 		enabled:       parseBoolEnv("GRAPH_ENABLED", true),
-		collectorURL:  strings.TrimRight(os.Getenv("GRAPH_COLLECTOR_URL"), "/"),
-		apiKey:        os.Getenv("GRAPH_API_KEY"),
+		collectorURL:  ep.URL,
+		auth:          ep.Credentials,
 		sampleRate:    parseFloatEnv("GRAPH_SAMPLE_RATE", 1.0),
 		bufferSize:    parseIntEnv("GRAPH_BUFFER_SIZE", 10000),
 		flushInterval: time.Duration(parseIntEnv("GRAPH_FLUSH_INTERVAL", 10)) * time.Second,
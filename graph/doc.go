@@ -20,7 +20,10 @@
 //	GRAPH_ENABLED        — default "true". "false" disables entirely.
 //	GRAPH_COLLECTOR_URL  — e.g. "https://go-fleet-graph.0exec.com".
 //	GRAPH_SAMPLE_RATE    — float 0..1, default 1.0.
-//	GRAPH_API_KEY        — fleet key sent as X-API-Key to the collector.
+//	GRAPH_API_KEY        — key sent as X-API-Key to the collector
+//	                       (else GRAPH_COLLECTOR_API_KEY).
+//	GRAPH_COLLECTOR_SIGNING_SECRET — optional,
+//	                       HMAC-signs each batch and lookup (see coordauth).
 //	GRAPH_BUFFER_SIZE    — ring capacity (default 10000 events).
 //	GRAPH_FLUSH_INTERVAL — flush cadence in seconds (default 10).
 //	GRAPH_FLUSH_BATCH    — max events per flush (default 500).
@@ -8,8 +8,6 @@ import (
 	"net/http"
 	"sync"
 	"time"
-
-	"github.com/baditaflorin/go-common/header"
 )
 
 // ErrNotConfigured is returned by Lookup when GRAPH_COLLECTOR_URL is
@@ -72,8 +70,8 @@ func LookupCtx(ctx context.Context, slug string) (Service, error) {
 	if err != nil {
 		return Service{}, err
 	}
-	if s.cfg.apiKey != "" {
-		req.Header.Set(header.APIKey, s.cfg.apiKey)
+	if err := s.cfg.auth.Sign(req, nil); err != nil {
+		return Service{}, err
 	}
 	client := &http.Client{Timeout: 3 * time.Second}
 	resp, err := client.Do(req)
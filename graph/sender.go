@@ -7,8 +7,6 @@ import (
 	"net/http"
 	"sync/atomic"
 	"time"
-
-	"github.com/baditaflorin/go-common/header"
 )
 
 // sender runs a background goroutine that periodically drains the ring
@@ -100,8 +98,9 @@ func (s *sender) send(events []Event) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "go-common-graph/"+s.version+" ("+s.serviceID+")")
-	if s.cfg.apiKey != "" {
-		req.Header.Set(header.APIKey, s.cfg.apiKey)
+	if err := s.cfg.auth.Sign(req, body); err != nil {
+		atomic.AddInt64(&s.counters.BatchesFailed, 1)
+		return
 	}
 	resp, err := s.client.Do(req)
 	if err != nil {
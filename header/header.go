@@ -41,6 +41,13 @@ const (
 	// FleetCaller is optionally set by mesh proxies to identify the
 	// calling service when the User-Agent has been rewritten.
 	FleetCaller = "X-Fleet-Caller"
+
+	// Priority is the caller's loadshed tier ("critical", "interactive",
+	// "batch"), read by loadshed.Gate.Guard. Backfill fan-outs set
+	// "batch" so they are shed before user-facing traffic. Trusted like
+	// the X-Auth-* headers: a public gateway must strip it on ingress so
+	// only mesh callers can claim "critical".
+	Priority = "X-Fleet-Priority"
 )
 
 // Client credential headers — set by callers, read by auth middleware.
@@ -49,6 +56,14 @@ const (
 	// callers and human-facing clients. Equivalent to Bearer in the
 	// Authorization header; both are accepted.
 	APIKey = "X-API-Key"
+
+	// FleetTimestamp, FleetNonce and FleetSignature carry the optional
+	// HMAC request signature coordinator clients add via
+	// coordauth.Credentials.Sign: unix seconds, a random per-request
+	// nonce, and the hex HMAC-SHA256 over the canonical request.
+	FleetTimestamp = "X-Fleet-Timestamp"
+	FleetNonce     = "X-Fleet-Nonce"
+	FleetSignature = "X-Fleet-Signature"
 )
 
 // Admin credential headers — used by apikey admin endpoints.
@@ -0,0 +1,11 @@
+// Package hedge issues hedged requests: when a call has not answered
+// within a delay — fixed, or the observed p95 latency for its host — a
+// second identical call is started and whichever succeeds first wins;
+// the loser is cancelled. It trims the tail latency fan-out services
+// inherit from one slow replica, at the cost of a bounded amount of
+// duplicate work: the hedge rate is capped to a fraction of traffic.
+//
+// Use Do for function calls (fleetfetch.WithHedging wires it into
+// Client.Get) and Transport for http.RoundTripper chains
+// (safehttp.WithHedging). Only duplicate work that is safe to run twice.
+package hedge
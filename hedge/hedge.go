@@ -0,0 +1,241 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go-common/retry"
+)
+
+// ErrLost is the cancellation cause of the losing call of a hedged
+// pair. Check it with context.Cause to tell "the other call won" from a
+// caller giving up.
+var ErrLost = errors.New("hedge: lost to a faster call")
+
+// Options configures a Hedger. Zero values fall back to the defaults
+// noted on each field.
+type Options struct {
+	// Delay is how long the first call may run before the hedge is
+	// sent. 0 derives it per host from Percentile of observed
+	// latency; until MinSamples calls have completed for a host, no
+	// hedge is sent (there is no basis for a delay yet).
+	Delay time.Duration
+	// Percentile of recent per-host latency used when Delay is 0.
+	// Default 0.95.
+	Percentile float64
+	// MinSamples is how many completed calls a host needs before its
+	// percentile is trusted. Default 20.
+	MinSamples int
+	// MaxRate caps hedges to this fraction of calls (0.05 = at most
+	// one hedge per 20 calls, with a small burst). Default 0.05.
+	MaxRate float64
+	// MaxHosts bounds the per-host latency trackers. Default 1024.
+	MaxHosts int
+}
+
+// Hedger holds the per-host latency history and the hedge-rate budget
+// shared by every call made through it. Safe for concurrent use.
+type Hedger struct {
+	delay      time.Duration
+	percentile float64
+	minSamples int
+	maxHosts   int
+	budget     *retry.RetryBudget
+
+	mu    sync.Mutex
+	hosts map[string]*latencies
+}
+
+// New builds a Hedger.
+func New(opts Options) *Hedger {
+	if opts.Percentile <= 0 || opts.Percentile >= 1 {
+		opts.Percentile = 0.95
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 20
+	}
+	if opts.MaxRate <= 0 {
+		opts.MaxRate = 0.05
+	}
+	if opts.MaxHosts <= 0 {
+		opts.MaxHosts = 1024
+	}
+	return &Hedger{
+		delay:      opts.Delay,
+		percentile: opts.Percentile,
+		minSamples: opts.MinSamples,
+		maxHosts:   opts.MaxHosts,
+		// A hedge is a retry that doesn't wait for failure; the same
+		// token bucket bounds both to a fraction of recent calls.
+		budget: retry.NewRetryBudget(retry.BudgetOptions{Ratio: opts.MaxRate, Burst: 2, MinPerSecond: -1}),
+		hosts:  make(map[string]*latencies),
+	}
+}
+
+// Delay returns the hedge delay currently used for host, and false when
+// calls to host are not hedged yet (percentile mode, too few samples).
+func (h *Hedger) Delay(host string) (time.Duration, bool) {
+	if h.delay > 0 {
+		return h.delay, true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := h.hosts[host]
+	if l == nil || l.n < h.minSamples {
+		return 0, false
+	}
+	return l.quantile(h.percentile), true
+}
+
+// observe records one completed call's latency for host.
+func (h *Hedger) observe(host string, d time.Duration) {
+	if h.delay > 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := h.hosts[host]
+	if l == nil {
+		if len(h.hosts) >= h.maxHosts {
+			// Crude but bounded: forget everything and relearn. A
+			// service touching more hosts than MaxHosts is not the
+			// latency-sensitive fan-out hedging is for.
+			clear(h.hosts)
+		}
+		l = &latencies{}
+		h.hosts[host] = l
+	}
+	l.add(d)
+}
+
+// latencySamples is the per-host ring size; the percentile is taken
+// over the most recent calls.
+const latencySamples = 128
+
+type latencies struct {
+	ring [latencySamples]time.Duration
+	next int
+	n    int
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.ring[l.next] = d
+	l.next = (l.next + 1) % latencySamples
+	l.n = min(l.n+1, latencySamples)
+}
+
+func (l *latencies) quantile(q float64) time.Duration {
+	s := slices.Clone(l.ring[:l.n])
+	slices.Sort(s)
+	return s[min(int(q*float64(len(s))), len(s)-1)]
+}
+
+type result[T any] struct {
+	v    T
+	err  error
+	idx  int // 0 = first call, 1 = hedge
+	took time.Duration
+}
+
+// Do runs fn and, if it has not returned after the host's hedge delay
+// and the rate cap allows, runs it a second time concurrently. The
+// first successful result wins and the other call's context is
+// cancelled with cause ErrLost. If one call fails, Do waits for the
+// other; if both fail it returns the last error. A failure before the
+// delay is returned as-is — retrying is retry's job.
+//
+// fn must be safe to run twice and must not hold on to its context
+// after returning: the winner's context is cancelled once Do returns.
+func Do[T any](ctx context.Context, h *Hedger, host string, fn func(context.Context) (T, error)) (T, error) {
+	v, cancel, err := run(ctx, h, host, fn, nil)
+	cancel()
+	return v, err
+}
+
+// run is Do with the winner's context cancel handed back (Transport
+// ties it to the response body) and discard called on every value
+// that is not returned — a failed call's, or one a cancelled loser
+// still produced — so it can release resources.
+func run[T any](ctx context.Context, h *Hedger, host string, fn func(context.Context) (T, error), discard func(T)) (T, context.CancelFunc, error) {
+	results := make(chan result[T], 2)
+	var cancels []context.CancelCauseFunc
+	launch := func() {
+		cctx, cancel := context.WithCancelCause(ctx)
+		idx := len(cancels)
+		cancels = append(cancels, cancel)
+		start := time.Now()
+		go func() {
+			v, err := fn(cctx)
+			results <- result[T]{v: v, err: err, idx: idx, took: time.Since(start)}
+		}()
+	}
+	h.budget.Deposit()
+	launch()
+	inflight := 1
+
+	var timer <-chan time.Time
+	delay, ok := h.Delay(host)
+	if ok {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		select {
+		case <-timer:
+			timer = nil
+			if !h.budget.Withdraw() {
+				emit(Event{Host: host, Phase: PhaseRateLimited, Delay: delay})
+				continue
+			}
+			emit(Event{Host: host, Phase: PhaseIssued, Delay: delay})
+			launch()
+			inflight++
+		case r := <-results:
+			inflight--
+			if r.err != nil && inflight > 0 {
+				// One of a hedged pair failed; the other may still win.
+				if discard != nil {
+					discard(r.v)
+				}
+				cancels[r.idx](nil)
+				continue
+			}
+			if r.err == nil {
+				h.observe(host, r.took)
+				if len(cancels) > 1 {
+					phase := PhaseLost
+					if r.idx == 1 {
+						phase = PhaseWon
+					}
+					emit(Event{Host: host, Phase: phase, Delay: delay})
+				}
+			}
+			for i, cancel := range cancels {
+				if i != r.idx {
+					cancel(ErrLost)
+				}
+			}
+			if inflight > 0 {
+				go reap(results, inflight, discard)
+			}
+			winner := cancels[r.idx]
+			return r.v, func() { winner(nil) }, r.err
+		}
+	}
+}
+
+// reap drains the calls still running after a winner was chosen,
+// discarding any value they produce anyway.
+func reap[T any](results <-chan result[T], n int, discard func(T)) {
+	for range n {
+		r := <-results
+		if discard != nil {
+			discard(r.v)
+		}
+	}
+}
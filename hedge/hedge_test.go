@@ -0,0 +1,187 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	phases []Phase
+}
+
+func (r *recorder) ObserveHedge(ev Event) {
+	r.mu.Lock()
+	r.phases = append(r.phases, ev.Phase)
+	r.mu.Unlock()
+}
+
+func (r *recorder) count(p Phase) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, got := range r.phases {
+		if got == p {
+			n++
+		}
+	}
+	return n
+}
+
+func watch(t *testing.T) *recorder {
+	rec := &recorder{}
+	SetDefaultObserver(rec)
+	t.Cleanup(func() { SetDefaultObserver(nil) })
+	return rec
+}
+
+func TestDoHedgeWinsAndLoserIsCancelled(t *testing.T) {
+	rec := watch(t)
+	h := New(Options{Delay: 10 * time.Millisecond, MaxRate: 1})
+	var calls atomic.Int32
+	loserCause := make(chan error, 1)
+
+	v, err := Do(context.Background(), h, "svc", func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			// The slow replica: blocks until cancelled.
+			<-ctx.Done()
+			loserCause <- context.Cause(ctx)
+			return "", ctx.Err()
+		}
+		return "fast", nil
+	})
+	if err != nil || v != "fast" {
+		t.Fatalf("Do = %q, %v; want the hedge's answer", v, err)
+	}
+	select {
+	case cause := <-loserCause:
+		if !errors.Is(cause, ErrLost) {
+			t.Fatalf("loser cancelled with %v, want ErrLost", cause)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("loser was not cancelled")
+	}
+	if rec.count(PhaseIssued) != 1 || rec.count(PhaseWon) != 1 {
+		t.Fatalf("events = %v, want issued + won", rec.phases)
+	}
+}
+
+func TestDoFastCallIsNotHedged(t *testing.T) {
+	rec := watch(t)
+	h := New(Options{Delay: time.Second, MaxRate: 1})
+	var calls atomic.Int32
+	_, err := Do(context.Background(), h, "svc", func(context.Context) (int, error) {
+		calls.Add(1)
+		return 1, nil
+	})
+	if err != nil || calls.Load() != 1 || rec.count(PhaseIssued) != 0 {
+		t.Fatalf("err=%v calls=%d events=%v", err, calls.Load(), rec.phases)
+	}
+}
+
+func TestDoFailedCallLetsTheOtherWin(t *testing.T) {
+	h := New(Options{Delay: 5 * time.Millisecond, MaxRate: 1})
+	var calls atomic.Int32
+	v, err := Do(context.Background(), h, "svc", func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return 0, errors.New("replica down")
+		}
+		time.Sleep(40 * time.Millisecond)
+		return 2, nil
+	})
+	if err != nil || v != 2 {
+		t.Fatalf("Do = %d, %v; want the surviving call's answer", v, err)
+	}
+}
+
+func TestDoRateCap(t *testing.T) {
+	rec := watch(t)
+	// MaxRate 0.01 with the 2-token burst: two hedges, then none.
+	h := New(Options{Delay: time.Millisecond, MaxRate: 0.01})
+	slow := func(context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	}
+	for range 5 {
+		if _, err := Do(context.Background(), h, "svc", slow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.count(PhaseIssued); got != 2 {
+		t.Fatalf("hedges issued = %d, want 2", got)
+	}
+	if got := rec.count(PhaseRateLimited); got != 3 {
+		t.Fatalf("rate-limited = %d, want 3", got)
+	}
+}
+
+func TestPercentileDelayNeedsSamples(t *testing.T) {
+	h := New(Options{MinSamples: 10})
+	if _, ok := h.Delay("svc"); ok {
+		t.Fatal("no samples: hedging must stay off")
+	}
+	for i := 1; i <= 100; i++ {
+		h.observe("svc", time.Duration(i)*time.Millisecond)
+	}
+	d, ok := h.Delay("svc")
+	if !ok || d < 90*time.Millisecond || d > 100*time.Millisecond {
+		t.Fatalf("p95 delay = %v (ok=%v), want ~95ms", d, ok)
+	}
+}
+
+func TestTransportHedgesSlowReplica(t *testing.T) {
+	rec := watch(t)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = io.WriteString(w, "fast")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, Options{Delay: 20 * time.Millisecond, MaxRate: 1})}
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fast" || time.Since(start) > 2*time.Second {
+		t.Fatalf("body=%q after %v, want the hedge's fast answer", body, time.Since(start))
+	}
+	if rec.count(PhaseWon) != 1 {
+		t.Fatalf("events = %v, want a won hedge", rec.phases)
+	}
+}
+
+func TestTransportDoesNotHedgePost(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport(nil, Options{Delay: time.Millisecond, MaxRate: 1})}
+	resp, err := client.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 1 {
+		t.Fatalf("POST hit the server %d times, want 1", hits.Load())
+	}
+}
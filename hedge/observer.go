@@ -0,0 +1,57 @@
+package hedge
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Phase buckets hedge events.
+type Phase string
+
+const (
+	PhaseIssued      Phase = "issued"       // the delay elapsed and a hedge call was started
+	PhaseWon         Phase = "won"          // the hedge call succeeded first
+	PhaseLost        Phase = "lost"         // a hedge was issued but the original call succeeded first
+	PhaseRateLimited Phase = "rate_limited" // the delay elapsed but MaxRate allowed no hedge
+)
+
+// Observer receives hedge events. Implementations MUST NOT block. The
+// canonical implementation lives in go-common/promx.
+type Observer interface {
+	ObserveHedge(Event)
+}
+
+// Event is the per-hedge payload handed to an Observer. Delay is the
+// hedge delay in effect for the call.
+type Event struct {
+	Host  string
+	Phase Phase
+	Delay time.Duration
+}
+
+var defaultObserver atomic.Pointer[Observer]
+
+// SetDefaultObserver installs a process-wide observer. Pass nil to
+// disable. Wired by promx.AutoWire.
+func SetDefaultObserver(o Observer) {
+	if o == nil {
+		defaultObserver.Store(nil)
+		return
+	}
+	defaultObserver.Store(&o)
+}
+
+// DefaultObserver returns the current process-wide observer or nil.
+func DefaultObserver() Observer {
+	p := defaultObserver.Load()
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func emit(ev Event) {
+	if obs := DefaultObserver(); obs != nil {
+		obs.ObserveHedge(ev)
+	}
+}
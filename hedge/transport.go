@@ -0,0 +1,96 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/baditaflorin/go-common/retry"
+)
+
+// Transport is an http.RoundTripper that hedges replayable requests
+// (see retry.IsReplayable: GET/HEAD/OPTIONS or an Idempotency-Key, with
+// a rewindable body). Other requests pass straight through. Safe for
+// concurrent use.
+type Transport struct {
+	inner  http.RoundTripper
+	hedger *Hedger
+}
+
+// NewTransport wraps inner (http.DefaultTransport when nil).
+func NewTransport(inner http.RoundTripper, opts Options) *Transport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &Transport{inner: inner, hedger: New(opts)}
+}
+
+// Hedger returns the transport's Hedger, e.g. to inspect Delay.
+func (t *Transport) Hedger() *Hedger { return t.hedger }
+
+// RoundTrip satisfies http.RoundTripper. A transport error or 5xx
+// counts as a failed call, so a healthy replica's answer can still win.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retry.IsReplayable(req) {
+		return t.inner.RoundTrip(req)
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	if req.Body != nil && req.Body != http.NoBody {
+		// Every call reads its own GetBody copy; the transport still
+		// owns closing the original.
+		defer req.Body.Close()
+	}
+	resp, cancel, err := run(req.Context(), t.hedger, host, func(ctx context.Context) (*http.Response, error) {
+		r := req.Clone(ctx)
+		if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := t.inner.RoundTrip(r)
+		if err == nil && resp.StatusCode >= 500 {
+			return resp, &statusError{resp: resp}
+		}
+		return resp, err
+	}, closeBody)
+	var se *statusError
+	if errors.As(err, &se) {
+		// No call succeeded; hand the caller the last 5xx as-is.
+		resp, err = se.resp, nil
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// statusError carries a 5xx response through run's error path.
+type statusError struct{ resp *http.Response }
+
+func (e *statusError) Error() string { return "hedge: upstream " + e.resp.Status }
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+		_ = resp.Body.Close()
+	}
+}
+
+// cancelOnClose releases the winning call's context once the caller is
+// done with the body; cancelling earlier would abort the read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -33,7 +33,11 @@
 //
 // Environment
 //
-//	LEDGER_SERVICE_URL   base URL (default: http://localhost:18314)
+//	LEDGER_SERVICE_URL             base URL (default: http://localhost:18314)
+//	LEDGER_SERVICE_SIGNING_SECRET  optional. Signs
+//	                               each call (see coordauth) — the signature
+//	                               proves which service sent it, the
+//	                               forwarded Bearer still says who pays.
 //
 // Production URL is the fleet-token-ledger registry entry
 // (services.json); see private fleet-state/OPS.md for topology.
@@ -49,7 +53,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/baditaflorin/go-common/env"
+	"github.com/baditaflorin/go-common/coordauth"
 	"github.com/baditaflorin/go-common/middleware"
 )
 
@@ -128,12 +132,19 @@ type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	UserAgent  string
+	// SigningSecret, when set, HMAC-signs every call. It never replaces
+	// the caller's Credential.
+	SigningSecret string
 }
 
-// New returns a Client wired from env vars.
+// New returns a Client wired from env vars. The resolved endpoint's API
+// key is deliberately ignored: the ledger charges the forwarded caller,
+// never this service (see the package doc).
 func New() *Client {
+	ep := coordauth.FromEnv("LEDGER_SERVICE", "http://localhost:18314")
 	return &Client{
-		BaseURL: env.String("LEDGER_SERVICE_URL", "http://localhost:18314"),
+		BaseURL:       ep.URL,
+		SigningSecret: ep.Secret,
 		HTTPClient: &http.Client{
 			Timeout:   5 * time.Second,
 			Transport: &http.Transport{Proxy: nil},
@@ -174,6 +185,9 @@ func (c *Client) Charge(ctx context.Context, cred Credential, amount int64, reas
 	req.Header.Set("Authorization", "Bearer "+string(cred))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
+	if err := (coordauth.Credentials{Secret: c.SigningSecret}).Sign(req, body); err != nil {
+		return nil, fmt.Errorf("ledger charge: sign req: %w", err)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -234,6 +248,9 @@ func (c *Client) Balance(ctx context.Context, cred Credential) (int64, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+string(cred))
 	req.Header.Set("User-Agent", c.UserAgent)
+	if err := (coordauth.Credentials{Secret: c.SigningSecret}).Sign(req, nil); err != nil {
+		return 0, fmt.Errorf("ledger balance: sign req: %w", err)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
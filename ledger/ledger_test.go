@@ -9,6 +9,9 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
+	"github.com/baditaflorin/go-common/header"
 )
 
 func TestCredentialFromRequest_bearer(t *testing.T) {
@@ -223,3 +226,23 @@ func TestBalance_malformedBody(t *testing.T) {
 		t.Fatalf("want ErrLedgerUnavailable on malformed body, got %v", err)
 	}
 }
+
+func TestCharge_signed(t *testing.T) {
+	v := coordauth.NewVerifier("", "s3cret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := v.Verify(r, body); err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+		if got := r.Header.Get(header.APIKey); got != "" {
+			t.Errorf("the ledger client must not send its own key, got %q", got)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"charged": 1}})
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTPClient: http.DefaultClient, SigningSecret: "s3cret"}
+	if _, err := c.Charge(context.Background(), "caller-token", 1, "test"); err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package loadshed
+
+import (
+	"math"
+	"time"
+)
+
+// Algorithm selects how an adaptive gate moves its limit.
+type Algorithm string
+
+const (
+	// AIMD grows the limit by ~1 per limit's worth of successful calls
+	// and cuts it by Backoff on a failure or a call slower than
+	// LatencyThreshold. Simple and predictable; needs a threshold to
+	// react to latency at all.
+	AIMD Algorithm = "aimd"
+	// Gradient compares short-term latency against a slowly moving
+	// no-load baseline (Vegas-style, after Netflix's Gradient2): when
+	// latency climbs above the baseline the limit shrinks in
+	// proportion, when it matches the limit grows by a small queue
+	// allowance. Needs no threshold. Failures still cut by Backoff.
+	Gradient Algorithm = "gradient"
+)
+
+// AdaptiveOptions configures NewAdaptive. Zero values fall back to the
+// defaults noted on each field.
+type AdaptiveOptions struct {
+	// Algorithm is AIMD or Gradient. Default Gradient.
+	Algorithm Algorithm
+	// InitialLimit is the starting limit. Default 20 (clamped to
+	// [MinLimit, MaxLimit]).
+	InitialLimit int
+	// MinLimit is the floor the limit never drops below, so a burst
+	// of errors cannot shed everything. Default 1.
+	MinLimit int
+	// MaxLimit is the ceiling. Default 200.
+	MaxLimit int
+	// Backoff is the multiplicative decrease on an overload signal.
+	// Default 0.9.
+	Backoff float64
+	// LatencyThreshold makes AIMD treat slower calls as overload.
+	// 0 = only OutcomeFailure cuts the limit. Ignored by Gradient.
+	LatencyThreshold time.Duration
+}
+
+// NewAdaptive constructs a Gate whose limit adapts to the latency and
+// error rate of the calls it admits, within [MinLimit, MaxLimit].
+// Report failures through TryAcquireOutcome (Guard does this for 5xx
+// responses); plain TryAcquire releases count as successes. gopts are
+// the same Options New takes.
+func NewAdaptive(name string, opts AdaptiveOptions, gopts ...Option) *Gate {
+	if opts.Algorithm == "" {
+		opts.Algorithm = Gradient
+	}
+	if opts.MinLimit <= 0 {
+		opts.MinLimit = 1
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 200
+	}
+	opts.MaxLimit = max(opts.MaxLimit, opts.MinLimit)
+	if opts.InitialLimit <= 0 {
+		opts.InitialLimit = 20
+	}
+	opts.InitialLimit = min(max(opts.InitialLimit, opts.MinLimit), opts.MaxLimit)
+	if opts.Backoff <= 0 || opts.Backoff >= 1 {
+		opts.Backoff = 0.9
+	}
+	g := New(name, opts.InitialLimit, gopts...)
+	g.adaptive = &adaptiveLimiter{opts: opts, limit: float64(opts.InitialLimit)}
+	return g
+}
+
+// adaptiveLimiter holds the learned state. Guarded by Gate.mu.
+type adaptiveLimiter struct {
+	opts  AdaptiveOptions
+	limit float64
+
+	// Gradient state: EWMAs of latency in seconds. long tracks the
+	// no-load baseline over ~100s of samples, short the last ~10.
+	long, short float64
+}
+
+// Smoothing factors for the gradient EWMAs and for moving the limit
+// toward its new target — small enough that one outlier sample can't
+// swing the limit.
+const (
+	gradientLongAlpha  = 0.01
+	gradientShortAlpha = 0.1
+	gradientSmoothing  = 0.2
+)
+
+// update folds one finished call into the limit and returns the new
+// integer limit. inFlight is the concurrency at the time the call
+// finished, including it.
+func (a *adaptiveLimiter) update(rtt time.Duration, failed bool, inFlight int) int {
+	switch {
+	case failed:
+		a.limit *= a.opts.Backoff
+	case a.opts.Algorithm == AIMD:
+		a.aimd(rtt, inFlight)
+	default:
+		a.gradient(rtt, inFlight)
+	}
+	a.limit = math.Min(math.Max(a.limit, float64(a.opts.MinLimit)), float64(a.opts.MaxLimit))
+	return int(a.limit)
+}
+
+func (a *adaptiveLimiter) aimd(rtt time.Duration, inFlight int) {
+	if a.opts.LatencyThreshold > 0 && rtt > a.opts.LatencyThreshold {
+		a.limit *= a.opts.Backoff
+		return
+	}
+	// Only grow when the limit is actually being used: a gate running
+	// at 3 of 100 learns nothing about whether 101 would be safe.
+	if float64(inFlight)*2 >= a.limit {
+		a.limit += 1 / a.limit
+	}
+}
+
+func (a *adaptiveLimiter) gradient(rtt time.Duration, inFlight int) {
+	sample := rtt.Seconds()
+	if a.long == 0 {
+		a.long, a.short = sample, sample
+		return
+	}
+	a.short += gradientShortAlpha * (sample - a.short)
+	a.long += gradientLongAlpha * (sample - a.long)
+	// A baseline that has drifted far above current latency (load
+	// just went away) is pulled down so growth resumes quickly.
+	if a.long > 2*a.short {
+		a.long = 2 * a.short
+	}
+	if float64(inFlight)*2 < a.limit {
+		return // app-limited: no signal
+	}
+	gradient := math.Max(0.5, math.Min(1, a.long/a.short))
+	target := a.limit*gradient + math.Sqrt(a.limit)
+	a.limit = a.limit*(1-gradientSmoothing) + target*gradientSmoothing
+}
@@ -0,0 +1,93 @@
+package loadshed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptive_DefaultsAndBounds(t *testing.T) {
+	g := NewAdaptive("a", AdaptiveOptions{InitialLimit: 500, MaxLimit: 50})
+	if g.Limit() != 50 {
+		t.Fatalf("InitialLimit must clamp to MaxLimit; got %d", g.Limit())
+	}
+	for i := 0; i < 200; i++ {
+		done, _ := g.TryAcquireOutcome()
+		done(OutcomeFailure)
+	}
+	if g.Limit() != 1 {
+		t.Fatalf("failures must floor at MinLimit=1; got %d", g.Limit())
+	}
+}
+
+func TestAdaptive_AIMDGrowsUnderLoadAndBacksOff(t *testing.T) {
+	a := &adaptiveLimiter{
+		opts:  AdaptiveOptions{Algorithm: AIMD, MinLimit: 1, MaxLimit: 100, Backoff: 0.5, LatencyThreshold: time.Second},
+		limit: 10,
+	}
+	for i := 0; i < 100; i++ {
+		a.update(10*time.Millisecond, false, 10)
+	}
+	if got := int(a.limit); got < 15 {
+		t.Fatalf("saturated successes should grow the limit; got %d", got)
+	}
+	before := a.limit
+	for i := 0; i < 100; i++ {
+		a.update(10*time.Millisecond, false, 1)
+	}
+	if a.limit != before {
+		t.Fatalf("app-limited traffic must not grow the limit: %v -> %v", before, a.limit)
+	}
+	if got := a.update(2*time.Second, false, 1); float64(got) > before/2+1 {
+		t.Fatalf("slow call must halve the limit; %v -> %d", before, got)
+	}
+}
+
+func TestAdaptive_GradientShrinksWhenLatencyRises(t *testing.T) {
+	a := &adaptiveLimiter{opts: AdaptiveOptions{Algorithm: Gradient, MinLimit: 1, MaxLimit: 1000, Backoff: 0.9}, limit: 20}
+	for i := 0; i < 200; i++ {
+		a.update(10*time.Millisecond, false, int(a.limit))
+	}
+	grown := a.limit
+	if grown <= 20 {
+		t.Fatalf("flat latency at saturation should grow the limit; got %v", grown)
+	}
+	for i := 0; i < 50; i++ {
+		a.update(100*time.Millisecond, false, int(a.limit))
+	}
+	if a.limit >= grown {
+		t.Fatalf("10x latency should shrink the limit: %v -> %v", grown, a.limit)
+	}
+}
+
+func TestAdaptive_EmitsLimitPhase(t *testing.T) {
+	rec := &recordingObserver{}
+	SetDefaultObserver(rec)
+	defer SetDefaultObserver(nil)
+
+	g := NewAdaptive("adapt", AdaptiveOptions{InitialLimit: 10})
+	done, _ := g.TryAcquireOutcome()
+	done(OutcomeFailure) // 10 * 0.9 => 9
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	last := rec.events[len(rec.events)-1]
+	if last.Phase != PhaseLimit || last.Limit != 9 {
+		t.Fatalf("want trailing PhaseLimit with Limit=9, got %+v", last)
+	}
+}
+
+func TestGuard_ReportsServerErrorsToAdaptiveGate(t *testing.T) {
+	g := NewAdaptive("guarded", AdaptiveOptions{InitialLimit: 10})
+	h := g.Guard(0, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if g.Limit() != 9 {
+		t.Fatalf("a 5xx behind Guard must cut the limit; got %d", g.Limit())
+	}
+	if g.InFlight() != 0 {
+		t.Fatalf("guard must release; in-flight=%d", g.InFlight())
+	}
+}
@@ -38,9 +38,40 @@
 //	// 2. Middleware — gate a whole handler:
 //	mux.Handle("/render", gate.Guard(0, "")(renderHandler))
 //
+// A fixed limit has to be guessed, and the right number moves with the
+// upstream's health. NewAdaptive learns it instead: the limit grows
+// while latency stays flat and shrinks when latency climbs or calls
+// fail (AIMD or a Vegas-style gradient), bounded by MinLimit/MaxLimit.
+// Report failures with TryAcquireOutcome; Guard does so for 5xx:
+//
+//	gate := loadshed.NewAdaptive("render", loadshed.AdaptiveOptions{
+//	    MinLimit: 4, MaxLimit: 64,
+//	})
+//	done, ok := gate.TryAcquireOutcome()
+//	...
+//	if err != nil { done(loadshed.OutcomeFailure) } else { done(loadshed.OutcomeSuccess) }
+//
+// Not every caller is equal. WithTierShares caps how much of the limit
+// batch (default 50%) and interactive (default 90%) callers may fill, so
+// a backfill fan-out is shed before user traffic and critical callers
+// (/selftest, health probes) keep headroom. WithQueue adds a bounded,
+// CoDel-style wait queue: Acquire waits up to MaxWait for a slot, cut
+// to Target once the queue has stood for a full Interval. Guard uses
+// both, taking the tier from X-Fleet-Priority or the keystore tier
+// (WithKeystoreTiers):
+//
+//	gate := loadshed.New("render", 32,
+//	    loadshed.WithTierShares(loadshed.TierShares{}),
+//	    loadshed.WithQueue(loadshed.QueueOptions{MaxWait: 250 * time.Millisecond}),
+//	    loadshed.WithKeystoreTiers(map[string]loadshed.Priority{"free": loadshed.PriorityBatch}),
+//	)
+//
 // Metrics: wire promx.AutoWire (or promx.NewLoadshedCollectors) once at
 // startup and every gate in the process emits loadshed_shed_total,
-// loadshed_admitted_total, and loadshed_in_flight, labelled by
-// {service, gate}. loadshed_shed_total is the canonical "you're shedding
-// load" alert signal.
+// loadshed_admitted_total, loadshed_in_flight, loadshed_limit (the
+// live limit, which moves on an adaptive gate), loadshed_queued,
+// loadshed_queue_wait_seconds and loadshed_priority_shed_total,
+// labelled by {service, gate} (plus priority on the last).
+// loadshed_shed_total is the canonical "you're shedding load" alert
+// signal.
 package loadshed
@@ -2,9 +2,12 @@ package loadshed
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/baditaflorin/go-common/header"
+	"github.com/baditaflorin/go-common/middleware"
 	"github.com/baditaflorin/go-common/response"
 )
 
@@ -69,16 +72,86 @@ func DecodeShed(statusCode int, body []byte) (message string, ok bool) {
 // need to gate only a sub-path of a handler — e.g. only cache MISSES,
 // letting hits and cheap direct calls through — call Gate.TryAcquire
 // in-line instead.
+//
+// Each request is admitted at the tier PriorityOf derives, and on a
+// gate built WithQueue it waits briefly for a slot before being shed.
+// On an adaptive gate (NewAdaptive) Guard reports a 5xx from next as
+// an overload signal, and a request whose client disconnected as
+// neither success nor failure.
 func (g *Gate) Guard(retryAfter int, msg string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			release, ok := g.TryAcquire()
-			if !ok {
+			done, err := g.Acquire(r.Context(), g.PriorityOf(r))
+			if errors.Is(err, ErrShed) {
 				WriteShed(w, retryAfter, msg)
 				return
 			}
-			defer release()
-			next.ServeHTTP(w, r)
+			if err != nil {
+				return // client went away while queued; nobody to answer
+			}
+			sw := &statusWriter{ResponseWriter: w}
+			out := OutcomeFailure // a panic in next is a failure
+			defer func() { done(out) }()
+			next.ServeHTTP(sw, r)
+			switch {
+			case r.Context().Err() != nil:
+				out = OutcomeIgnore
+			case sw.status >= 500:
+				out = OutcomeFailure
+			default:
+				out = OutcomeSuccess
+			}
 		})
 	}
 }
+
+// PriorityOf derives the tier Guard admits r at: an explicit
+// X-Fleet-Priority header (header.Priority) wins; otherwise the tier
+// middleware.TokenAuthKeystore verified (middleware.AuthTierFromContext)
+// is looked up in the WithKeystoreTiers map; otherwise interactive. The
+// X-Auth-Tier header is never consulted — local-token and private-mesh
+// callers can set it themselves — so mount Guard inside the auth
+// middleware for keystore tiers to apply. X-Fleet-Priority is trusted;
+// have the gateway strip it on ingress.
+func (g *Gate) PriorityOf(r *http.Request) Priority {
+	if p, ok := ParsePriority(r.Header.Get(header.Priority)); ok {
+		return p
+	}
+	if tier, ok := middleware.AuthTierFromContext(r.Context()); ok && tier != "" {
+		if p, ok := g.keystoreTiers[tier]; ok {
+			return p
+		}
+	}
+	return PriorityInteractive
+}
+
+// statusWriter captures the response status for Guard's outcome.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working behind Guard.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach Flush/Hijack on the
+// underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
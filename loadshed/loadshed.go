@@ -3,15 +3,30 @@ package loadshed
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
-// Gate is a non-blocking concurrency limiter. Construct with New; safe
-// for concurrent TryAcquire calls. A nil-sem Gate (limit <= 0) is
+// Gate is a non-blocking concurrency limiter. Construct with New (fixed
+// limit) or NewAdaptive (limit learned from latency and errors); safe
+// for concurrent TryAcquire calls. A fixed Gate with limit <= 0 is
 // unbounded: TryAcquire always admits. The zero value is not usable —
-// always go through New.
+// always go through a constructor.
 type Gate struct {
 	name string
-	sem  chan struct{} // nil => unbounded
+
+	// adaptive is nil for a fixed gate. When set, it owns the limit
+	// and is updated on every release.
+	adaptive *adaptiveLimiter
+
+	// shares is nil unless WithTierShares; queue is nil unless
+	// WithQueue. keystoreTiers feeds Guard's priority lookup.
+	shares        *TierShares
+	queue         *waitQueue
+	keystoreTiers map[string]Priority
+	now           func() time.Time
+
+	mu    sync.Mutex
+	limit int // 0 => unbounded; mutated only by the adaptive limiter
 
 	inFlight atomic.Int64
 	admitted atomic.Int64 // total successful TryAcquire admissions
@@ -22,14 +37,15 @@ type Gate struct {
 // limit <= 0 leaves the gate unbounded (TryAcquire always succeeds) —
 // the test default and a clean opt-out. name is the gate slug
 // ("render", "screenshot") used as a metric label; keep it short and
-// stable. An empty name folds to "_unnamed".
-func New(name string, limit int) *Gate {
+// stable. An empty name folds to "_unnamed". Options enable priority
+// tiers (WithTierShares) and a wait queue (WithQueue).
+func New(name string, limit int, opts ...Option) *Gate {
 	if name == "" {
 		name = "_unnamed"
 	}
-	g := &Gate{name: name}
-	if limit > 0 {
-		g.sem = make(chan struct{}, limit)
+	g := &Gate{name: name, limit: max(limit, 0), now: time.Now}
+	for _, o := range opts {
+		o(g)
 	}
 	return g
 }
@@ -37,9 +53,20 @@ func New(name string, limit int) *Gate {
 // Name returns the gate slug.
 func (g *Gate) Name() string { return g.name }
 
-// Limit returns the configured concurrency cap, or 0 if the gate is
-// unbounded.
-func (g *Gate) Limit() int { return cap(g.sem) }
+// Limit returns the current concurrency cap, or 0 if the gate is
+// unbounded. For an adaptive gate this is the live, learned limit.
+func (g *Gate) Limit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.limit
+}
+
+// Queued returns the number of Acquire callers waiting for a slot.
+func (g *Gate) Queued() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.queue.len()
+}
 
 // InFlight returns the current number of admitted-but-not-yet-released
 // callers.
@@ -63,40 +90,103 @@ var noop = func() {}
 // The returned release is idempotent and always safe to call, including
 // after a shed — so `release, ok := g.TryAcquire(); defer release()`
 // before the `if !ok` check will not double-release.
+//
+// On an adaptive gate, release reports a successful call. Use
+// TryAcquireOutcome when the call can fail. The caller is interactive;
+// TryAcquirePriority picks the tier.
 func (g *Gate) TryAcquire() (release func(), ok bool) {
-	if g.sem == nil {
-		// Unbounded: admit unconditionally but still track in-flight so
-		// metrics/observers see real concurrency.
-		g.admitted.Add(1)
-		g.inFlight.Add(1)
-		g.emit(PhaseAdmitted)
-		return g.releaser(false), true
+	done, ok := g.TryAcquirePriority(PriorityInteractive)
+	if !ok {
+		return noop, false
 	}
-	select {
-	case g.sem <- struct{}{}:
-		g.admitted.Add(1)
-		g.inFlight.Add(1)
-		g.emit(PhaseAdmitted)
-		return g.releaser(true), true
-	default:
+	return func() { done(OutcomeSuccess) }, true
+}
+
+// Outcome classifies a finished call for an adaptive gate's limiter.
+// Fixed gates ignore it.
+type Outcome int
+
+const (
+	// OutcomeSuccess is a completed call; its latency is a sample.
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure is an overload signal (timeout, 5xx, upstream
+	// shed): the limit is cut.
+	OutcomeFailure
+	// OutcomeIgnore frees the slot without teaching the limiter
+	// anything — e.g. the client went away mid-call.
+	OutcomeIgnore
+)
+
+var noopOutcome = func(Outcome) {}
+
+// TryAcquireOutcome is TryAcquire with a done func that reports how the
+// call ended, so an adaptive gate learns from errors as well as
+// latency. done is idempotent like release.
+func (g *Gate) TryAcquireOutcome() (done func(Outcome), ok bool) {
+	return g.TryAcquirePriority(PriorityInteractive)
+}
+
+// TryAcquirePriority is TryAcquireOutcome for a caller of tier p. With
+// WithTierShares, p is shed once the gate is past its tier's share of
+// the limit; critical callers may always use the whole limit. Never
+// waits, even on a gate with a queue — use Acquire for that.
+func (g *Gate) TryAcquirePriority(p Priority) (done func(Outcome), ok bool) {
+	g.mu.Lock()
+	if !g.admitsLocked(p) {
+		g.mu.Unlock()
 		g.shed.Add(1)
-		g.emit(PhaseShed)
-		return noop, false
+		g.emit(PhaseShed, p, 0)
+		return noopOutcome, false
 	}
+	g.inFlight.Add(1)
+	g.mu.Unlock()
+	g.admitted.Add(1)
+	g.emit(PhaseAdmitted, p, 0)
+	return g.releaser(p, time.Now()), true
 }
 
-// releaser builds an idempotent release closure. drain reports whether
-// a slot must be returned to the semaphore on release (false for the
-// unbounded path).
-func (g *Gate) releaser(drain bool) func() {
+// admitsLocked reports whether a tier-p caller fits right now.
+func (g *Gate) admitsLocked(p Priority) bool {
+	if g.limit <= 0 {
+		return true
+	}
+	return g.inFlight.Load() < g.tierCapLocked(p)
+}
+
+// tierCapLocked is the in-flight ceiling for tier p: the full limit
+// for critical callers or when tiers are off, else the tier's share
+// (at least one slot).
+func (g *Gate) tierCapLocked(p Priority) int64 {
+	if g.shares == nil || p == PriorityCritical {
+		return int64(g.limit)
+	}
+	share := g.shares.Interactive
+	if p == PriorityBatch {
+		share = g.shares.Batch
+	}
+	return max(1, int64(float64(g.limit)*share))
+}
+
+// releaser builds an idempotent done closure for a tier-p caller
+// admitted at start.
+func (g *Gate) releaser(p Priority, start time.Time) func(Outcome) {
 	var once sync.Once
-	return func() {
+	return func(out Outcome) {
 		once.Do(func() {
-			if drain {
-				<-g.sem
+			g.mu.Lock()
+			inFlight := g.inFlight.Add(-1) + 1
+			changed := false
+			if g.adaptive != nil && out != OutcomeIgnore {
+				before := g.limit
+				g.limit = g.adaptive.update(time.Since(start), out == OutcomeFailure, int(inFlight))
+				changed = g.limit != before
+			}
+			g.dispatchLocked()
+			g.mu.Unlock()
+			g.emit(PhaseReleased, p, 0)
+			if changed {
+				g.emit(PhaseLimit, p, 0)
 			}
-			g.inFlight.Add(-1)
-			g.emit(PhaseReleased)
 		})
 	}
 }
@@ -105,9 +195,12 @@ func (g *Gate) releaser(drain bool) func() {
 type Phase string
 
 const (
-	PhaseAdmitted Phase = "admitted" // TryAcquire obtained a slot
-	PhaseReleased Phase = "released" // an admitted caller released its slot
-	PhaseShed     Phase = "shed"     // TryAcquire refused because the gate was full
+	PhaseAdmitted  Phase = "admitted"  // TryAcquire obtained a slot
+	PhaseReleased  Phase = "released"  // an admitted caller released its slot
+	PhaseShed      Phase = "shed"      // refused: gate (or the caller's tier share) full, queue full, or queue wait expired
+	PhaseLimit     Phase = "limit"     // an adaptive gate's limit changed; Event.Limit is the new value
+	PhaseQueued    Phase = "queued"    // Acquire found the gate full and is waiting for a slot
+	PhaseAbandoned Phase = "abandoned" // a queued caller's context ended before it got a slot
 )
 
 // Observer receives one event per TryAcquire / release transition.
@@ -116,12 +209,18 @@ type Observer interface {
 	ObserveLoadshed(Event)
 }
 
-// Event is the payload handed to an Observer on each transition.
+// Event is the payload handed to an Observer on each transition. Limit
+// is the gate's live limit at the time of the event (0 = unbounded).
+// Priority is the caller's tier; Wait is the time spent queued, set on
+// admissions and sheds that came out of the wait queue.
 type Event struct {
 	Gate     string
 	Phase    Phase
+	Priority Priority
 	Limit    int
 	InFlight int64
+	Queued   int
+	Wait     time.Duration
 }
 
 var defaultObserver atomic.Pointer[Observer]
@@ -145,15 +244,21 @@ func DefaultObserver() Observer {
 	return *p
 }
 
-func (g *Gate) emit(phase Phase) {
+func (g *Gate) emit(phase Phase, p Priority, wait time.Duration) {
 	obs := DefaultObserver()
 	if obs == nil {
 		return
 	}
+	g.mu.Lock()
+	limit, queued := g.limit, g.queue.len()
+	g.mu.Unlock()
 	obs.ObserveLoadshed(Event{
 		Gate:     g.name,
 		Phase:    phase,
-		Limit:    cap(g.sem),
+		Priority: p,
+		Limit:    limit,
 		InFlight: g.inFlight.Load(),
+		Queued:   queued,
+		Wait:     wait,
 	})
 }
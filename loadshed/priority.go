@@ -0,0 +1,117 @@
+package loadshed
+
+import (
+	"strings"
+	"time"
+)
+
+// Priority is a caller's shedding tier. As utilization rises, batch
+// callers are shed first, then interactive; critical callers (health
+// probes, /selftest, control traffic) may use the whole limit.
+type Priority string
+
+const (
+	PriorityCritical    Priority = "critical"
+	PriorityInteractive Priority = "interactive" // the default tier
+	PriorityBatch       Priority = "batch"
+)
+
+// priorities lists the tiers highest first — the order queued waiters
+// are woken in.
+var priorities = [...]Priority{PriorityCritical, PriorityInteractive, PriorityBatch}
+
+// ParsePriority maps a tier name (case-insensitive) to a Priority.
+// Unknown or empty names return PriorityInteractive and false.
+func ParsePriority(s string) (Priority, bool) {
+	switch p := Priority(strings.ToLower(strings.TrimSpace(s))); p {
+	case PriorityCritical, PriorityInteractive, PriorityBatch:
+		return p, true
+	}
+	return PriorityInteractive, false
+}
+
+// rank orders tiers for the queue: 0 is served first.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityCritical:
+		return 0
+	case PriorityBatch:
+		return 2
+	}
+	return 1
+}
+
+// Option configures a Gate at construction (New, NewAdaptive).
+type Option func(*Gate)
+
+// TierShares caps the fraction of the limit each lower tier may occupy.
+// Critical callers always get the full limit; the gap between the
+// shares is headroom held back for the tiers above.
+type TierShares struct {
+	// Interactive is the share interactive callers may fill. Default 0.9.
+	Interactive float64
+	// Batch is the share batch callers may fill. Default 0.5.
+	Batch float64
+}
+
+// WithTierShares enables priority tiers. Without it every tier shares
+// the full limit and Priority only orders the wait queue. A tier's cap
+// is never below 1 slot, so an idle gate still serves batch work.
+func WithTierShares(s TierShares) Option {
+	if s.Interactive <= 0 || s.Interactive > 1 {
+		s.Interactive = 0.9
+	}
+	if s.Batch <= 0 || s.Batch > 1 {
+		s.Batch = 0.5
+	}
+	s.Batch = min(s.Batch, s.Interactive)
+	return func(g *Gate) { g.shares = &s }
+}
+
+// QueueOptions configures the bounded wait queue behind Acquire.
+type QueueOptions struct {
+	// MaxLen bounds the number of waiters; an arrival beyond it is shed
+	// at once. Default 64.
+	MaxLen int
+	// MaxWait is how long a waiter may queue while the queue is
+	// healthy. Default 1s.
+	MaxWait time.Duration
+	// Target is the wait applied once the queue has been standing —
+	// never drained — for longer than Interval (CoDel's overload
+	// signal): a persistent queue only adds latency, so waiters give up
+	// after Target instead of MaxWait. Default 5ms.
+	Target time.Duration
+	// Interval is how long the queue may stay non-empty before Target
+	// applies. Default 100ms.
+	Interval time.Duration
+}
+
+// WithQueue lets Acquire wait briefly for a slot instead of shedding at
+// once. TryAcquire is unaffected and stays fail-fast.
+func WithQueue(q QueueOptions) Option {
+	if q.MaxLen <= 0 {
+		q.MaxLen = 64
+	}
+	if q.MaxWait <= 0 {
+		q.MaxWait = time.Second
+	}
+	if q.Target <= 0 {
+		q.Target = 5 * time.Millisecond
+	}
+	if q.Interval <= 0 {
+		q.Interval = 100 * time.Millisecond
+	}
+	q.Target = min(q.Target, q.MaxWait)
+	return func(g *Gate) { g.queue = &waitQueue{opts: q} }
+}
+
+// WithKeystoreTiers maps keystore access tiers (the tier
+// middleware.TokenAuthKeystore verified, e.g. "free", "vetted-pentest")
+// to priorities for Guard. Unmapped tiers are interactive.
+func WithKeystoreTiers(m map[string]Priority) Option {
+	cp := make(map[string]Priority, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return func(g *Gate) { g.keystoreTiers = cp }
+}
@@ -0,0 +1,160 @@
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShed is returned by Acquire when the caller was refused: the gate
+// (or its tier share) was full and there was no queue, the queue was
+// full, or the wait expired.
+var ErrShed = errors.New("loadshed: at capacity")
+
+// Acquire takes a slot for a tier-p caller, waiting in the gate's queue
+// (WithQueue) when the gate is full. The wait is bounded by
+// QueueOptions.MaxWait — cut to Target once the queue has been standing
+// for longer than Interval — and by ctx. Freed slots go to the highest
+// tier first, oldest waiter first within a tier.
+//
+// On success done must be called exactly once, as with
+// TryAcquireOutcome. On refusal err is ErrShed; if ctx ended first it
+// is ctx's error. Without a queue Acquire is TryAcquirePriority.
+func (g *Gate) Acquire(ctx context.Context, p Priority) (done func(Outcome), err error) {
+	if err := ctx.Err(); err != nil {
+		return noopOutcome, err
+	}
+	g.mu.Lock()
+	if g.admitsLocked(p) {
+		g.inFlight.Add(1)
+		g.mu.Unlock()
+		g.admitted.Add(1)
+		g.emit(PhaseAdmitted, p, 0)
+		return g.releaser(p, time.Now()), nil
+	}
+	q := g.queue
+	if q == nil || q.n >= q.opts.MaxLen {
+		g.mu.Unlock()
+		g.shed.Add(1)
+		g.emit(PhaseShed, p, 0)
+		return noopOutcome, ErrShed
+	}
+	start := g.now()
+	wait := q.opts.MaxWait
+	if q.standing(start) {
+		wait = q.opts.Target
+	}
+	w := q.push(p, start)
+	g.mu.Unlock()
+	g.emit(PhaseQueued, p, 0)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	granted := w.granted
+	if !granted {
+		q.remove(w)
+	}
+	waited := g.now().Sub(start)
+	g.mu.Unlock()
+	if granted {
+		// A slot handed over in the same instant the wait expired is
+		// still ours: dispatchLocked already counted it in flight.
+		g.admitted.Add(1)
+		g.emit(PhaseAdmitted, p, waited)
+		return g.releaser(p, time.Now()), nil
+	}
+	if err := ctx.Err(); err != nil {
+		g.emit(PhaseAbandoned, p, waited)
+		return noopOutcome, err
+	}
+	g.shed.Add(1)
+	g.emit(PhaseShed, p, waited)
+	return noopOutcome, ErrShed
+}
+
+// waiter is one queued Acquire. granted is guarded by Gate.mu; ready
+// is closed when it is set.
+type waiter struct {
+	p       Priority
+	ready   chan struct{}
+	granted bool
+}
+
+// waitQueue is the per-tier FIFO behind Acquire. Guarded by Gate.mu.
+type waitQueue struct {
+	opts  QueueOptions
+	tiers [len(priorities)][]*waiter
+	n     int
+	// since is when the queue last went from empty to non-empty; zero
+	// while empty.
+	since time.Time
+}
+
+// len is nil-safe so callers needn't check whether a queue exists.
+func (q *waitQueue) len() int {
+	if q == nil {
+		return 0
+	}
+	return q.n
+}
+
+// standing reports CoDel's overload condition: the queue has not
+// drained for a full Interval, so it is absorbing sustained overload
+// rather than a burst.
+func (q *waitQueue) standing(now time.Time) bool {
+	return q.n > 0 && now.Sub(q.since) > q.opts.Interval
+}
+
+func (q *waitQueue) push(p Priority, now time.Time) *waiter {
+	if q.n == 0 {
+		q.since = now
+	}
+	w := &waiter{p: p, ready: make(chan struct{})}
+	q.tiers[p.rank()] = append(q.tiers[p.rank()], w)
+	q.n++
+	return w
+}
+
+func (q *waitQueue) remove(w *waiter) {
+	tier := q.tiers[w.p.rank()]
+	for i, x := range tier {
+		if x == w {
+			q.tiers[w.p.rank()] = append(tier[:i], tier[i+1:]...)
+			q.n--
+			break
+		}
+	}
+	if q.n == 0 {
+		q.since = time.Time{}
+	}
+}
+
+// dispatchLocked hands free slots to queued waiters, highest tier
+// first. A tier that cannot be admitted blocks only itself and the
+// tiers below it, whose caps are no larger.
+func (g *Gate) dispatchLocked() {
+	q := g.queue
+	if q.len() == 0 {
+		return
+	}
+	for i, p := range priorities {
+		for len(q.tiers[i]) > 0 && g.admitsLocked(p) {
+			w := q.tiers[i][0]
+			q.tiers[i] = q.tiers[i][1:]
+			q.n--
+			w.granted = true
+			g.inFlight.Add(1)
+			close(w.ready)
+		}
+	}
+	if q.n == 0 {
+		q.since = time.Time{}
+	}
+}
@@ -0,0 +1,220 @@
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/apikey"
+	"github.com/baditaflorin/go-common/header"
+	"github.com/baditaflorin/go-common/middleware"
+)
+
+func TestTiers_BatchShedFirst(t *testing.T) {
+	g := New("tiers", 10, WithTierShares(TierShares{Interactive: 0.8, Batch: 0.5}))
+	for i := 0; i < 5; i++ {
+		if _, ok := g.TryAcquirePriority(PriorityBatch); !ok {
+			t.Fatalf("batch %d should fit under its 50%% share", i)
+		}
+	}
+	if _, ok := g.TryAcquirePriority(PriorityBatch); ok {
+		t.Fatal("batch past 50% utilization must be shed")
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := g.TryAcquire(); !ok {
+			t.Fatalf("interactive %d should fit under its 80%% share", i)
+		}
+	}
+	if _, ok := g.TryAcquire(); ok {
+		t.Fatal("interactive past 80% utilization must be shed")
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := g.TryAcquirePriority(PriorityCritical); !ok {
+			t.Fatalf("critical %d must use the reserved headroom", i)
+		}
+	}
+	if _, ok := g.TryAcquirePriority(PriorityCritical); ok {
+		t.Fatal("critical is still bounded by the limit")
+	}
+}
+
+func TestTiers_OffByDefault(t *testing.T) {
+	g := New("flat", 2)
+	if _, ok := g.TryAcquirePriority(PriorityBatch); !ok {
+		t.Fatal("without WithTierShares batch may use the full limit")
+	}
+	if _, ok := g.TryAcquirePriority(PriorityBatch); !ok {
+		t.Fatal("without WithTierShares batch may use the full limit")
+	}
+}
+
+func TestAcquire_WaitsForReleaseHighestTierFirst(t *testing.T) {
+	g := New("q", 1, WithQueue(QueueOptions{MaxWait: 5 * time.Second}))
+	hold, err := g.Acquire(context.Background(), PriorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Priority, 2)
+	start := func(p Priority) {
+		go func() {
+			done, err := g.Acquire(context.Background(), p)
+			if err != nil {
+				t.Errorf("%s: %v", p, err)
+				return
+			}
+			order <- p
+			done(OutcomeSuccess)
+		}()
+	}
+	start(PriorityBatch)
+	waitQueued(t, g, 1)
+	start(PriorityCritical)
+	waitQueued(t, g, 2)
+
+	hold(OutcomeSuccess)
+	if first, second := <-order, <-order; first != PriorityCritical || second != PriorityBatch {
+		t.Fatalf("wake order = %s, %s; want critical before batch", first, second)
+	}
+	if g.InFlight() != 0 || g.Queued() != 0 {
+		t.Fatalf("in-flight=%d queued=%d after drain", g.InFlight(), g.Queued())
+	}
+}
+
+func TestAcquire_ShedsWhenQueueFullOrWaitExpires(t *testing.T) {
+	g := New("q", 1, WithQueue(QueueOptions{MaxLen: 1, MaxWait: 20 * time.Millisecond}))
+	hold, _ := g.Acquire(context.Background(), PriorityInteractive)
+	defer hold(OutcomeSuccess)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.Acquire(context.Background(), PriorityInteractive)
+		errc <- err
+	}()
+	waitQueued(t, g, 1)
+	if _, err := g.Acquire(context.Background(), PriorityInteractive); !errors.Is(err, ErrShed) {
+		t.Fatalf("queue full: err = %v, want ErrShed", err)
+	}
+	if err := <-errc; !errors.Is(err, ErrShed) {
+		t.Fatalf("expired wait: err = %v, want ErrShed", err)
+	}
+	if g.ShedTotal() != 2 || g.Queued() != 0 {
+		t.Fatalf("shed=%d queued=%d, want 2 and 0", g.ShedTotal(), g.Queued())
+	}
+}
+
+func TestAcquire_StandingQueueCutsWaitToTarget(t *testing.T) {
+	g := New("codel", 1, WithQueue(QueueOptions{MaxWait: 10 * time.Second, Target: time.Millisecond, Interval: time.Second}))
+	clk := time.Unix(1_700_000_000, 0)
+	g.now = func() time.Time { return clk }
+	hold, _ := g.Acquire(context.Background(), PriorityInteractive)
+	defer hold(OutcomeSuccess)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _, _ = g.Acquire(ctx, PriorityInteractive) }()
+	waitQueued(t, g, 1)
+
+	// The queue has now stood for longer than Interval: a new waiter
+	// gets Target, not MaxWait.
+	g.mu.Lock()
+	clk = clk.Add(2 * time.Second)
+	g.mu.Unlock()
+	began := time.Now()
+	if _, err := g.Acquire(context.Background(), PriorityInteractive); !errors.Is(err, ErrShed) {
+		t.Fatalf("err = %v, want ErrShed", err)
+	}
+	if waited := time.Since(began); waited > time.Second {
+		t.Fatalf("standing queue should shed after Target; waited %v", waited)
+	}
+}
+
+func TestAcquire_ContextCancelAbandons(t *testing.T) {
+	g := New("q", 1, WithQueue(QueueOptions{}))
+	hold, _ := g.Acquire(context.Background(), PriorityInteractive)
+	defer hold(OutcomeSuccess)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.Acquire(ctx, PriorityInteractive)
+		errc <- err
+	}()
+	waitQueued(t, g, 1)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if g.ShedTotal() != 0 {
+		t.Fatalf("an abandoned wait is not a shed; shed=%d", g.ShedTotal())
+	}
+}
+
+// tierVerifier resolves every key but the local one to the tier named
+// by the key itself.
+type tierVerifier struct{}
+
+func (tierVerifier) Verify(_ context.Context, key string) (*apikey.VerifyResult, error) {
+	return &apikey.VerifyResult{User: "u", Scope: "*", Tier: key}, nil
+}
+
+func TestGuard_PriorityFromHeaderAndKeystoreTier(t *testing.T) {
+	g := New("guard", 10,
+		WithTierShares(TierShares{Interactive: 0.1, Batch: 0.1}),
+		WithKeystoreTiers(map[string]Priority{"free": PriorityBatch, "vetted-pentest": PriorityCritical}),
+	)
+	occupy, _ := g.TryAcquirePriority(PriorityCritical) // 1/10 = interactive and batch share
+	defer occupy(OutcomeSuccess)
+
+	auth := middleware.TokenAuthKeystore(middleware.KeystoreOpts{Verifier: tierVerifier{}, LocalTokens: []string{"local"}})
+	h := auth(g.Guard(0, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	cases := []struct {
+		name   string
+		key    string
+		hdr    string
+		val    string
+		status int
+	}{
+		{"explicit critical header", "free", header.Priority, "critical", http.StatusOK},
+		{"keystore free tier", "free", "", "", http.StatusServiceUnavailable},
+		{"keystore vetted tier", "vetted-pentest", "", "", http.StatusOK},
+		{"unmapped tier is interactive", "pro", "", "", http.StatusServiceUnavailable},
+		// A local token carries no verified tier; a tier header it
+		// sends itself must not buy priority.
+		{"forged tier on a local token", "local", header.AuthTier, "vetted-pentest", http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?api_key="+tc.key, nil)
+			if tc.hdr != "" {
+				r.Header.Set(tc.hdr, tc.val)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Fatalf("status = %d, want %d", w.Code, tc.status)
+			}
+		})
+	}
+
+	// Without keystore auth in front, the tier header is ignored too.
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(header.AuthTier, "vetted-pentest")
+	if p := g.PriorityOf(r); p != PriorityInteractive {
+		t.Fatalf("PriorityOf with a bare X-Auth-Tier = %v, want interactive", p)
+	}
+}
+
+func waitQueued(t *testing.T, g *Gate, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Queued() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", g.Queued(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -51,6 +51,7 @@ func TokenAuthKeystore(opts KeystoreOpts) Middleware {
 	// TierSatisfies against any non-empty RequiredTier by construction,
 	// not because each call site remembered to special-case it.
 	admit := func(w http.ResponseWriter, r *http.Request, next http.Handler, src AuthSource, callerTier string, d time.Duration) {
+		r = r.WithContext(context.WithValue(r.Context(), authTierKey{}, callerTier))
 		if opts.RequiredTier == "" || apikey.TierSatisfies(callerTier, opts.RequiredTier) {
 			observe(src, AuthResultAllow, d)
 			next.ServeHTTP(w, r)
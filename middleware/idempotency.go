@@ -2,12 +2,18 @@ package middleware
 
 import (
 	"bytes"
-	"container/list"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
 	"net/http"
-	"sync"
 	"time"
 
-	"golang.org/x/sync/singleflight"
+	fleetErrors "github.com/baditaflorin/go-common/errors"
+	"github.com/baditaflorin/go-common/response"
 )
 
 // IdempotencyKeyHeader is the request header used to key the cache.
@@ -19,6 +25,23 @@ const IdempotencyKeyHeader = "Idempotency-Key"
 // a debug proxy) can tell a cached reply from a freshly-computed one.
 const IdempotencyCachedHeader = "Idempotency-Cached"
 
+// Error codes surfaced by IdempotencyKey, following the IETF
+// idempotency-key draft (draft-ietf-httpapi-idempotency-key-header).
+var (
+	// ErrIdempotencyKeyReused is returned (422) when a key is replayed
+	// with a different method, path, or body than its first use —
+	// almost always a client bug generating keys per session instead
+	// of per operation.
+	ErrIdempotencyKeyReused = fleetErrors.New(http.StatusUnprocessableEntity,
+		"idempotency.key_reused", "idempotency key was already used with a different request")
+
+	// ErrIdempotencyInFlight is returned (409) while the original
+	// request for a key is still executing. Clients retry after
+	// Retry-After and receive the stored reply.
+	ErrIdempotencyInFlight = fleetErrors.New(http.StatusConflict,
+		"idempotency.in_flight", "a request with this idempotency key is still being processed")
+)
+
 // IdempotencyConfig configures the idempotency-key cache.
 type IdempotencyConfig struct {
 	// TTL is the maximum age of a cached response. After TTL elapses,
@@ -26,8 +49,8 @@ type IdempotencyConfig struct {
 	// Default: 1 hour.
 	TTL time.Duration
 
-	// MaxEntries caps the in-memory cache size to bound RAM. Eviction is
-	// LRU. Default: 10000.
+	// MaxEntries caps the default in-memory store to bound RAM.
+	// Eviction is LRU. Ignored when Store is set. Default: 10000.
 	MaxEntries int
 
 	// RequiredMethods is the set of HTTP methods that consult the cache.
@@ -35,6 +58,24 @@ type IdempotencyConfig struct {
 	// body, idempotent by HTTP spec).
 	RequiredMethods []string
 
+	// Store persists cached replies and in-flight locks. nil = a
+	// process-local LRU sized by MaxEntries. Use a shared store (e.g.
+	// NewFileIdempotencyStore on a common volume) so retries survive
+	// a deploy and dedupe across replicas.
+	Store IdempotencyStore
+
+	// LockTTL bounds how long an in-flight lock is honored. A replica
+	// that dies mid-handler holds its keys for at most LockTTL before
+	// another replica may take over. Set it above your slowest
+	// handler. Default: 1 minute.
+	LockTTL time.Duration
+
+	// MaxFingerprintBytes caps how much of the request body is read to
+	// compute the request fingerprint. A larger body is rejected with
+	// 413 rather than fingerprinted on a prefix (a prefix match would
+	// let two different payloads share a key). Default: 10 MiB.
+	MaxFingerprintBytes int64
+
 	// now is an injectable clock for tests. nil = time.Now.
 	now func() time.Time
 }
@@ -49,35 +90,28 @@ func (c IdempotencyConfig) withDefaults() IdempotencyConfig {
 	if len(c.RequiredMethods) == 0 {
 		c.RequiredMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
 	}
+	if c.LockTTL <= 0 {
+		c.LockTTL = time.Minute
+	}
+	if c.MaxFingerprintBytes <= 0 {
+		c.MaxFingerprintBytes = 10 << 20
+	}
 	if c.now == nil {
 		c.now = time.Now
 	}
+	if c.Store == nil {
+		c.Store = newMemoryIdempotencyStore(c.MaxEntries, c.now)
+	}
 	return c
 }
 
-// cachedResponse is the persisted server reply for a given key.
-type cachedResponse struct {
-	status      int
-	contentType string
-	body        []byte
-	expiresAt   time.Time
-}
-
-// idempotencyCache is a bounded LRU + TTL cache plus a singleflight
-// group to dedupe concurrent requests sharing the same key.
+// idempotencyCache binds the resolved config to the method set. All
+// state lives in the store; the store's in-flight lock is the only
+// concurrency control, so a second request on this replica and one on
+// another replica are treated identically.
 type idempotencyCache struct {
 	cfg     IdempotencyConfig
 	methods map[string]struct{}
-	sf      singleflight.Group
-
-	mu      sync.Mutex
-	entries map[string]*list.Element // key -> list element
-	lru     *list.List               // front = most recent
-}
-
-type lruItem struct {
-	key   string
-	value cachedResponse
 }
 
 func newIdempotencyCache(cfg IdempotencyConfig) *idempotencyCache {
@@ -89,54 +123,57 @@ func newIdempotencyCache(cfg IdempotencyConfig) *idempotencyCache {
 	return &idempotencyCache{
 		cfg:     cfg,
 		methods: methods,
-		entries: make(map[string]*list.Element),
-		lru:     list.New(),
 	}
 }
 
-// get returns the cached response for key if present and unexpired.
-func (c *idempotencyCache) get(key string) (cachedResponse, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	el, ok := c.entries[key]
-	if !ok {
-		return cachedResponse{}, false
+// get returns the stored response for key if present and unexpired.
+func (c *idempotencyCache) get(ctx context.Context, key string) (IdempotencyRecord, bool, error) {
+	rec, ok, err := c.cfg.Store.Get(ctx, key)
+	if err != nil || !ok {
+		return IdempotencyRecord{}, false, err
 	}
-	item := el.Value.(*lruItem)
-	if !item.value.expiresAt.After(c.cfg.now()) {
-		// Expired — drop it.
-		c.lru.Remove(el)
-		delete(c.entries, key)
-		return cachedResponse{}, false
+	if !rec.ExpiresAt.After(c.cfg.now()) {
+		return IdempotencyRecord{}, false, nil
 	}
-	c.lru.MoveToFront(el)
-	return item.value, true
+	return rec, true, nil
 }
 
-// put stores a response under key, applying LRU eviction.
-func (c *idempotencyCache) put(key string, v cachedResponse) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if el, ok := c.entries[key]; ok {
-		el.Value.(*lruItem).value = v
-		c.lru.MoveToFront(el)
-		return
-	}
-	el := c.lru.PushFront(&lruItem{key: key, value: v})
-	c.entries[key] = el
-	for c.lru.Len() > c.cfg.MaxEntries {
-		oldest := c.lru.Back()
-		if oldest == nil {
-			break
+// fingerprint hashes method, path, and body so a key reused for a
+// different operation can be told apart from a genuine retry. The body
+// is read fully and restored on r so the handler still sees it.
+// Returns ok=false when the body exceeds MaxFingerprintBytes.
+func (c *idempotencyCache) fingerprint(r *http.Request) (string, bool, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, c.cfg.MaxFingerprintBytes+1))
+		_ = r.Body.Close()
+		if err != nil {
+			return "", false, err
+		}
+		if int64(len(body)) > c.cfg.MaxFingerprintBytes {
+			return "", false, nil
 		}
-		c.lru.Remove(oldest)
-		delete(c.entries, oldest.Value.(*lruItem).key)
+		h.Write(body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
 	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// newLockOwner returns a random token identifying one handler
+// invocation as the holder of an in-flight lock.
+func newLockOwner() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
 }
 
 // captureWriter buffers the handler's response in memory; bytes are
 // not forwarded to any real client. The idempotency middleware
-// replays the captured response on every caller (leader + followers).
+// stores the captured response, then replays it to the caller.
 type captureWriter struct {
 	header      http.Header
 	status      int
@@ -172,17 +209,28 @@ func (rw *captureWriter) Write(b []byte) (int, error) {
 //   - Empty header: passthrough (no caching).
 //   - Method not in RequiredMethods (default POST/PUT/PATCH/DELETE):
 //     passthrough.
-//   - Cache HIT: cached body, status, and Content-Type are replayed.
-//     The handler is NOT invoked. Idempotency-Cached: true is set.
-//   - Cache MISS: handler runs once (via singleflight). 2xx and 4xx
-//     responses are cached; 5xx is NOT (transient errors should be
-//     retriable, not poisoned into the cache).
+//   - Cache HIT with a matching fingerprint (method + path + body):
+//     cached body, status, and Content-Type are replayed. The handler
+//     is NOT invoked. Idempotency-Cached: true is set.
+//   - Cache HIT with a different fingerprint: 422
+//     idempotency.key_reused. The handler is NOT invoked.
+//   - Original request still running (on this or any replica sharing
+//     the store): 409 idempotency.in_flight with Retry-After.
+//   - Cache MISS: handler runs once under the store's in-flight lock.
+//     2xx and 4xx responses are cached; 5xx is NOT (transient errors
+//     should be retriable, not poisoned into the cache).
+//   - Store failure: 503 with Retry-After. The middleware fails
+//     closed — running the handler without the store could execute a
+//     mutation twice, which is exactly what it exists to prevent.
 //
 // Caveats:
 //   - Only body, status, and Content-Type are cached. Custom headers
 //     set by the handler do not survive a replay.
-//   - The cache is process-local. Multi-replica services need an
-//     external store (out of scope for this middleware).
+//   - The handler's response is buffered until it finishes, then
+//     flushed in one go. This matches typical JSON-API handlers but
+//     breaks streaming.
+//   - The default store is process-local. Multi-replica services set
+//     IdempotencyConfig.Store to a shared store.
 func IdempotencyKey(cfg IdempotencyConfig) Middleware {
 	cache := newIdempotencyCache(cfg)
 
@@ -198,94 +246,134 @@ func IdempotencyKey(cfg IdempotencyConfig) Middleware {
 				return
 			}
 
+			fp, ok, err := cache.fingerprint(r)
+			if err != nil {
+				writeIdempotencyError(w, fleetErrors.Wrap(err, http.StatusBadRequest,
+					"bad_request.body", "could not read request body"))
+				return
+			}
+			if !ok {
+				writeIdempotencyError(w, fleetErrors.New(http.StatusRequestEntityTooLarge,
+					"idempotency.body_too_large", "request body too large to fingerprint"))
+				return
+			}
+
 			// Fast path: cached + unexpired → replay without invoking
 			// the handler at all.
-			if v, ok := cache.get(key); ok {
-				replay(w, v, true)
+			if done := cache.replayStored(r.Context(), w, key, fp); done {
 				return
 			}
 
-			// Slow path: singleflight ensures concurrent requests with
-			// the same key result in exactly one handler invocation.
-			// The leader executes the handler against its OWN writer
-			// (streaming to its own client) and stores the response in
-			// the cache. Followers receive the leader's return value
-			// (shared=true) and replay from cache against their own
-			// writer.
-			//
-			// To keep the leader's writer separate from followers, we
-			// can't pass `w` into the singleflight closure (the
-			// closure runs once but is observed by N callers). The
-			// leader needs a separate recordingWriter that ALSO
-			// streams to its real client. We handle that by having
-			// the singleflight Do produce only the cached value;
-			// every caller (leader and followers) then writes to its
-			// own w from that cached value.
-			//
-			// Trade-off: the leader's response is buffered until the
-			// handler finishes, then flushed in one go. This matches
-			// typical JSON-API handlers but breaks streaming.
-
-			result, _, shared := cache.sf.Do(key, func() (interface{}, error) {
-				// Re-check the cache inside the singleflight in case
-				// a sibling request just populated it.
-				if v, ok := cache.get(key); ok {
-					return &v, nil
-				}
+			owner := newLockOwner()
+			locked, err := cache.cfg.Store.Lock(r.Context(), key, owner, cache.cfg.now().Add(cache.cfg.LockTTL))
+			if err != nil {
+				idempotencyStoreUnavailable(w, err)
+				return
+			}
+			if !locked {
+				w.Header().Set("Retry-After", "1")
+				writeIdempotencyError(w, ErrIdempotencyInFlight)
+				return
+			}
+			// Unlock on a detached context: the request context may
+			// already be cancelled, and a lingering lock would 409
+			// every retry for LockTTL.
+			defer func() {
+				_ = cache.cfg.Store.Unlock(context.WithoutCancel(r.Context()), key, owner)
+			}()
 
-				// Capture-only writer — discard streamed writes
-				// (every caller will write from the captured bytes).
-				rec := newCaptureWriter()
-				next.ServeHTTP(rec, r)
-				if !rec.wroteHeader {
-					rec.status = http.StatusOK
-				}
+			// Re-check under the lock: the previous holder may have
+			// stored its reply and unlocked between our miss above and
+			// our Lock.
+			if done := cache.replayStored(r.Context(), w, key, fp); done {
+				return
+			}
 
-				// Only cache 2xx and 4xx. 5xx is treated as a
-				// transient failure that should be retried, not
-				// memoized. We still want to propagate the response
-				// to the leader's client, so return a non-cached
-				// envelope.
-				stored := cachedResponse{
-					status:      rec.status,
-					contentType: rec.header.Get("Content-Type"),
-					body:        rec.body.Bytes(),
-					expiresAt:   cache.cfg.now().Add(cache.cfg.TTL),
-				}
-				if rec.status < 500 {
-					cache.put(key, stored)
-				}
-				return &stored, nil
-			})
+			// Capture-only writer — the reply is stored before it is
+			// written so a crash between the two never leaves a client
+			// with a response the store doesn't know about.
+			rec := newCaptureWriter()
+			next.ServeHTTP(rec, r)
+			if !rec.wroteHeader {
+				rec.status = http.StatusOK
+			}
 
-			if result == nil {
-				return
+			// Only cache 2xx and 4xx. 5xx is treated as a transient
+			// failure that should be retried, not memoized. We still
+			// propagate the response to this caller.
+			out := IdempotencyRecord{
+				Status:      rec.status,
+				ContentType: rec.header.Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+				Fingerprint: fp,
+				ExpiresAt:   cache.cfg.now().Add(cache.cfg.TTL),
 			}
-			v := result.(*cachedResponse)
-			// If singleflight shared the result with other callers,
-			// this caller is consuming a deduped response — mark it
-			// cached for client visibility. If it's a solo leader,
-			// the response is fresh.
-			replay(w, *v, shared)
+			if rec.status < 500 {
+				if err := cache.cfg.Store.Put(context.WithoutCancel(r.Context()), key, out); err != nil {
+					// The handler already ran; its reply is still the
+					// right answer for this caller. A retry will
+					// re-execute, which is the pre-store behavior —
+					// log loudly rather than hide the result.
+					log.Printf("middleware: idempotency store put: %v", err)
+				}
+			}
+			replay(w, out, false)
 		})
 	}
 }
 
+// replayStored writes the stored reply for key (or a 422 on fingerprint
+// mismatch, or a 503 on store failure). Returns false when there is no
+// stored reply and the caller should proceed to run the handler.
+func (c *idempotencyCache) replayStored(ctx context.Context, w http.ResponseWriter, key, fp string) bool {
+	v, ok, err := c.get(ctx, key)
+	if err != nil {
+		idempotencyStoreUnavailable(w, err)
+		return true
+	}
+	if !ok {
+		return false
+	}
+	// Records written before fingerprinting existed carry no
+	// fingerprint; replay them rather than reject every retry.
+	if v.Fingerprint != "" && v.Fingerprint != fp {
+		writeIdempotencyError(w, ErrIdempotencyKeyReused)
+		return true
+	}
+	replay(w, v, true)
+	return true
+}
+
+// writeIdempotencyError writes e as the canonical fleet error envelope.
+func writeIdempotencyError(w http.ResponseWriter, e *fleetErrors.Error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(e.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(response.NewError(e.HTTPStatus(), e.Code, e.Msg))
+}
+
+// idempotencyStoreUnavailable writes the fail-closed 503 used when the
+// store cannot be consulted.
+func idempotencyStoreUnavailable(w http.ResponseWriter, err error) {
+	log.Printf("middleware: idempotency store unavailable: %v", err)
+	w.Header().Set("Retry-After", "5")
+	writeIdempotencyError(w, fleetErrors.Wrap(err, http.StatusServiceUnavailable,
+		"idempotency.store_unavailable", "idempotency store unavailable; retry shortly"))
+}
+
 // replay writes a captured response back to the client. If cached is
-// true (the response came from the cache OR was shared by the
-// singleflight from another caller's handler invocation), an
-// Idempotency-Cached: true header is set.
-func replay(w http.ResponseWriter, v cachedResponse, cached bool) {
-	if v.contentType != "" {
-		w.Header().Set("Content-Type", v.contentType)
+// true (the response came from the store rather than this request's
+// own handler invocation), an Idempotency-Cached: true header is set.
+func replay(w http.ResponseWriter, v IdempotencyRecord, cached bool) {
+	if v.ContentType != "" {
+		w.Header().Set("Content-Type", v.ContentType)
 	}
 	if cached {
 		w.Header().Set(IdempotencyCachedHeader, "true")
 	}
-	status := v.status
+	status := v.Status
 	if status == 0 {
 		status = http.StatusOK
 	}
 	w.WriteHeader(status)
-	_, _ = w.Write(v.body)
+	_, _ = w.Write(v.Body)
 }
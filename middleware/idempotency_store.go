@@ -0,0 +1,491 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is a captured handler reply as persisted by an
+// IdempotencyStore. Only status, Content-Type, and body survive a
+// replay (see IdempotencyKey caveats).
+type IdempotencyRecord struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+	// Fingerprint is the hex SHA-256 of the method, path, and body of
+	// the request that produced this reply. A later request reusing
+	// the key with a different fingerprint is rejected with 422.
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// IdempotencyStore persists cached replies and in-flight locks for
+// IdempotencyKey. The default is a process-local LRU (see
+// NewMemoryIdempotencyStore); replicas behind one load balancer share
+// a store so a retry landing on a different replica — or on the same
+// replica after a deploy — replays instead of re-executing.
+//
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Get returns the unexpired record for key. ok=false on a miss;
+	// err is reserved for backend failures.
+	Get(ctx context.Context, key string) (rec IdempotencyRecord, ok bool, err error)
+
+	// Put stores rec under key, replacing any existing record.
+	Put(ctx context.Context, key string, rec IdempotencyRecord) error
+
+	// Lock claims the in-flight lock for key on behalf of owner until
+	// expiresAt. Returns false (no error) when a different owner holds
+	// an unexpired lock. An expired lock is taken over — that is how a
+	// replica crashing mid-handler releases its keys.
+	Lock(ctx context.Context, key, owner string, expiresAt time.Time) (bool, error)
+
+	// Unlock releases the lock for key if owner still holds it. A lock
+	// already taken over by another owner is left alone.
+	Unlock(ctx context.Context, key, owner string) error
+}
+
+// ─── Memory store ─────────────────────────────────────────────────────────
+
+// MemoryIdempotencyStore is a bounded LRU + TTL store. It is the
+// IdempotencyConfig default and is process-local: it does not survive
+// a restart and is not shared between replicas.
+type MemoryIdempotencyStore struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key -> list element
+	lru     *list.List               // front = most recent
+	locks   map[string]idempotencyLock
+}
+
+type lruItem struct {
+	key   string
+	value IdempotencyRecord
+}
+
+type idempotencyLock struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewMemoryIdempotencyStore returns an in-process store holding at
+// most maxEntries records (LRU eviction). maxEntries <= 0 means 10000.
+func NewMemoryIdempotencyStore(maxEntries int) *MemoryIdempotencyStore {
+	return newMemoryIdempotencyStore(maxEntries, time.Now)
+}
+
+func newMemoryIdempotencyStore(maxEntries int, now func() time.Time) *MemoryIdempotencyStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryIdempotencyStore{
+		maxEntries: maxEntries,
+		now:        now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		locks:      make(map[string]idempotencyLock),
+	}
+}
+
+// Get implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return IdempotencyRecord{}, false, nil
+	}
+	item := el.Value.(*lruItem)
+	if !item.value.ExpiresAt.After(s.now()) {
+		// Expired — drop it.
+		s.lru.Remove(el)
+		delete(s.entries, key)
+		return IdempotencyRecord{}, false, nil
+	}
+	s.lru.MoveToFront(el)
+	return item.value, true, nil
+}
+
+// Put implements IdempotencyStore, applying LRU eviction.
+func (s *MemoryIdempotencyStore) Put(_ context.Context, key string, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*lruItem).value = rec
+		s.lru.MoveToFront(el)
+		return nil
+	}
+	el := s.lru.PushFront(&lruItem{key: key, value: rec})
+	s.entries[key] = el
+	for s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		if oldest == nil {
+			break
+		}
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+// Lock implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Lock(_ context.Context, key, owner string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.locks[key]; ok && cur.Owner != owner && cur.ExpiresAt.After(s.now()) {
+		return false, nil
+	}
+	s.locks[key] = idempotencyLock{Owner: owner, ExpiresAt: expiresAt}
+	return true, nil
+}
+
+// Unlock implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Unlock(_ context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.locks[key]; ok && cur.Owner == owner {
+		delete(s.locks, key)
+	}
+	return nil
+}
+
+// ─── File store ───────────────────────────────────────────────────────────
+
+// FileIdempotencyStore persists records as one JSON file per key under
+// a directory, so cached replies survive a restart and can be shared
+// by replicas mounting the same volume. Writes are atomic (tmp file +
+// rename); in-flight locks are complete lock files published with a
+// hard link, which is safe across processes on any filesystem with
+// POSIX link semantics (local disks, NFSv3+).
+//
+// Expired records are deleted lazily on Get. Services with a high key
+// churn should run Sweep periodically to reclaim disk.
+type FileIdempotencyStore struct {
+	dir string
+	now func() time.Time
+}
+
+// maxIdempotencyFile caps a single record read. A corrupted or hostile
+// file must not exhaust memory; replies larger than this were never
+// going to be useful to replay.
+const maxIdempotencyFile = 8 << 20
+
+// NewFileIdempotencyStore returns a store rooted at dir, creating the
+// directory if needed.
+func NewFileIdempotencyStore(dir string) (*FileIdempotencyStore, error) {
+	if dir == "" {
+		return nil, errors.New("middleware: idempotency store dir is empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("middleware: idempotency store mkdir %q: %w", dir, err)
+	}
+	return &FileIdempotencyStore{dir: dir, now: time.Now}, nil
+}
+
+// path maps key to a file name. Keys are client-supplied, so they are
+// hashed rather than used verbatim (no path traversal, bounded length).
+func (s *FileIdempotencyStore) path(key, ext string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+ext)
+}
+
+// Get implements IdempotencyStore.
+func (s *FileIdempotencyStore) Get(_ context.Context, key string) (IdempotencyRecord, bool, error) {
+	p := s.path(key, ".json")
+	var rec IdempotencyRecord
+	ok, err := readJSONFile(p, &rec)
+	if err != nil || !ok {
+		return IdempotencyRecord{}, false, err
+	}
+	if !rec.ExpiresAt.After(s.now()) {
+		_ = os.Remove(p)
+		return IdempotencyRecord{}, false, nil
+	}
+	return rec, true, nil
+}
+
+// Put implements IdempotencyStore.
+func (s *FileIdempotencyStore) Put(_ context.Context, key string, rec IdempotencyRecord) error {
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return fmt.Errorf("middleware: idempotency record marshal: %w", err)
+	}
+	return writeFileAtomic(s.path(key, ".json"), data)
+}
+
+// Lock implements IdempotencyStore.
+//
+// The lock record is written to a temp file first and published with
+// os.Link, which fails when the lock file already exists, so a lock
+// file is never visible half-written and exactly one creator wins. A
+// lock that cannot be read or parsed (a corrupted or foreign file) is
+// treated as held until its mtime is older than the requested TTL
+// (expiresAt - now). Taking over an expired lock first claims a
+// takeover marker named after that lock's exact contents, so when
+// several replicas see the same stale lock only one may remove it.
+func (s *FileIdempotencyStore) Lock(_ context.Context, key, owner string, expiresAt time.Time) (bool, error) {
+	p := s.path(key, ".lock")
+	data, err := json.Marshal(idempotencyLock{Owner: owner, ExpiresAt: expiresAt})
+	if err != nil {
+		return false, err
+	}
+	tmp, err := writeTempFile(p, data)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	// Two passes: the second runs only when the lock vanished between
+	// the failed link and the read, or after clearing a stale lock.
+	for attempt := 0; attempt < 2; attempt++ {
+		won, err := linkExclusive(tmp, p)
+		if err != nil || won {
+			return won, err
+		}
+		cur, err := readLockFile(p)
+		if err != nil {
+			return false, err
+		}
+		if !cur.exists {
+			continue
+		}
+		if cur.parsed && cur.lock.Owner == owner {
+			// Re-entrant: refresh the expiry in place.
+			return true, writeFileAtomic(p, data)
+		}
+		now := s.now()
+		stale := !cur.lock.ExpiresAt.After(now)
+		if !cur.parsed {
+			stale = now.Sub(cur.modTime) >= expiresAt.Sub(now)
+		}
+		if !stale {
+			return false, nil
+		}
+		if ok, err := s.removeStaleLock(p, cur); err != nil || !ok {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// lockFile is a lock file as read from disk.
+type lockFile struct {
+	exists  bool
+	parsed  bool
+	lock    idempotencyLock
+	raw     []byte
+	modTime time.Time
+}
+
+// readLockFile reads the lock file at p without judging it.
+func readLockFile(p string) (lockFile, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lockFile{}, nil
+		}
+		return lockFile{}, fmt.Errorf("middleware: idempotency lock read: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return lockFile{}, fmt.Errorf("middleware: idempotency lock read: %w", err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(f, maxIdempotencyFile))
+	if err != nil {
+		return lockFile{}, fmt.Errorf("middleware: idempotency lock read: %w", err)
+	}
+	lf := lockFile{exists: true, raw: raw, modTime: fi.ModTime()}
+	lf.parsed = json.Unmarshal(raw, &lf.lock) == nil && lf.lock.Owner != ""
+	return lf, nil
+}
+
+// takeoverMarkerTTL is how long a takeover marker is kept. A replica
+// that read the same stale lock and stalled for longer than this could
+// remove its successor's lock; Sweep reclaims older markers.
+const takeoverMarkerTTL = 10 * time.Minute
+
+// removeStaleLock deletes the lock file at p if it still is cur, after
+// claiming the takeover marker for cur's generation. ok=false means
+// another replica is taking the same lock over.
+func (s *FileIdempotencyStore) removeStaleLock(p string, cur lockFile) (bool, error) {
+	h := sha256.New()
+	h.Write(cur.raw)
+	fmt.Fprint(h, cur.modTime.UnixNano())
+	marker := strings.TrimSuffix(p, ".lock") + "." + hex.EncodeToString(h.Sum(nil)[:8]) + ".takeover"
+	mtmp, err := writeTempFile(marker, nil)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(mtmp)
+	if ok, err := linkExclusive(mtmp, marker); err != nil || !ok {
+		return false, err
+	}
+	again, err := readLockFile(p)
+	if err != nil {
+		return false, err
+	}
+	if !again.exists {
+		return true, nil
+	}
+	if !bytes.Equal(again.raw, cur.raw) || !again.modTime.Equal(cur.modTime) {
+		return false, nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("middleware: idempotency lock takeover: %w", err)
+	}
+	return true, nil
+}
+
+// Unlock implements IdempotencyStore.
+func (s *FileIdempotencyStore) Unlock(_ context.Context, key, owner string) error {
+	p := s.path(key, ".lock")
+	var cur idempotencyLock
+	ok, err := readJSONFile(p, &cur)
+	if err != nil || !ok || cur.Owner != owner {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("middleware: idempotency unlock: %w", err)
+	}
+	return nil
+}
+
+// Sweep deletes expired records and locks. Returns the number of
+// files removed. Safe to run concurrently with request traffic.
+func (s *FileIdempotencyStore) Sweep() (int, error) {
+	ents, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("middleware: idempotency sweep: %w", err)
+	}
+	now := s.now()
+	removed := 0
+	for _, e := range ents {
+		if e.IsDir() {
+			continue
+		}
+		p := filepath.Join(s.dir, e.Name())
+		var expiresAt time.Time
+		switch filepath.Ext(e.Name()) {
+		case ".json":
+			var rec IdempotencyRecord
+			if ok, err := readJSONFile(p, &rec); err != nil || !ok {
+				continue
+			}
+			expiresAt = rec.ExpiresAt
+		case ".lock":
+			cur, err := readLockFile(p)
+			if err != nil || !cur.parsed || cur.lock.ExpiresAt.After(now) {
+				continue
+			}
+			// Same protocol as a takeover, so Sweep never removes a
+			// lock a replica has just claimed in its place.
+			if ok, _ := s.removeStaleLock(p, cur); ok {
+				removed++
+			}
+			continue
+		case ".takeover":
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			expiresAt = info.ModTime().Add(takeoverMarkerTTL)
+		default:
+			continue
+		}
+		if expiresAt.After(now) {
+			continue
+		}
+		if err := os.Remove(p); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// readJSONFile decodes the file at p into v. Returns ok=false (no
+// error) when the file does not exist. A file that fails to parse is
+// treated as missing — a torn write from a crashed process must not
+// wedge the key forever.
+func readJSONFile(p string, v any) (bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("middleware: idempotency store read: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxIdempotencyFile+1))
+	if err != nil {
+		return false, fmt.Errorf("middleware: idempotency store read: %w", err)
+	}
+	if len(data) > maxIdempotencyFile {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// writeFileAtomic writes data to p via a unique tmp file + rename so a
+// concurrent reader never observes a partial record.
+func writeFileAtomic(p string, data []byte) error {
+	tmp, err := writeTempFile(p, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("middleware: idempotency store rename: %w", err)
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new uniquely named file beside p and
+// returns its path.
+func writeTempFile(p string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("middleware: idempotency store write: %w", err)
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("middleware: idempotency store write: %w", errors.Join(werr, cerr))
+	}
+	return tmp.Name(), nil
+}
+
+// linkExclusive publishes the complete file src under dst, failing
+// with ok=false when dst already exists.
+func linkExclusive(src, dst string) (bool, error) {
+	err := os.Link(src, dst)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("middleware: idempotency lock: %w", err)
+}
+
+// Compile-time assertions.
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+var _ IdempotencyStore = (*FileIdempotencyStore)(nil)
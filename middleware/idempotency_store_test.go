@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency_FileStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	var count int32
+
+	newHandler := func() http.Handler {
+		store, err := NewFileIdempotencyStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return IdempotencyKey(IdempotencyConfig{Store: store})(countingHandler(&count, http.StatusCreated, `{"id":7}`))
+	}
+
+	w1 := doReq(t, newHandler(), http.MethodPost, "deploy-key")
+	if w1.Code != http.StatusCreated {
+		t.Fatalf("first call: status %d", w1.Code)
+	}
+
+	// A fresh middleware (new process after a deploy) over the same
+	// directory must replay, not re-execute.
+	w2 := doReq(t, newHandler(), http.MethodPost, "deploy-key")
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Fatalf("handler ran %d times across restart, want 1", got)
+	}
+	if w2.Code != http.StatusCreated || w2.Body.String() != `{"id":7}` {
+		t.Fatalf("replay: status=%d body=%q", w2.Code, w2.Body.String())
+	}
+	if w2.Header().Get(IdempotencyCachedHeader) != "true" {
+		t.Fatalf("replay after restart should be marked cached")
+	}
+	if w2.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("content-type not persisted: %q", w2.Header().Get("Content-Type"))
+	}
+}
+
+func TestIdempotency_SharedStoreLocksAcrossReplicas(t *testing.T) {
+	store := NewMemoryIdempotencyStore(0)
+	var count int32
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			close(entered)
+		}
+		<-gate
+		_, _ = w.Write([]byte("once"))
+	})
+	cfg := IdempotencyConfig{Store: store}
+	// Two middleware instances = two replicas sharing one store.
+	replicaA := IdempotencyKey(cfg)(handler)
+	replicaB := IdempotencyKey(cfg)(handler)
+
+	var wg sync.WaitGroup
+	codeA := make(chan int, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codeA <- doReq(t, replicaA, http.MethodPost, "shared").Code
+	}()
+	<-entered
+
+	// B arrives while A is still running: 409, handler not invoked.
+	wB := doReq(t, replicaB, http.MethodPost, "shared")
+	if wB.Code != http.StatusConflict {
+		t.Fatalf("replica B during A's run: want 409, got %d", wB.Code)
+	}
+	close(gate)
+	wg.Wait()
+	if code := <-codeA; code != http.StatusOK {
+		t.Fatalf("replica A status %d", code)
+	}
+
+	// B's retry replays A's stored reply.
+	wB = doReq(t, replicaB, http.MethodPost, "shared")
+	if wB.Body.String() != "once" || wB.Header().Get(IdempotencyCachedHeader) != "true" {
+		t.Fatalf("replica B retry should replay A's reply as cached, got %q", wB.Body.String())
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Fatalf("handler ran %d times across replicas, want 1", got)
+	}
+}
+
+func TestIdempotency_ExpiredLockTakenOver(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// A replica that crashed mid-handler left an already-expired lock.
+	if ok, err := store.Lock(ctx, "k", "dead-replica", time.Now().Add(-time.Second)); err != nil || !ok {
+		t.Fatalf("seed lock: ok=%v err=%v", ok, err)
+	}
+
+	var count int32
+	h := IdempotencyKey(IdempotencyConfig{Store: store})(countingHandler(&count, http.StatusOK, `ok`))
+	w := doReq(t, h, http.MethodPost, "k")
+	if w.Code != http.StatusOK || atomic.LoadInt32(&count) != 1 {
+		t.Fatalf("expired lock should be taken over: status=%d count=%d", w.Code, count)
+	}
+}
+
+func TestFileIdempotencyStore_LockOwnership(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	exp := time.Now().Add(time.Minute)
+
+	if ok, _ := store.Lock(ctx, "k", "a", exp); !ok {
+		t.Fatal("first lock should succeed")
+	}
+	if ok, _ := store.Lock(ctx, "k", "b", exp); ok {
+		t.Fatal("second owner must not acquire a held lock")
+	}
+	// Unlock by a non-owner is a no-op.
+	_ = store.Unlock(ctx, "k", "b")
+	if ok, _ := store.Lock(ctx, "k", "b", exp); ok {
+		t.Fatal("non-owner unlock must not release the lock")
+	}
+	_ = store.Unlock(ctx, "k", "a")
+	if ok, _ := store.Lock(ctx, "k", "b", exp); !ok {
+		t.Fatal("lock should be free after owner unlock")
+	}
+}
+
+// raceLock has n replicas — each its own store on dir — claim key at
+// once and returns how many won.
+func raceLock(t *testing.T, dir, key string, n int, exp time.Time) int {
+	t.Helper()
+	var (
+		wg    sync.WaitGroup
+		won   atomic.Int32
+		start = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		store, err := NewFileIdempotencyStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			<-start
+			ok, err := store.Lock(context.Background(), key, owner, exp)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				won.Add(1)
+			}
+		}(fmt.Sprintf("replica-%d", i))
+	}
+	close(start)
+	wg.Wait()
+	return int(won.Load())
+}
+
+func TestFileIdempotencyStore_LockExclusiveUnderContention(t *testing.T) {
+	dir := t.TempDir()
+	exp := time.Now().Add(time.Minute)
+	for round := 0; round < 50; round++ {
+		if n := raceLock(t, dir, fmt.Sprintf("fresh-%d", round), 16, exp); n != 1 {
+			t.Fatalf("round %d: %d replicas acquired a free lock, want 1", round, n)
+		}
+	}
+
+	// Many replicas finding the same expired lock: one takes it over.
+	seed, _ := NewFileIdempotencyStore(dir)
+	for round := 0; round < 50; round++ {
+		key := fmt.Sprintf("stale-%d", round)
+		if ok, err := seed.Lock(context.Background(), key, "dead-replica", time.Now().Add(-time.Second)); err != nil || !ok {
+			t.Fatalf("seed lock: ok=%v err=%v", ok, err)
+		}
+		if n := raceLock(t, dir, key, 16, exp); n != 1 {
+			t.Fatalf("round %d: %d replicas took over an expired lock, want 1", round, n)
+		}
+	}
+}
+
+func TestFileIdempotencyStore_UnreadableLockHeldUntilTTL(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// An empty lock file — say, from a foreign writer — is not proof
+	// that nobody holds the key.
+	p := store.path("k", ".lock")
+	if err := os.WriteFile(p, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Lock(ctx, "k", "a", time.Now().Add(time.Minute)); ok {
+		t.Fatal("a fresh unreadable lock must be treated as held")
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("the unreadable lock was removed: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Lock(ctx, "k", "a", time.Now().Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("an unreadable lock older than the TTL should be taken over: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestFileIdempotencyStore_Sweep(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = store.Put(ctx, "old", IdempotencyRecord{Status: 200, ExpiresAt: time.Now().Add(-time.Minute)})
+	_ = store.Put(ctx, "new", IdempotencyRecord{Status: 200, ExpiresAt: time.Now().Add(time.Minute)})
+	_, _ = store.Lock(ctx, "dead", "a", time.Now().Add(-time.Minute))
+	_, _ = store.Lock(ctx, "live", "a", time.Now().Add(time.Minute))
+
+	// The expired record, and the expired lock (through a takeover
+	// marker, which stays until takeoverMarkerTTL).
+	n, err := store.Sweep()
+	if err != nil || n != 2 {
+		t.Fatalf("sweep: removed=%d err=%v, want 2", n, err)
+	}
+	if ok, _ := store.Lock(ctx, "live", "b", time.Now().Add(time.Minute)); ok {
+		t.Fatal("sweep removed an unexpired lock")
+	}
+	if _, ok, _ := store.Get(ctx, "new"); !ok {
+		t.Fatal("unexpired record must survive the sweep")
+	}
+	store.now = func() time.Time { return time.Now().Add(takeoverMarkerTTL + time.Minute) }
+	if n, _ := store.Sweep(); n != 3 {
+		t.Fatalf("late sweep removed %d files, want the record, the lock and the marker", n)
+	}
+}
+
+type failingIdempotencyStore struct{ MemoryIdempotencyStore }
+
+func (*failingIdempotencyStore) Get(context.Context, string) (IdempotencyRecord, bool, error) {
+	return IdempotencyRecord{}, false, errors.New("backend down")
+}
+
+func TestIdempotency_StoreErrorFailsClosed(t *testing.T) {
+	var count int32
+	h := IdempotencyKey(IdempotencyConfig{Store: &failingIdempotencyStore{}})(countingHandler(&count, http.StatusOK, `ok`))
+	w := doReq(t, h, http.MethodPost, "k")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("store failure: want 503, got %d", w.Code)
+	}
+	if atomic.LoadInt32(&count) != 0 {
+		t.Fatal("handler must not run when the store is unavailable")
+	}
+}
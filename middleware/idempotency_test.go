@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -205,47 +206,117 @@ func TestIdempotency_MaxEntriesLRU(t *testing.T) {
 	}
 }
 
-func TestIdempotency_InFlightDedupSingleflight(t *testing.T) {
+func TestIdempotency_InFlightReturnsConflict(t *testing.T) {
 	var count int32
 	gate := make(chan struct{})
+	entered := make(chan struct{})
 
 	// Block the handler until the gate is closed. While blocked, fire
-	// N concurrent requests with the same key and verify only one
-	// makes it past the gate.
+	// N concurrent requests with the same key and verify none of them
+	// runs the handler or blocks: each gets an immediate 409.
 	h := IdempotencyKey(IdempotencyConfig{})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		atomic.AddInt32(&count, 1)
+		close(entered)
 		<-gate
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("done"))
 	}))
 
-	const N = 8
 	var wg sync.WaitGroup
+	var leader *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		leader = doReq(t, h, http.MethodPost, "same")
+	}()
+	<-entered
+
+	const N = 8
 	recs := make([]*httptest.ResponseRecorder, N)
+	var fwg sync.WaitGroup
 	for i := 0; i < N; i++ {
-		wg.Add(1)
+		fwg.Add(1)
 		go func(i int) {
-			defer wg.Done()
+			defer fwg.Done()
 			recs[i] = doReq(t, h, http.MethodPost, "same")
 		}(i)
 	}
-
-	// Give the goroutines a moment to land in the singleflight queue,
-	// then release the handler. (A short sleep is the canonical way
-	// to assert "concurrent" here; we verify count==1 after, so a too-
-	// short sleep would just produce a stricter check.)
-	time.Sleep(50 * time.Millisecond)
+	fwg.Wait()
 	close(gate)
 	wg.Wait()
 
 	if got := atomic.LoadInt32(&count); got != 1 {
-		t.Fatalf("singleflight should dedupe: handler ran %d times, want 1", got)
+		t.Fatalf("handler ran %d times, want 1", got)
+	}
+	if leader.Code != http.StatusOK || leader.Body.String() != "done" {
+		t.Fatalf("leader: status=%d body=%q", leader.Code, leader.Body.String())
 	}
 	for i, w := range recs {
-		if w.Code != http.StatusOK || w.Body.String() != "done" {
-			t.Fatalf("req %d: status=%d body=%q", i, w.Code, w.Body.String())
+		if w.Code != http.StatusConflict {
+			t.Fatalf("req %d: want 409 while in flight, got %d", i, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "idempotency.in_flight") {
+			t.Fatalf("req %d: missing error_code in body %q", i, w.Body.String())
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Fatalf("req %d: 409 must carry Retry-After", i)
 		}
 	}
+
+	// Once the leader is done, a retry replays its reply.
+	w := doReq(t, h, http.MethodPost, "same")
+	if w.Code != http.StatusOK || w.Header().Get(IdempotencyCachedHeader) != "true" {
+		t.Fatalf("retry after completion: status=%d cached=%q", w.Code, w.Header().Get(IdempotencyCachedHeader))
+	}
+}
+
+func TestIdempotency_FingerprintMismatchRejected(t *testing.T) {
+	var count int32
+	h := IdempotencyKey(IdempotencyConfig{})(countingHandler(&count, http.StatusCreated, `{"id":1}`))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "fp")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/orders", `{"qty":1}`); w.Code != http.StatusCreated {
+		t.Fatalf("first use: status %d", w.Code)
+	}
+	// Genuine retry: same method, path, body → replay.
+	if w := post("/orders", `{"qty":1}`); w.Code != http.StatusCreated || w.Header().Get(IdempotencyCachedHeader) != "true" {
+		t.Fatalf("retry: status=%d cached=%q", w.Code, w.Header().Get(IdempotencyCachedHeader))
+	}
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"body": post("/orders", `{"qty":2}`),
+		"path": post("/refunds", `{"qty":1}`),
+	} {
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s mismatch: want 422, got %d", name, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "idempotency.key_reused") {
+			t.Fatalf("%s mismatch: missing error_code in body %q", name, w.Body.String())
+		}
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Fatalf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestIdempotency_HandlerSeesBodyAfterFingerprint(t *testing.T) {
+	var got string
+	h := IdempotencyKey(IdempotencyConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+	req.Header.Set(IdempotencyKeyHeader, "k")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "payload" {
+		t.Fatalf("handler body after fingerprinting: %q", got)
+	}
 }
 
 func TestIdempotency_CachedHeaderEmitted(t *testing.T) {
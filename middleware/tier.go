@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/baditaflorin/go-common/apikey"
+)
+
+type authTierKey struct{}
+
+// AuthTierFromContext returns the access tier TokenAuthKeystore verified
+// for the caller. ok is false when the request did not pass through
+// keystore auth (bypass paths such as /health included). The tier is ""
+// for trust paths that never verify one — local tokens and the private
+// mesh — which is why it is read from here and never from the
+// X-Auth-Tier header, which those paths leave as the caller sent it.
+func AuthTierFromContext(ctx context.Context) (tier string, ok bool) {
+	tier, ok = ctx.Value(authTierKey{}).(string)
+	return tier, ok
+}
+
+// RequireTier gates a single handler on the caller's access tier, the
+// per-route counterpart of KeystoreOpts.RequiredTier: a request whose
+// AuthTierFromContext does not satisfy requiredTier under
+// apikey.TierSatisfies gets the same 403 the keystore gate writes. It
+// must sit inside TokenAuthKeystore (server.WithKeystoreAuth); without
+// keystore auth no caller has a verified tier and every request is
+// refused. An empty requiredTier admits everything.
+func RequireTier(requiredTier string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if requiredTier == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tier, _ := AuthTierFromContext(r.Context()); !apikey.TierSatisfies(tier, requiredTier) {
+				denyTier(w, requiredTier)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/baditaflorin/go-common/apikey"
+	"github.com/baditaflorin/go-common/header"
+)
+
+func TestRequireTier_UsesVerifiedTier(t *testing.T) {
+	v := &stubVerifier{verify: func(ctx context.Context, k string) (*apikey.VerifyResult, error) {
+		return &apikey.VerifyResult{User: "alice", Scope: "*", Tier: "pro"}, nil
+	}}
+	auth := TokenAuthKeystore(KeystoreOpts{Verifier: v, LocalTokens: []string{"default_token"}})
+	gated := func(tier string) Middleware {
+		return func(next http.Handler) http.Handler { return auth(RequireTier(tier)(next)) }
+	}
+
+	if code, _ := run(t, gated("pro"), newReq("/x?api_key=real")); code != http.StatusOK {
+		t.Fatalf("keystore-verified pro caller: want 200 got %d", code)
+	}
+	if code, _ := run(t, gated("vetted-pentest"), newReq("/x?api_key=real")); code != http.StatusForbidden {
+		t.Fatalf("pro caller on a vetted-pentest route: want 403 got %d", code)
+	}
+
+	// A local token with a smuggled X-Auth-Tier header carries no
+	// verified tier.
+	r := newReq("/x?api_key=default_token")
+	r.Header.Set(header.AuthTier, "pro")
+	if code, _ := run(t, gated("pro"), r); code != http.StatusForbidden {
+		t.Fatalf("local token with a forged tier header: want 403 got %d", code)
+	}
+
+	// Without keystore auth nobody has a tier; an empty requirement admits all.
+	if code, _ := run(t, RequireTier("pro"), newReq("/x")); code != http.StatusForbidden {
+		t.Fatalf("no auth: want 403 got %d", code)
+	}
+	if code, _ := run(t, RequireTier(""), newReq("/x")); code != http.StatusOK {
+		t.Fatalf("empty tier: want 200 got %d", code)
+	}
+}
@@ -7,9 +7,16 @@
 //	// optionally enrich with handler annotations:
 //	openapi.ScanDir(".", spec)
 //	srv := server.New(cfg, server.WithOpenAPI(spec))
+//
+// Schemas can be derived from Go types rather than written by hand:
+// SchemaFor[T] inlines one, RegisterSchema[T] adds it to the spec's
+// components and returns a $ref.
 package openapi
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"reflect"
+)
 
 // Spec is the root OpenAPI 3.0.3 document.  Only the fields fleet services
 // use are included — the type is intentionally minimal.
@@ -18,6 +25,10 @@ type Spec struct {
 	Info       Info                `json:"info"`
 	Paths      map[string]PathItem `json:"paths"`
 	Components *Components         `json:"components,omitempty"`
+
+	// componentNames maps the Go types RegisterSchema has added to
+	// Components to their keys.
+	componentNames map[reflect.Type]string
 }
 
 // Info carries the service identity block.
@@ -29,11 +40,13 @@ type Info struct {
 
 // PathItem groups the operations available on a single URL path.
 type PathItem struct {
-	Get    *Operation `json:"get,omitempty"`
-	Post   *Operation `json:"post,omitempty"`
-	Put    *Operation `json:"put,omitempty"`
-	Delete *Operation `json:"delete,omitempty"`
-	Patch  *Operation `json:"patch,omitempty"`
+	Get     *Operation `json:"get,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
 }
 
 // Operation is a single HTTP method on a path.
@@ -42,9 +55,17 @@ type Operation struct {
 	Description string              `json:"description,omitempty"`
 	Tags        []string            `json:"tags,omitempty"`
 	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
 	Responses   map[string]Response `json:"responses"`
 }
 
+// RequestBody describes the body an operation accepts.
+type RequestBody struct {
+	Description string                     `json:"description,omitempty"`
+	Required    bool                       `json:"required,omitempty"`
+	Content     map[string]MediaTypeObject `json:"content"`
+}
+
 // Parameter describes a path, query, header, or cookie parameter.
 type Parameter struct {
 	Name        string  `json:"name"`
@@ -65,11 +86,27 @@ type MediaTypeObject struct {
 	Schema *Schema `json:"schema,omitempty"`
 }
 
-// Schema is a simplified JSON Schema subset used inside OpenAPI.
+// Schema is a simplified JSON Schema subset used inside OpenAPI. A
+// schema with Ref set stands for the component it names and carries no
+// other keywords.
 type Schema struct {
-	Type       string             `json:"type,omitempty"`
-	Properties map[string]*Schema `json:"properties,omitempty"`
-	Example    interface{}        `json:"example,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
 }
 
 // Components holds reusable schema definitions.
@@ -188,6 +225,10 @@ func (s *Spec) AddRoute(method, path string, op Operation) {
 		item.Delete = &op
 	case "PATCH":
 		item.Patch = &op
+	case "HEAD":
+		item.Head = &op
+	case "OPTIONS":
+		item.Options = &op
 	}
 
 	s.Paths[path] = item
@@ -0,0 +1,375 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// SchemaFor is SchemaOf for T:
+//
+//	type CreateUser struct {
+//	    Name  string `json:"name"  validate:"required,max=64"`
+//	    Email string `json:"email" validate:"required,email"`
+//	    Role  string `json:"role"  validate:"oneof=admin|user|viewer"`
+//	}
+//	op.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaTypeObject{
+//	    "application/json": {Schema: openapi.SchemaFor[CreateUser]()},
+//	}}
+func SchemaFor[T any]() *Schema {
+	return SchemaOf(reflect.TypeFor[T]())
+}
+
+// SchemaOf derives the schema of the JSON encoding/json produces for
+// values of t: struct fields under their `json` names (embedded structs
+// flattened, "-" skipped, ",string" as strings), slices and arrays as
+// arrays ([]byte as base64 strings), maps as objects with
+// additionalProperties, pointers as nullable, and time.Time as a
+// date-time string. A type with its own MarshalJSON is left open ({}),
+// one with MarshalText is a string, and a recursive reference is a bare
+// object.
+//
+// A field's `validate` tag (see package validate) becomes the matching
+// constraint: required lists the field in the object's required,
+// min/max bound a string's length, a number's value or a slice's item
+// count, oneof is an enum, pattern a pattern, and email and url the
+// "email" and "uri" formats.
+//
+// The schema is fully inline; RegisterSchema moves named structs into
+// the spec's components instead.
+func SchemaOf(t reflect.Type) *Schema {
+	g := &schemaGen{visiting: map[reflect.Type]bool{}}
+	return g.schemaOf(t)
+}
+
+// RegisterSchema adds the schema of T to spec.Components.Schemas and
+// returns a {"$ref": "#/components/schemas/<name>"} to it, so a type
+// shared by several operations is described once. Every named struct T
+// reaches is registered the same way under its Go type name
+// (type-argument package paths dropped, "Page[pkg.Item]" → "Page_Item";
+// a second type of the same name is prefixed with its package), which
+// also lets recursive types be described in full. A non-struct T, such
+// as []Item, is returned inline around its references. A pointer to a
+// component is its plain $ref: OpenAPI 3.0 ignores keywords beside one.
+//
+// Registering the same type again returns the same reference.
+func RegisterSchema[T any](spec *Spec) *Schema {
+	return spec.RegisterSchemaOf(reflect.TypeFor[T]())
+}
+
+// RegisterSchemaOf is RegisterSchema for a reflect.Type.
+func (s *Spec) RegisterSchemaOf(t reflect.Type) *Schema {
+	if s.Components == nil {
+		s.Components = &Components{}
+	}
+	if s.Components.Schemas == nil {
+		s.Components.Schemas = map[string]*Schema{}
+	}
+	if s.componentNames == nil {
+		s.componentNames = map[reflect.Type]string{}
+	}
+	g := &schemaGen{visiting: map[reflect.Type]bool{}, spec: s}
+	return g.schemaOf(t)
+}
+
+// schemaGen walks types for SchemaOf and RegisterSchemaOf. With spec
+// set, named structs become components of it.
+type schemaGen struct {
+	visiting map[reflect.Type]bool
+	spec     *Spec
+}
+
+func (g *schemaGen) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := *g.schemaOf(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return &s
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType), reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Uint8, reflect.Uint16, reflect.Int, reflect.Uint, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if g.spec != nil && t.Name() != "" {
+			return g.component(t)
+		}
+		if g.visiting[t] {
+			return &Schema{Type: "object"}
+		}
+		g.visiting[t] = true
+		defer delete(g.visiting, t)
+		return g.object(t)
+	}
+	return &Schema{}
+}
+
+func (g *schemaGen) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+// component registers the named struct t with g.spec, once, and returns
+// a reference to it. The name is reserved before the fields are walked
+// so a recursive field refers back to it.
+func (g *schemaGen) component(t reflect.Type) *Schema {
+	name, ok := g.spec.componentNames[t]
+	if !ok {
+		name = g.spec.componentName(t)
+		g.spec.componentNames[t] = name
+		g.spec.Components.Schemas[name] = g.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// typeArgPath matches a package-qualified type argument up to its final
+// element, e.g. "github.com/x/pkg." in "Page[github.com/x/pkg.Item]".
+var typeArgPath = regexp.MustCompile(`[^\[\],;*]*\.`)
+
+// componentName picks an unused component key for t.
+func (s *Spec) componentName(t reflect.Type) string {
+	base := t.Name()
+	if i := strings.IndexByte(base, '['); i >= 0 {
+		args := typeArgPath.ReplaceAllString(base[i:], "")
+		base = base[:i] + "_" + strings.NewReplacer("[", "", "]", "", ",", "_", "*", "", ";", "_").Replace(args)
+		base = strings.TrimSuffix(base, "_")
+	}
+	name := base
+	if _, taken := s.Components.Schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + base
+	}
+	for i := 2; ; i++ {
+		if _, taken := s.Components.Schemas[name]; !taken {
+			return name
+		}
+		name = path.Base(t.PkgPath()) + "." + base + strconv.Itoa(i)
+	}
+}
+
+// addFields adds t's JSON-visible fields to s.Properties.
+func (g *schemaGen) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := g.schemaOf(ft)
+		if hasOpt(opts, "string") && (fs.Type == "integer" || fs.Type == "number" || fs.Type == "boolean") {
+			fs = &Schema{Type: "string", Nullable: fs.Nullable}
+		}
+		s.Properties[name] = fs
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			applyValidate(s, name, fs, ft, tag)
+		}
+	}
+}
+
+// applyValidate records the rules of a `validate` tag on the field
+// schema fs (of Go type ft) named name inside the object s. Rules with
+// no schema counterpart, or that do not apply to ft, are skipped.
+func applyValidate(s *Schema, name string, fs *Schema, ft reflect.Type, tag string) {
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		key, arg, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			s.Required = append(s.Required, name)
+			continue
+		}
+		if fs.Ref != "" {
+			continue
+		}
+		switch key {
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			setBound(fs, ft, key == "min", n)
+		case "email":
+			fs.Format = "email"
+		case "url":
+			fs.Format = "uri"
+		case "pattern":
+			fs.Pattern = arg
+		case "oneof":
+			fs.Enum = nil
+			for _, c := range strings.Split(arg, "|") {
+				fs.Enum = append(fs.Enum, enumValue(ft, c))
+			}
+		}
+	}
+}
+
+// setBound applies a min (or max) rule the way package validate checks
+// it: on a string's length, a number's value, or a slice's length.
+func setBound(fs *Schema, ft reflect.Type, isMin bool, n float64) {
+	switch ft.Kind() {
+	case reflect.String:
+		l := int(n)
+		if isMin {
+			fs.MinLength = &l
+		} else {
+			fs.MaxLength = &l
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if isMin {
+			fs.Minimum = &n
+		} else {
+			fs.Maximum = &n
+		}
+	case reflect.Slice:
+		if fs.Type != "array" {
+			return
+		}
+		l := int(n)
+		if isMin {
+			fs.MinItems = &l
+		} else {
+			fs.MaxItems = &l
+		}
+	}
+}
+
+// enumValue types a oneof choice like the field it constrains.
+func enumValue(ft reflect.Type, c string) interface{} {
+	switch ft.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(c, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(c, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(c, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(c); err == nil {
+			return b
+		}
+	}
+	return c
+}
+
+func hasOpt(opts, want string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == want {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONSchema returns s as a plain JSON Schema (draft-07) object, the
+// shape agent.Tool.InputSchema and MCP tool descriptions carry:
+// OpenAPI's nullable becomes a "null" alternative in type (and enum).
+// References are kept as they are, so convert inline schemas such as
+// SchemaFor's rather than RegisterSchema's.
+func (s *Schema) JSONSchema() map[string]any {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return map[string]any{}
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return map[string]any{}
+	}
+	dropNullable(m)
+	return m
+}
+
+// dropNullable rewrites OpenAPI's nullable keyword in m and its
+// subschemas into JSON Schema's type alternatives.
+func dropNullable(m map[string]any) {
+	if m["nullable"] == true {
+		delete(m, "nullable")
+		if t, ok := m["type"].(string); ok {
+			m["type"] = []any{t, "null"}
+		}
+		if enum, ok := m["enum"].([]any); ok {
+			m["enum"] = append(enum, nil)
+		}
+	}
+	if props, ok := m["properties"].(map[string]any); ok {
+		for _, p := range props {
+			if pm, ok := p.(map[string]any); ok {
+				dropNullable(pm)
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := m[key].(map[string]any); ok {
+			dropNullable(sub)
+		}
+	}
+}
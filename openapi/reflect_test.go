@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reflectBase struct {
+	ID string `json:"id"`
+}
+
+type reflectNode struct {
+	reflectBase
+	Name     string          `json:"name"`
+	Count    int64           `json:"count,string"`
+	Score    *float64        `json:"score,omitempty"`
+	Tags     []string        `json:"tags"`
+	Labels   map[string]int  `json:"labels"`
+	Raw      []byte          `json:"raw"`
+	At       time.Time       `json:"at"`
+	Extra    json.RawMessage `json:"extra"`
+	Children []*reflectNode  `json:"children"`
+	Skip     string          `json:"-"`
+	internal string          //nolint:unused // unexported fields are not encoded
+	Untagged bool
+	Nested   struct{ X uint8 } `json:"nested"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(reflect.TypeFor[reflectNode]())
+	if s.Type != "object" {
+		t.Fatalf("type = %q", s.Type)
+	}
+	want := map[string]Schema{
+		"id":       {Type: "string"},
+		"name":     {Type: "string"},
+		"count":    {Type: "string"},
+		"score":    {Type: "number", Format: "double", Nullable: true},
+		"raw":      {Type: "string", Format: "byte"},
+		"at":       {Type: "string", Format: "date-time"},
+		"extra":    {},
+		"Untagged": {Type: "boolean"},
+	}
+	for name, w := range want {
+		got := s.Properties[name]
+		if got == nil || got.Type != w.Type || got.Format != w.Format || got.Nullable != w.Nullable {
+			t.Errorf("%s = %+v, want %+v", name, got, w)
+		}
+	}
+	for _, gone := range []string{"Skip", "-", "internal", "reflectBase"} {
+		if _, ok := s.Properties[gone]; ok {
+			t.Errorf("%s should not be a property", gone)
+		}
+	}
+	if tags := s.Properties["tags"]; tags.Type != "array" || tags.Items.Type != "string" {
+		t.Errorf("tags = %+v", tags)
+	}
+	if labels := s.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties.Type != "integer" {
+		t.Errorf("labels = %+v", labels)
+	}
+	if nested := s.Properties["nested"]; nested.Properties["X"].Type != "integer" {
+		t.Errorf("nested = %+v", nested)
+	}
+	// The recursive reference stops at a bare object.
+	child := s.Properties["children"].Items
+	if child.Type != "object" || child.Properties != nil || !child.Nullable {
+		t.Errorf("recursive child = %+v", child)
+	}
+}
+
+type validatedInput struct {
+	Name  string            `json:"name" validate:"required,min=2,max=64"`
+	Email string            `json:"email" validate:"required,email"`
+	Site  *string           `json:"site" validate:"url"`
+	Role  string            `json:"role" validate:"oneof=admin|user"`
+	Level int               `json:"level" validate:"oneof=1|2|3"`
+	Age   uint8             `json:"age" validate:"min=18,max=150"`
+	Code  string            `json:"code" validate:"pattern=^[A-Z]{3}$"`
+	Tags  []string          `json:"tags" validate:"max=10"`
+	Meta  map[string]string `json:"meta" validate:"required"`
+}
+
+func TestSchemaFor_Validate(t *testing.T) {
+	s := SchemaFor[validatedInput]()
+	if got := strings.Join(s.Required, ","); got != "name,email,meta" {
+		t.Errorf("required = %q", got)
+	}
+	p := s.Properties
+	if *p["name"].MinLength != 2 || *p["name"].MaxLength != 64 {
+		t.Errorf("name = %+v", p["name"])
+	}
+	if p["email"].Format != "email" || p["site"].Format != "uri" || !p["site"].Nullable {
+		t.Errorf("email = %+v, site = %+v", p["email"], p["site"])
+	}
+	if !reflect.DeepEqual(p["role"].Enum, []interface{}{"admin", "user"}) ||
+		!reflect.DeepEqual(p["level"].Enum, []interface{}{int64(1), int64(2), int64(3)}) {
+		t.Errorf("role enum = %v, level enum = %v", p["role"].Enum, p["level"].Enum)
+	}
+	if *p["age"].Minimum != 18 || *p["age"].Maximum != 150 || p["age"].MinLength != nil {
+		t.Errorf("age = %+v", p["age"])
+	}
+	if p["code"].Pattern != "^[A-Z]{3}$" {
+		t.Errorf("code = %+v", p["code"])
+	}
+	if p["tags"].MaxItems == nil || *p["tags"].MaxItems != 10 {
+		t.Errorf("tags = %+v", p["tags"])
+	}
+}
+
+type treeNode struct {
+	Value    int         `json:"value"`
+	Children []*treeNode `json:"children"`
+	Owner    *reflectBase
+}
+
+type page[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next"`
+}
+
+func TestRegisterSchema(t *testing.T) {
+	spec := New("svc", "1.0.0")
+	ref := RegisterSchema[treeNode](spec)
+	if ref.Ref != "#/components/schemas/treeNode" || ref.Type != "" {
+		t.Fatalf("ref = %+v", ref)
+	}
+	node := spec.Components.Schemas["treeNode"]
+	if node == nil || node.Properties["children"].Items.Ref != "#/components/schemas/treeNode" {
+		t.Fatalf("recursive component = %+v", node)
+	}
+	if owner := node.Properties["Owner"]; owner.Ref != "#/components/schemas/reflectBase" || owner.Nullable {
+		t.Errorf("pointer to a component = %+v, want its plain $ref", owner)
+	}
+	if spec.Components.Schemas["reflectBase"] == nil {
+		t.Error("nested struct was not registered")
+	}
+	if again := RegisterSchema[treeNode](spec); again.Ref != ref.Ref || len(spec.Components.Schemas) != 2 {
+		t.Errorf("re-registering: %+v, %d components", again, len(spec.Components.Schemas))
+	}
+
+	list := RegisterSchema[[]page[treeNode]](spec)
+	if list.Type != "array" || list.Items.Ref != "#/components/schemas/page_treeNode" {
+		t.Fatalf("generic list = %+v (items %+v)", list, list.Items)
+	}
+	if items := spec.Components.Schemas["page_treeNode"].Properties["items"]; items.Items.Ref != ref.Ref {
+		t.Errorf("page items = %+v", items.Items)
+	}
+
+	// A different type under a taken name is prefixed with its package.
+	type reflectBase struct{ Other bool }
+	if r := RegisterSchema[reflectBase](spec); r.Ref != "#/components/schemas/openapi.reflectBase" {
+		t.Errorf("colliding name = %q", r.Ref)
+	}
+
+	// The result is a valid document: every $ref resolves.
+	b, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllSubmatch(b, -1) {
+		if spec.Components.Schemas[string(m[1])] == nil {
+			t.Errorf("dangling $ref %s", m[1])
+		}
+	}
+}
+
+func TestSchema_JSONSchema(t *testing.T) {
+	type in struct {
+		Mode *string `json:"mode" validate:"oneof=a|b"`
+		List []*int  `json:"list"`
+	}
+	m := SchemaFor[in]().JSONSchema()
+	props := m["properties"].(map[string]any)
+	mode := props["mode"].(map[string]any)
+	if !reflect.DeepEqual(mode["type"], []any{"string", "null"}) || !reflect.DeepEqual(mode["enum"], []any{"a", "b", nil}) {
+		t.Errorf("mode = %v", mode)
+	}
+	items := props["list"].(map[string]any)["items"].(map[string]any)
+	if !reflect.DeepEqual(items["type"], []any{"integer", "null"}) || items["nullable"] != nil {
+		t.Errorf("list items = %v", items)
+	}
+}
@@ -0,0 +1,67 @@
+package promx
+
+import (
+	"github.com/baditaflorin/go-common/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheCollectors records cache.Cache operations for the fleet. Wired
+// by AutoWire as a process-wide default observer, so every cache built
+// with cache.WithName emits metrics with no per-cache wiring.
+//
+// Metrics exposed:
+//
+//	cache_events_total{service, cache, phase}          // hit / stale_hit / negative_hit / miss / load / load_error / evict
+//	cache_evictions_total{service, cache, reason}      // capacity / expired
+//	cache_load_duration_seconds{service, cache, result} // histogram: GetOrLoad loader wall time (ok / error)
+//
+// Hit ratio is `sum(rate(cache_events_total{phase=~"hit|stale_hit"}))
+// / sum(rate(cache_events_total{phase=~"hit|stale_hit|miss"}))`. A
+// rising evictions_total{reason="capacity"} with a falling hit ratio
+// means the cache is undersized.
+type CacheCollectors struct {
+	service string
+
+	events       *prometheus.CounterVec
+	evictions    *prometheus.CounterVec
+	loadDuration *prometheus.HistogramVec
+}
+
+// NewCacheCollectors registers the cache collectors on reg. reg may be
+// nil — the shared promx.Registry() is used in that case.
+func NewCacheCollectors(reg prometheus.Registerer) *CacheCollectors {
+	if reg == nil {
+		reg = Registry()
+	}
+	c := &CacheCollectors{
+		service: ServiceID(),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_events_total",
+			Help: "Total cache operations, labelled by phase (hit/stale_hit/negative_hit/miss/load/load_error/evict).",
+		}, []string{"service", "cache", "phase"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Total cache evictions, labelled by reason (capacity/expired).",
+		}, []string{"service", "cache", "reason"}),
+		loadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_load_duration_seconds",
+			Help:    "Wall time of cache GetOrLoad loader calls. Coalesced loads are observed once.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "cache", "result"}),
+	}
+	reg.MustRegister(c.events, c.evictions, c.loadDuration)
+	return c
+}
+
+// ObserveCache satisfies cache.Observer.
+func (c *CacheCollectors) ObserveCache(ev cache.Event) {
+	c.events.WithLabelValues(c.service, ev.Cache, string(ev.Phase)).Inc()
+	switch ev.Phase {
+	case cache.PhaseEvict:
+		c.evictions.WithLabelValues(c.service, ev.Cache, ev.Reason).Inc()
+	case cache.PhaseLoad:
+		c.loadDuration.WithLabelValues(c.service, ev.Cache, "ok").Observe(ev.Duration.Seconds())
+	case cache.PhaseLoadError:
+		c.loadDuration.WithLabelValues(c.service, ev.Cache, "error").Observe(ev.Duration.Seconds())
+	}
+}
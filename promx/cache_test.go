@@ -0,0 +1,31 @@
+package promx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCacheCollectors_ObserveCache(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := NewCacheCollectors(reg)
+
+	c.ObserveCache(cache.Event{Cache: "users", Phase: cache.PhaseHit})
+	c.ObserveCache(cache.Event{Cache: "users", Phase: cache.PhaseHit})
+	c.ObserveCache(cache.Event{Cache: "users", Phase: cache.PhaseMiss})
+	c.ObserveCache(cache.Event{Cache: "users", Phase: cache.PhaseEvict, Reason: cache.EvictCapacity})
+	c.ObserveCache(cache.Event{Cache: "users", Phase: cache.PhaseLoad, Duration: 20 * time.Millisecond})
+
+	if v := testutil.ToFloat64(c.events.WithLabelValues(c.service, "users", "hit")); v != 2 {
+		t.Fatalf("events(hit) = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(c.evictions.WithLabelValues(c.service, "users", "capacity")); v != 1 {
+		t.Fatalf("evictions(capacity) = %v, want 1", v)
+	}
+	if n := testutil.CollectAndCount(c.loadDuration); n != 1 {
+		t.Fatalf("load duration series = %d, want 1", n)
+	}
+}
@@ -13,18 +13,22 @@ import (
 //	fleet_circuit_state{service, upstream}        // 0=closed 1=open 2=half_open
 //	fleet_circuit_transitions_total{service, upstream, from, to, reason}
 //	fleet_circuit_trips_total{service, upstream}  // shortcut: transitions to open
+//	fleet_circuit_trip_failure_rate{service, upstream} // window failure rate at the last trip
 //
 // fleet_circuit_state is the visual signal — one line per
 // (service, upstream); a 1-line on a graph is a tripped breaker.
 // transitions_total captures the full state machine for forensic
 // drill-down; trips_total is the common "did this breaker trip?"
-// alert target.
+// alert target. trip_failure_rate answers "how bad was it when it
+// tripped?" — it holds the rolling-window failure rate carried on the
+// most recent transition to open.
 type CircuitCollectors struct {
 	service string
 
 	state       *prometheus.GaugeVec
 	transitions *prometheus.CounterVec
 	trips       *prometheus.CounterVec
+	tripRate    *prometheus.GaugeVec
 }
 
 // NewCircuitCollectors registers the circuitbreaker collectors on
@@ -48,8 +52,12 @@ func NewCircuitCollectors(reg prometheus.Registerer) *CircuitCollectors {
 			Name: "fleet_circuit_trips_total",
 			Help: "Total times a circuit-breaker transitioned to open.",
 		}, []string{"service", "upstream"}),
+		tripRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "fleet_circuit_trip_failure_rate",
+			Help: "Rolling-window failure rate (0..1) observed when the breaker last tripped open.",
+		}, []string{"service", "upstream"}),
 	}
-	reg.MustRegister(c.state, c.transitions, c.trips)
+	reg.MustRegister(c.state, c.transitions, c.trips, c.tripRate)
 	return c
 }
 
@@ -59,5 +67,6 @@ func (c *CircuitCollectors) ObserveCircuit(ev circuitbreaker.Event) {
 	c.transitions.WithLabelValues(c.service, ev.Upstream, ev.From.String(), ev.To.String(), ev.Reason).Inc()
 	if ev.To == circuitbreaker.StateOpen && ev.From != circuitbreaker.StateOpen {
 		c.trips.WithLabelValues(c.service, ev.Upstream).Inc()
+		c.tripRate.WithLabelValues(c.service, ev.Upstream).Set(ev.FailureRate)
 	}
 }
@@ -0,0 +1,27 @@
+package promx
+
+import (
+	"testing"
+
+	"github.com/baditaflorin/go-common/circuitbreaker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCircuitCollectors_TripFailureRate(t *testing.T) {
+	c := NewCircuitCollectors(prometheus.NewRegistry())
+	c.ObserveCircuit(circuitbreaker.Event{
+		Upstream:    "html-proxy",
+		From:        circuitbreaker.StateClosed,
+		To:          circuitbreaker.StateOpen,
+		Reason:      "failure_rate_exceeded",
+		FailureRate: 0.45,
+		Requests:    40,
+	})
+	if got := testutil.ToFloat64(c.tripRate.WithLabelValues(c.service, "html-proxy")); got != 0.45 {
+		t.Fatalf("trip failure rate = %v, want 0.45", got)
+	}
+	if got := testutil.ToFloat64(c.trips.WithLabelValues(c.service, "html-proxy")); got != 1 {
+		t.Fatalf("trips = %v, want 1", got)
+	}
+}
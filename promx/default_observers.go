@@ -2,11 +2,14 @@ package promx
 
 import (
 	"github.com/baditaflorin/go-common/backoffcoord"
+	"github.com/baditaflorin/go-common/cache"
 	"github.com/baditaflorin/go-common/circuitbreaker"
 	"github.com/baditaflorin/go-common/degraded"
 	"github.com/baditaflorin/go-common/fleetfetch"
+	"github.com/baditaflorin/go-common/hedge"
 	"github.com/baditaflorin/go-common/loadshed"
 	"github.com/baditaflorin/go-common/response"
+	"github.com/baditaflorin/go-common/retry"
 	"github.com/baditaflorin/go-common/safehttp"
 	"github.com/baditaflorin/go-common/workpool"
 )
@@ -23,3 +26,6 @@ func setCircuitDefaultObserver(c *CircuitCollectors)           { circuitbreaker.
 func setWorkpoolDefaultObserver(c *WorkpoolCollectors)         { workpool.SetDefaultObserver(c) }
 func setLoadshedDefaultObserver(c *LoadshedCollectors)         { loadshed.SetDefaultObserver(c) }
 func setBackoffCoordDefaultObserver(c *BackoffCoordCollectors) { backoffcoord.SetDefaultObserver(c) }
+func setCacheDefaultObserver(c *CacheCollectors)               { cache.SetDefaultObserver(c) }
+func setRetryDefaultObserver(c *RetryCollectors)               { retry.SetDefaultObserver(c) }
+func setHedgeDefaultObserver(c *HedgeCollectors)               { hedge.SetDefaultObserver(c) }
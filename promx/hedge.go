@@ -0,0 +1,56 @@
+package promx
+
+import (
+	"github.com/baditaflorin/go-common/hedge"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HedgeCollectors records hedged-request outcomes for the fleet. Wired
+// by AutoWire as a process-wide default observer.
+//
+// Metrics exposed:
+//
+//	hedge_events_total{service, host, phase}  // issued / won / lost / rate_limited
+//	hedge_delay_seconds{service, host}        // histogram: delay before each issued hedge
+//
+// won / issued is how often hedging actually paid off; if it is near
+// zero the delay is too short and the hedges are pure extra load.
+type HedgeCollectors struct {
+	service string
+	cap     *hostCardCap
+
+	events *prometheus.CounterVec
+	delay  *prometheus.HistogramVec
+}
+
+// NewHedgeCollectors registers the hedge collectors on reg. reg may be
+// nil — the shared promx.Registry() is used in that case.
+func NewHedgeCollectors(reg prometheus.Registerer) *HedgeCollectors {
+	if reg == nil {
+		reg = Registry()
+	}
+	c := &HedgeCollectors{
+		service: ServiceID(),
+		cap:     newHostCardCap(256),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hedge_events_total",
+			Help: "Total hedged-request events, labelled by phase (issued/won/lost/rate_limited).",
+		}, []string{"service", "host", "phase"}),
+		delay: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hedge_delay_seconds",
+			Help:    "Delay after which each hedge request was issued.",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, []string{"service", "host"}),
+	}
+	reg.MustRegister(c.events, c.delay)
+	return c
+}
+
+// ObserveHedge satisfies hedge.Observer.
+func (c *HedgeCollectors) ObserveHedge(ev hedge.Event) {
+	host := c.cap.label(ev.Host)
+	c.events.WithLabelValues(c.service, host, string(ev.Phase)).Inc()
+	if ev.Phase == hedge.PhaseIssued {
+		c.delay.WithLabelValues(c.service, host).Observe(ev.Delay.Seconds())
+	}
+}
@@ -0,0 +1,22 @@
+package promx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/hedge"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHedgeCollectors_IssuedAndWon(t *testing.T) {
+	c := NewHedgeCollectors(prometheus.NewRegistry())
+	c.ObserveHedge(hedge.Event{Host: "replica.internal", Phase: hedge.PhaseIssued, Delay: 80 * time.Millisecond})
+	c.ObserveHedge(hedge.Event{Host: "replica.internal", Phase: hedge.PhaseWon, Delay: 80 * time.Millisecond})
+
+	for _, phase := range []string{"issued", "won"} {
+		if got := testutil.ToFloat64(c.events.WithLabelValues(c.service, "replica.internal", phase)); got != 1 {
+			t.Fatalf("%s = %v, want 1", phase, got)
+		}
+	}
+}
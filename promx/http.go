@@ -147,6 +147,19 @@ func NewHTTPCollectors(reg prometheus.Registerer, opts ...HTTPOption) *HTTPColle
 	return c
 }
 
+// WithRouteFunc returns a view of c that records into the same metric
+// vectors but derives the "route" label with fn — WithRouteFunc for
+// collectors that already exist, such as the AutoWire set every
+// server.New in the process shares. A nil fn returns c unchanged.
+func (c *HTTPCollectors) WithRouteFunc(fn func(*http.Request) string) *HTTPCollectors {
+	if fn == nil {
+		return c
+	}
+	v := *c
+	v.routeFn = fn
+	return &v
+}
+
 // Middleware returns the net/http middleware that records every request
 // on the collectors. Use middleware.Chain to compose it with logging,
 // auth, etc. The /metrics endpoint should NOT be wrapped — it would
@@ -55,6 +55,26 @@ func TestHTTPMiddlewareRouteFunc(t *testing.T) {
 	}
 }
 
+// TestHTTPCollectorsWithRouteFunc: the view shares the parent's vectors,
+// so an existing collector set can be relabelled without re-registering.
+func TestHTTPCollectorsWithRouteFunc(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	coll := NewHTTPCollectors(reg)
+	view := coll.WithRouteFunc(func(r *http.Request) string { return "/things/{id}" })
+	if coll.WithRouteFunc(nil) != coll {
+		t.Fatal("a nil RouteFunc should return the collectors unchanged")
+	}
+	view.Middleware()(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/things/7", nil))
+	coll.Middleware()(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/things/7", nil))
+
+	if got := testutil.ToFloat64(coll.requestsTotal.WithLabelValues(coll.service, "GET", "/things/{id}", "404")); got != 1 {
+		t.Errorf("view-labelled requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(coll.requestsTotal.WithLabelValues(coll.service, "GET", "/things/7", "404")); got != 1 {
+		t.Errorf("parent-labelled requests = %v, want 1", got)
+	}
+}
+
 // TestHTTPMiddlewareCapturesStatus: a non-200 response is reflected in
 // the status label.
 func TestHTTPMiddlewareCapturesStatus(t *testing.T) {
@@ -11,19 +11,32 @@ import (
 //
 // Metrics exposed:
 //
-//	loadshed_in_flight{service, gate}      // gauge: admitted-but-not-released callers
-//	loadshed_admitted_total{service, gate} // counter: callers granted a slot
-//	loadshed_shed_total{service, gate}     // counter: callers refused (gate full)
+//	loadshed_in_flight{service, gate}                     // gauge: admitted-but-not-released callers
+//	loadshed_admitted_total{service, gate}                // counter: callers granted a slot
+//	loadshed_shed_total{service, gate}                    // counter: callers refused (gate full)
+//	loadshed_limit{service, gate}                         // gauge: current limit (0 = unbounded); moves on adaptive gates
+//	loadshed_queued{service, gate}                        // gauge: Acquire callers waiting for a slot
+//	loadshed_queue_wait_seconds{service, gate}            // histogram: time queued callers waited
+//	loadshed_priority_shed_total{service, gate, priority} // counter: sheds by caller tier
 //
 // loadshed_shed_total is the canonical "this service is shedding load"
 // alert signal — any sustained nonzero rate means the gate's upstream
 // is saturated and callers are being fast-503'd. The companion
 // loadshed_in_flight pinned at the gate's limit confirms saturation
-// (vs. a transient blip).
+// (vs. a transient blip). On an adaptive gate compare against
+// loadshed_limit rather than the configured ceiling: a limit pinned at
+// MinLimit means the upstream is degraded, not merely busy.
+// loadshed_priority_shed_total shows which tiers are paying: batch
+// sheds alone are the tiering working as intended; interactive or
+// critical sheds mean the gate is undersized.
 type LoadshedCollectors struct {
 	service string
 
 	inflight      *prometheus.GaugeVec
+	limit         *prometheus.GaugeVec
+	queued        *prometheus.GaugeVec
+	queueWait     *prometheus.HistogramVec
+	priorityShed  *prometheus.CounterVec
 	admittedTotal *prometheus.CounterVec
 	shedTotal     *prometheus.CounterVec
 }
@@ -40,6 +53,23 @@ func NewLoadshedCollectors(reg prometheus.Registerer) *LoadshedCollectors {
 			Name: "loadshed_in_flight",
 			Help: "Current number of admitted-but-not-released callers holding a loadshed gate slot.",
 		}, []string{"service", "gate"}),
+		limit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "loadshed_limit",
+			Help: "Current loadshed gate concurrency limit (0 = unbounded). Moves on adaptive gates.",
+		}, []string{"service", "gate"}),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "loadshed_queued",
+			Help: "Current number of callers waiting in a loadshed gate's queue.",
+		}, []string{"service", "gate"}),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "loadshed_queue_wait_seconds",
+			Help:    "Time callers spent queued for a loadshed gate slot, admitted or shed.",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"service", "gate"}),
+		priorityShed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loadshed_priority_shed_total",
+			Help: "Total callers shed by a loadshed gate, by caller priority tier.",
+		}, []string{"service", "gate", "priority"}),
 		admittedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "loadshed_admitted_total",
 			Help: "Total callers granted a loadshed gate slot.",
@@ -49,17 +79,27 @@ func NewLoadshedCollectors(reg prometheus.Registerer) *LoadshedCollectors {
 			Help: "Total callers shed (fast-503'd) because a loadshed gate was full. The canonical load-shedding alert signal.",
 		}, []string{"service", "gate"}),
 	}
-	reg.MustRegister(c.inflight, c.admittedTotal, c.shedTotal)
+	reg.MustRegister(c.inflight, c.limit, c.queued, c.queueWait, c.priorityShed, c.admittedTotal, c.shedTotal)
 	return c
 }
 
 // ObserveLoadshed satisfies loadshed.Observer.
 func (c *LoadshedCollectors) ObserveLoadshed(ev loadshed.Event) {
 	c.inflight.WithLabelValues(c.service, ev.Gate).Set(float64(ev.InFlight))
+	c.limit.WithLabelValues(c.service, ev.Gate).Set(float64(ev.Limit))
+	c.queued.WithLabelValues(c.service, ev.Gate).Set(float64(ev.Queued))
+	if ev.Wait > 0 {
+		c.queueWait.WithLabelValues(c.service, ev.Gate).Observe(ev.Wait.Seconds())
+	}
 	switch ev.Phase {
 	case loadshed.PhaseAdmitted:
 		c.admittedTotal.WithLabelValues(c.service, ev.Gate).Inc()
 	case loadshed.PhaseShed:
 		c.shedTotal.WithLabelValues(c.service, ev.Gate).Inc()
+		p := string(ev.Priority)
+		if p == "" {
+			p = "_unknown"
+		}
+		c.priorityShed.WithLabelValues(c.service, ev.Gate, p).Inc()
 	}
 }
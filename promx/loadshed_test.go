@@ -0,0 +1,39 @@
+package promx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/loadshed"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoadshedCollectors_TracksLiveLimit(t *testing.T) {
+	c := NewLoadshedCollectors(prometheus.NewRegistry())
+	c.ObserveLoadshed(loadshed.Event{Gate: "render", Phase: loadshed.PhaseAdmitted, Limit: 20, InFlight: 1})
+	c.ObserveLoadshed(loadshed.Event{Gate: "render", Phase: loadshed.PhaseLimit, Limit: 18, InFlight: 0})
+
+	if got := testutil.ToFloat64(c.limit.WithLabelValues(c.service, "render")); got != 18 {
+		t.Fatalf("loadshed_limit = %v, want 18", got)
+	}
+	if got := testutil.ToFloat64(c.admittedTotal.WithLabelValues(c.service, "render")); got != 1 {
+		t.Fatalf("admitted = %v, want 1 (PhaseLimit must not count)", got)
+	}
+}
+
+func TestLoadshedCollectors_PriorityShedAndQueueWait(t *testing.T) {
+	c := NewLoadshedCollectors(prometheus.NewRegistry())
+	c.ObserveLoadshed(loadshed.Event{Gate: "render", Phase: loadshed.PhaseQueued, Priority: loadshed.PriorityBatch, Limit: 4, Queued: 1})
+	c.ObserveLoadshed(loadshed.Event{Gate: "render", Phase: loadshed.PhaseShed, Priority: loadshed.PriorityBatch, Limit: 4, Wait: 50 * time.Millisecond})
+
+	if got := testutil.ToFloat64(c.priorityShed.WithLabelValues(c.service, "render", "batch")); got != 1 {
+		t.Fatalf("priority shed = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(c.queued.WithLabelValues(c.service, "render")); got != 0 {
+		t.Fatalf("queued = %v, want 0 after the waiter left", got)
+	}
+	if got := testutil.CollectAndCount(c.queueWait); got != 1 {
+		t.Fatalf("queue wait series = %d, want 1", got)
+	}
+}
@@ -56,6 +56,9 @@ var (
 	autoWorkpool     *WorkpoolCollectors
 	autoLoadshed     *LoadshedCollectors
 	autoBackoffCoord *BackoffCoordCollectors
+	autoCache        *CacheCollectors
+	autoRetry        *RetryCollectors
+	autoHedge        *HedgeCollectors
 	autoBoundReg     *prometheus.Registry // the registry the singletons are bound to
 )
 
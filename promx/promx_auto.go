@@ -34,6 +34,9 @@ func AutoWire(serviceID, version string) (*EgressCollectors, *HTTPCollectors, *A
 		autoWorkpool = nil
 		autoLoadshed = nil
 		autoBackoffCoord = nil
+		autoCache = nil
+		autoRetry = nil
+		autoHedge = nil
 		autoBoundReg = reg
 	}
 	if autoEgress == nil {
@@ -98,6 +101,18 @@ func AutoWire(serviceID, version string) (*EgressCollectors, *HTTPCollectors, *A
 		autoBackoffCoord = NewBackoffCoordCollectors(reg)
 		setBackoffCoordDefaultObserver(autoBackoffCoord)
 	}
+	if autoCache == nil {
+		autoCache = NewCacheCollectors(reg)
+		setCacheDefaultObserver(autoCache)
+	}
+	if autoRetry == nil {
+		autoRetry = NewRetryCollectors(reg)
+		setRetryDefaultObserver(autoRetry)
+	}
+	if autoHedge == nil {
+		autoHedge = NewHedgeCollectors(reg)
+		setHedgeDefaultObserver(autoHedge)
+	}
 	return autoEgress, autoHTTP, autoAuth
 }
 
@@ -169,6 +184,30 @@ func AutoBackoffCoord() *BackoffCoordCollectors {
 	return autoBackoffCoord
 }
 
+// AutoCache returns the singleton CacheCollectors. AutoWire has
+// already installed it as the process-wide cache.Observer.
+func AutoCache() *CacheCollectors {
+	autoMu.Lock()
+	defer autoMu.Unlock()
+	return autoCache
+}
+
+// AutoRetry returns the singleton RetryCollectors. AutoWire has
+// already installed it as the process-wide retry.Observer.
+func AutoRetry() *RetryCollectors {
+	autoMu.Lock()
+	defer autoMu.Unlock()
+	return autoRetry
+}
+
+// AutoHedge returns the singleton HedgeCollectors. AutoWire has
+// already installed it as the process-wide hedge.Observer.
+func AutoHedge() *HedgeCollectors {
+	autoMu.Lock()
+	defer autoMu.Unlock()
+	return autoHedge
+}
+
 // AutoSelftest returns the singleton SelftestCollectors created by
 // AutoWire. Returns nil if AutoWire has not been called. Wire it on
 // your selftest.Suite via selftest.WithObserver(promx.AutoSelftest()).
@@ -15,8 +15,16 @@ import (
 //	ratecoord_decisions_total{service, host, outcome}
 //	ratecoord_fallback_total{service}
 //	ratecoord_wait_seconds{service, fellback}
+//	ratecoord_lease_size{service}         // histogram: tokens granted per Reservation lease
+//	ratecoord_lease_utilization{service}  // histogram: share of a lease spent before it ended
 //
-// "outcome" is one of: "allowed", "fallback_allowed", "fallback_denied".
+// "outcome" is one of: "allowed", "leased" (served from a Reservation's
+// lease, no round trip), "fallback_allowed", "fallback_denied",
+// "prelimit_denied" (the host's local policy bucket refused the call
+// before the coordinator was asked).
+// A low ratecoord_lease_utilization means ReserveOptions.Tokens is
+// oversized for the traffic: tokens sit leased and unspent until the
+// TTL returns them.
 // `ratecoord_fallback_total` is a fleet-wide canary for coordinator
 // outage — a sudden non-zero rate across many services means the
 // central service is unreachable and every caller is now running on
@@ -28,9 +36,11 @@ type RateCoordCollectors struct {
 	service string
 	cap     *hostCardCap
 
-	decisions *prometheus.CounterVec
-	fallback  *prometheus.CounterVec
-	wait      *prometheus.HistogramVec
+	decisions   *prometheus.CounterVec
+	fallback    *prometheus.CounterVec
+	wait        *prometheus.HistogramVec
+	leaseSize   *prometheus.HistogramVec
+	utilization *prometheus.HistogramVec
 }
 
 // NewRateCoordCollectors registers the ratecoord collectors on reg.
@@ -55,20 +65,41 @@ func NewRateCoordCollectors(reg prometheus.Registerer) *RateCoordCollectors {
 			Help:    "Time spent waiting on a token, labelled by whether the in-process fallback served the answer.",
 			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 		}, []string{"service", "fellback"}),
+		leaseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ratecoord_lease_size",
+			Help:    "Tokens granted per ratecoord Reservation lease, observed when the lease ends.",
+			Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200},
+		}, []string{"service"}),
+		utilization: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ratecoord_lease_utilization",
+			Help:    "Fraction of a ratecoord Reservation lease spent before it ended.",
+			Buckets: []float64{0.1, 0.25, 0.5, 0.75, 0.9, 1},
+		}, []string{"service"}),
 	}
-	reg.MustRegister(c.decisions, c.fallback, c.wait)
+	reg.MustRegister(c.decisions, c.fallback, c.wait, c.leaseSize, c.utilization)
 	return c
 }
 
 // ObserveRate satisfies ratecoord.Observer.
 func (c *RateCoordCollectors) ObserveRate(ev ratecoord.Event) {
+	if ev.LeaseClosed {
+		if ev.LeaseSize > 0 {
+			c.leaseSize.WithLabelValues(c.service).Observe(float64(ev.LeaseSize))
+			c.utilization.WithLabelValues(c.service).Observe(float64(ev.LeaseUsed) / float64(ev.LeaseSize))
+		}
+		return
+	}
 	host := c.cap.label(ev.Host)
 	outcome := "allowed"
 	switch {
+	case ev.PreLimited && !ev.Allowed && !ev.FellBack:
+		outcome = "prelimit_denied"
 	case ev.FellBack && !ev.Allowed:
 		outcome = "fallback_denied"
 	case ev.FellBack:
 		outcome = "fallback_allowed"
+	case ev.Leased:
+		outcome = "leased"
 	}
 	c.decisions.WithLabelValues(c.service, host, outcome).Inc()
 	if ev.FellBack {
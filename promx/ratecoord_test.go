@@ -19,6 +19,8 @@ func TestRateCoordCollectors_Outcomes(t *testing.T) {
 	c.ObserveRate(ratecoord.Event{Host: "b.example", Weight: 1, Waited: 200 * time.Millisecond, Allowed: true, FellBack: true})
 	// Fallback denied (local bucket said no within maxWait).
 	c.ObserveRate(ratecoord.Event{Host: "b.example", Weight: 1, Waited: 5 * time.Second, Allowed: false, FellBack: true})
+	// Pre-limit denied (host policy bucket refused before the coordinator).
+	c.ObserveRate(ratecoord.Event{Host: "c.example", Weight: 2, Waited: time.Second, Allowed: false, PreLimited: true})
 
 	if v := testutil.ToFloat64(c.decisions.WithLabelValues(c.service, "a.example", "allowed")); v != 1 {
 		t.Fatalf("decisions(a,allowed) = %v, want 1", v)
@@ -29,7 +31,28 @@ func TestRateCoordCollectors_Outcomes(t *testing.T) {
 	if v := testutil.ToFloat64(c.decisions.WithLabelValues(c.service, "b.example", "fallback_denied")); v != 1 {
 		t.Fatalf("decisions(b,fallback_denied) = %v, want 1", v)
 	}
+	if v := testutil.ToFloat64(c.decisions.WithLabelValues(c.service, "c.example", "prelimit_denied")); v != 1 {
+		t.Fatalf("decisions(c,prelimit_denied) = %v, want 1", v)
+	}
 	if v := testutil.ToFloat64(c.fallback.WithLabelValues(c.service)); v != 2 {
 		t.Fatalf("fallback total = %v, want 2", v)
 	}
 }
+
+func TestRateCoordCollectors_Leases(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := NewRateCoordCollectors(reg)
+
+	c.ObserveRate(ratecoord.Event{Host: "a.example", Weight: 1, Allowed: true, Leased: true, LeaseSize: 10, LeaseUsed: 1})
+	c.ObserveRate(ratecoord.Event{Host: "a.example", Leased: true, LeaseClosed: true, LeaseSize: 10, LeaseUsed: 4})
+
+	if v := testutil.ToFloat64(c.decisions.WithLabelValues(c.service, "a.example", "leased")); v != 1 {
+		t.Fatalf("decisions(a,leased) = %v, want 1 (the closing event is not a call)", v)
+	}
+	if n := testutil.CollectAndCount(c.utilization); n != 1 {
+		t.Fatalf("utilization series = %d, want 1", n)
+	}
+	if n := testutil.CollectAndCount(c.wait); n != 1 {
+		t.Fatalf("wait series = %d, want 1", n)
+	}
+}
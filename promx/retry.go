@@ -0,0 +1,78 @@
+package promx
+
+import (
+	"github.com/baditaflorin/go-common/retry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RetryCollectors records retry.Transport attempts for the fleet.
+// Wired by AutoWire as a process-wide default observer.
+//
+// Metrics exposed:
+//
+//	retry_attempts_total{service, host}          // every round trip, first attempt included
+//	retry_retries_total{service, host, source}   // retries scheduled; source = backoff / retry_after
+//	retry_giveups_total{service, host, reason}   // retryable but returned; reason = exhausted / budget
+//	retry_wait_seconds{service, host}            // histogram: wait before each retry
+//
+// retries_total / attempts_total is the retry amplification a host is
+// causing; a climbing giveups_total{reason="budget"} means the
+// RetryBudget is doing its job during an outage.
+type RetryCollectors struct {
+	service string
+	cap     *hostCardCap
+
+	attempts *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+	giveups  *prometheus.CounterVec
+	wait     *prometheus.HistogramVec
+}
+
+// NewRetryCollectors registers the retry collectors on reg. reg may be
+// nil — the shared promx.Registry() is used in that case.
+func NewRetryCollectors(reg prometheus.Registerer) *RetryCollectors {
+	if reg == nil {
+		reg = Registry()
+	}
+	c := &RetryCollectors{
+		service: ServiceID(),
+		cap:     newHostCardCap(256),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retry_attempts_total",
+			Help: "Total round trips made by retry.Transport, first attempts included.",
+		}, []string{"service", "host"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retry_retries_total",
+			Help: "Total retries scheduled by retry.Transport, labelled by wait source (backoff/retry_after).",
+		}, []string{"service", "host", "source"}),
+		giveups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retry_giveups_total",
+			Help: "Retryable failures returned to the caller, labelled by reason (exhausted/budget).",
+		}, []string{"service", "host", "reason"}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "retry_wait_seconds",
+			Help:    "Wait before each retry.Transport retry.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"service", "host"}),
+	}
+	reg.MustRegister(c.attempts, c.retries, c.giveups, c.wait)
+	return c
+}
+
+// ObserveRetry satisfies retry.Observer.
+func (c *RetryCollectors) ObserveRetry(ev retry.Event) {
+	host := c.cap.label(ev.Host)
+	switch ev.Phase {
+	case retry.PhaseAttempt:
+		c.attempts.WithLabelValues(c.service, host).Inc()
+	case retry.PhaseRetry:
+		source := "backoff"
+		if ev.FromServer {
+			source = "retry_after"
+		}
+		c.retries.WithLabelValues(c.service, host, source).Inc()
+		c.wait.WithLabelValues(c.service, host).Observe(ev.Wait.Seconds())
+	case retry.PhaseExhausted, retry.PhaseBudget:
+		c.giveups.WithLabelValues(c.service, host, string(ev.Phase)).Inc()
+	}
+}
@@ -0,0 +1,28 @@
+package promx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRetryCollectors_CountsPerHost(t *testing.T) {
+	c := NewRetryCollectors(prometheus.NewRegistry())
+	c.ObserveRetry(retry.Event{Host: "api.example.com", Phase: retry.PhaseAttempt, Attempt: 1, StatusCode: 503})
+	c.ObserveRetry(retry.Event{Host: "api.example.com", Phase: retry.PhaseRetry, Attempt: 1, Wait: 2 * time.Second, FromServer: true})
+	c.ObserveRetry(retry.Event{Host: "api.example.com", Phase: retry.PhaseAttempt, Attempt: 2, StatusCode: 503})
+	c.ObserveRetry(retry.Event{Host: "api.example.com", Phase: retry.PhaseBudget, Attempt: 2})
+
+	if got := testutil.ToFloat64(c.attempts.WithLabelValues(c.service, "api.example.com")); got != 2 {
+		t.Fatalf("attempts = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.retries.WithLabelValues(c.service, "api.example.com", "retry_after")); got != 1 {
+		t.Fatalf("retry_after retries = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(c.giveups.WithLabelValues(c.service, "api.example.com", "budget")); got != 1 {
+		t.Fatalf("budget giveups = %v, want 1", got)
+	}
+}
@@ -10,22 +10,33 @@ import (
 //
 // Metrics exposed:
 //
-//	pool_inflight{service, pool}            // gauge: current in-flight tasks
-//	pool_queue_depth{service, pool}         // gauge: callers blocked in Submit
-//	pool_phase_total{service, pool, phase}  // counter: queued / started / finished / canceled / shed
+//	pool_inflight{service, pool}              // gauge: current in-flight tasks
+//	pool_queue_depth{service, pool}           // gauge: callers blocked in Submit
+//	pool_phase_total{service, pool, phase}    // counter: queued / started / finished / canceled / shed / panicked / resized
+//	pool_task_duration_seconds{service, pool} // histogram: task run time, observed on finish
+//	pool_size{service, pool}                  // gauge: concurrency limit (moves on Resize)
+//	pool_key_queue_depth{service, pool, key}  // gauge: callers blocked in SubmitKey, per key
 //
 // Saturation pattern: alert on
 //
-//	`pool_inflight / on(service, pool) group_left pool_size >= 1`
+//	`pool_inflight / on(service, pool) pool_size >= 1`
 //
-// once we publish pool_size as a separate gauge — for now,
-// `pool_queue_depth > 0` is the canonical "you're starved" signal.
+// or `pool_queue_depth > 0` — the canonical "you're starved" signal.
+// pool_key_queue_depth shows which SubmitKey key (host, caller) the
+// backlog belongs to; keys are capped at 256 per collector, overflow
+// folding into "_other".
+// Any pool_phase_total{phase="panicked"} is a bug: Group/Map recovered
+// it, but the task is broken.
 type WorkpoolCollectors struct {
 	service string
+	keys    *hostCardCap
 
 	inflight   *prometheus.GaugeVec
 	queueDepth *prometheus.GaugeVec
 	phaseTotal *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	size       *prometheus.GaugeVec
+	keyDepth   *prometheus.GaugeVec
 }
 
 // NewWorkpoolCollectors registers the workpool collectors on reg. reg
@@ -36,6 +47,7 @@ func NewWorkpoolCollectors(reg prometheus.Registerer) *WorkpoolCollectors {
 	}
 	c := &WorkpoolCollectors{
 		service: ServiceID(),
+		keys:    newHostCardCap(256),
 		inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "pool_inflight",
 			Help: "Current number of in-flight tasks in a workpool.",
@@ -46,10 +58,23 @@ func NewWorkpoolCollectors(reg prometheus.Registerer) *WorkpoolCollectors {
 		}, []string{"service", "pool"}),
 		phaseTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "pool_phase_total",
-			Help: "Total workpool lifecycle events, labelled by phase (queued/started/finished/canceled/shed).",
+			Help: "Total workpool lifecycle events, labelled by phase (queued/started/finished/canceled/shed/panicked/resized).",
 		}, []string{"service", "pool", "phase"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pool_task_duration_seconds",
+			Help:    "Workpool task run time, from slot acquisition to return.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "pool"}),
+		size: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pool_size",
+			Help: "Current workpool concurrency limit.",
+		}, []string{"service", "pool"}),
+		keyDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pool_key_queue_depth",
+			Help: "Current number of callers blocked in SubmitKey, per key.",
+		}, []string{"service", "pool", "key"}),
 	}
-	reg.MustRegister(c.inflight, c.queueDepth, c.phaseTotal)
+	reg.MustRegister(c.inflight, c.queueDepth, c.phaseTotal, c.duration, c.size, c.keyDepth)
 	return c
 }
 
@@ -58,4 +83,11 @@ func (c *WorkpoolCollectors) ObserveWorkpool(ev workpool.Event) {
 	c.inflight.WithLabelValues(c.service, ev.Pool).Set(float64(ev.InFlight))
 	c.queueDepth.WithLabelValues(c.service, ev.Pool).Set(float64(ev.QueueDepth))
 	c.phaseTotal.WithLabelValues(c.service, ev.Pool, string(ev.Phase)).Inc()
+	c.size.WithLabelValues(c.service, ev.Pool).Set(float64(ev.Size))
+	if ev.Key != "" {
+		c.keyDepth.WithLabelValues(c.service, ev.Pool, c.keys.label(ev.Key)).Set(float64(ev.KeyQueueDepth))
+	}
+	if ev.Phase == workpool.PhaseFinished {
+		c.duration.WithLabelValues(c.service, ev.Pool).Observe(ev.Duration.Seconds())
+	}
 }
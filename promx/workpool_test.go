@@ -0,0 +1,36 @@
+package promx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/workpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWorkpoolCollectors_DurationAndPanics(t *testing.T) {
+	c := NewWorkpoolCollectors(prometheus.NewRegistry())
+	c.ObserveWorkpool(workpool.Event{Pool: "crawler", Phase: workpool.PhasePanicked, Panic: "boom"})
+	c.ObserveWorkpool(workpool.Event{Pool: "crawler", Phase: workpool.PhaseFinished, Duration: 250 * time.Millisecond})
+
+	if got := testutil.ToFloat64(c.phaseTotal.WithLabelValues(c.service, "crawler", "panicked")); got != 1 {
+		t.Fatalf("panicked = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(c.duration); got != 1 {
+		t.Fatalf("duration series = %d, want 1", got)
+	}
+}
+
+func TestWorkpoolCollectors_SizeAndKeyDepth(t *testing.T) {
+	c := NewWorkpoolCollectors(prometheus.NewRegistry())
+	c.ObserveWorkpool(workpool.Event{Pool: "crawler", Phase: workpool.PhaseResized, Size: 16})
+	c.ObserveWorkpool(workpool.Event{Pool: "crawler", Phase: workpool.PhaseQueued, Size: 16, Key: "example.com", KeyQueueDepth: 3})
+
+	if got := testutil.ToFloat64(c.size.WithLabelValues(c.service, "crawler")); got != 16 {
+		t.Fatalf("pool_size = %v, want 16", got)
+	}
+	if got := testutil.ToFloat64(c.keyDepth.WithLabelValues(c.service, "crawler", "example.com")); got != 3 {
+		t.Fatalf("pool_key_queue_depth = %v, want 3", got)
+	}
+}
@@ -0,0 +1,127 @@
+package ratecoord
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baditaflorin/go-common/retry"
+	"golang.org/x/time/rate"
+)
+
+// hostBudget is the local limiter for one host plus what Learn has
+// taught it. Guarded by Client.fbMu.
+type hostBudget struct {
+	lim  *rate.Limiter
+	base rate.Limit // the configured rate, restored after restoreAt
+	// pausedUntil blocks the host outright (Retry-After, or an
+	// exhausted X-RateLimit-Remaining) until that instant.
+	pausedUntil time.Time
+	// restoreAt is when a learned, tighter rate lapses.
+	restoreAt time.Time
+}
+
+// Learn tightens host's local budget from an upstream response, so the
+// fallback bucket and the pre-limit stop spending quota the upstream
+// has said it does not have:
+//
+//   - Retry-After on a 429 or 503 pauses the host until the given time
+//     and halves its configured local rate for the following minute;
+//     further 429s in that minute do not halve it again.
+//   - X-RateLimit-Remaining with X-RateLimit-Reset (seconds until
+//     reset, or a Unix timestamp) caps the local rate at
+//     remaining/seconds-to-reset until the reset; remaining 0 pauses
+//     the host until then.
+//
+// Learning only ever tightens the configured budget, never loosens it.
+// Responses without these headers are ignored, and resp's body is not
+// touched. Transport (or safehttp.WithRateLearning) calls it for every
+// response; call it directly for responses fetched another way.
+func (c *Client) Learn(host string, resp *http.Response) {
+	if host == "" || resp == nil {
+		return
+	}
+	now := c.now()
+	h := resp.Header
+
+	c.fbMu.Lock()
+	defer c.fbMu.Unlock()
+	b := c.budgetLocked(host)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := retry.ParseRetryAfter(h.Get("Retry-After"), now); ok {
+			b.pausedUntil = later(b.pausedUntil, now.Add(d))
+			b.lim.SetLimitAt(now, min(b.lim.Limit(), b.base/2))
+			b.restoreAt = later(b.restoreAt, now.Add(d).Add(time.Minute))
+		}
+	}
+	remaining, okRem := headerInt(h, "X-RateLimit-Remaining")
+	reset, okReset := rateLimitReset(h, now)
+	if !okRem || !okReset || !reset.After(now) {
+		return
+	}
+	if remaining <= 0 {
+		b.pausedUntil = later(b.pausedUntil, reset)
+		return
+	}
+	if perSec := rate.Limit(float64(remaining) / reset.Sub(now).Seconds()); perSec < b.lim.Limit() {
+		b.lim.SetLimitAt(now, perSec)
+		b.restoreAt = later(b.restoreAt, reset)
+	}
+}
+
+// Transport returns a RoundTripper that passes every response from next
+// (http.DefaultTransport when nil) to Learn under the request's host, so
+// upstream rate-limit headers tighten the local budget without each
+// caller remembering to. It only learns: limiting still takes Wait or
+// a Reservation.
+//
+//	hc := &http.Client{Transport: rc.Transport(nil)}
+func (c *Client) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &learnTransport{c: c, next: next}
+}
+
+type learnTransport struct {
+	c    *Client
+	next http.RoundTripper
+}
+
+func (t *learnTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.c.Learn(req.URL.Hostname(), resp)
+	}
+	return resp, err
+}
+
+// headerInt parses the first of a rate-limit header's comma-separated
+// values (some APIs send one per policy window).
+func headerInt(h http.Header, name string) (int64, bool) {
+	v, _, _ := strings.Cut(h.Get(name), ",")
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	return n, err == nil
+}
+
+// rateLimitReset reads X-RateLimit-Reset, which upstreams send either
+// as seconds-until-reset or as a Unix timestamp; values past one year
+// of seconds are taken as timestamps.
+func rateLimitReset(h http.Header, now time.Time) (time.Time, bool) {
+	n, ok := headerInt(h, "X-RateLimit-Reset")
+	if !ok || n < 0 {
+		return time.Time{}, false
+	}
+	if n > 365*24*3600 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
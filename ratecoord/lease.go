@@ -0,0 +1,380 @@
+package ratecoord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
+)
+
+// errLeaseUnsupported means the coordinator has no /lease endpoint; the
+// Reservation then spends through Client.Wait instead.
+var errLeaseUnsupported = errors.New("ratecoord: coordinator does not support leases")
+
+// errReservationClosed is returned by Wait after Close.
+var errReservationClosed = errors.New("ratecoord: reservation closed")
+
+// ReserveOptions configures a Reservation. Zero values fall back to the
+// defaults noted on each field.
+type ReserveOptions struct {
+	// Tokens is the lease size asked for per renewal. The coordinator
+	// may grant fewer (never more than the host's burst). Default 20.
+	Tokens int
+	// TTL is how long a lease's tokens may be spent before the unused
+	// rest is returned. Default 1s.
+	TTL time.Duration
+	// MaxWait bounds each Wait, as Client.Wait's maxWait does.
+	// Default 5s.
+	MaxWait time.Duration
+}
+
+// Reservation spends one host's coordinator budget from a local lease:
+// one network round trip buys Tokens tokens, and Wait hands them out
+// without touching the network until the lease runs dry or expires.
+// Unused tokens go back to the coordinator when the lease expires and
+// on Close. Construct with Client.Reserve; safe for concurrent use.
+//
+// A failed renewal degrades to the client's local fallback bucket,
+// exactly like a failed Client.Wait, and renewal is not retried for
+// one TTL so an outage does not cost a round trip per call. A
+// coordinator without lease support is detected once and the
+// Reservation then spends through Client.Wait.
+type Reservation struct {
+	c    *Client
+	host string
+	opts ReserveOptions
+
+	mu          sync.Mutex
+	cur         *lease
+	renewing    chan struct{} // non-nil while one Wait leases; closed when it is done
+	retryAt     time.Time
+	unsupported bool
+	closed      bool
+}
+
+type lease struct {
+	id      string
+	size    int
+	used    int
+	expires time.Time
+	timer   *time.Timer
+}
+
+// Reserve returns a Reservation for host. Nothing is leased until the
+// first Wait.
+func (c *Client) Reserve(host string, opts ReserveOptions) *Reservation {
+	if opts.Tokens <= 0 {
+		opts.Tokens = 20
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Second
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 5 * time.Second
+	}
+	return &Reservation{c: c, host: host, opts: opts}
+}
+
+// Wait takes weight tokens for the Reservation's host, from the current
+// lease when it holds enough and from a fresh lease otherwise. Host
+// policies apply as in Client.Wait: the weight is multiplied and the
+// local pre-limit is charged first. A weight above Tokens bypasses the
+// lease and goes through Client.Wait.
+func (r *Reservation) Wait(ctx context.Context, weight int) (*WaitResult, error) {
+	c := r.c
+	if r.host == "" {
+		return nil, errors.New("ratecoord: host required")
+	}
+	weight = max(weight, 1)
+	r.mu.Lock()
+	closed, unsupported := r.closed, r.unsupported
+	r.mu.Unlock()
+	if closed {
+		return nil, errReservationClosed
+	}
+	pol, preLimit := c.policy(r.host)
+	if unsupported || weight*max(pol.Weight, 1) > r.opts.Tokens {
+		return c.Wait(ctx, r.host, weight, r.opts.MaxWait)
+	}
+	if preLimit {
+		weight *= pol.Weight
+	}
+	deadline := time.Now().Add(r.opts.MaxWait)
+
+	if preLimit {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		start := time.Now()
+		err := c.waitLocal(waitCtx, r.host, weight)
+		cancel()
+		if err != nil {
+			out := &WaitResult{
+				WaitedMs: time.Since(start).Milliseconds(),
+				Reason:   "local pre-limit: " + err.Error(),
+			}
+			c.emit(Event{Host: r.host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: false, FellBack: false, PreLimited: true, Reason: out.Reason})
+			return out, err
+		}
+	}
+
+	start := time.Now()
+	size, used, short, err := r.take(ctx, weight, deadline)
+	if err == nil {
+		out := &WaitResult{WaitedMs: time.Since(start).Milliseconds()}
+		c.emit(Event{Host: r.host, Weight: weight, Waited: time.Since(start), Allowed: true, PreLimited: preLimit, Leased: true, LeaseSize: size, LeaseUsed: used})
+		return out, nil
+	}
+	// Only what the lease did not cover is charged elsewhere; the
+	// pre-limit, if any, is already paid.
+	if errors.Is(err, errLeaseUnsupported) {
+		return c.waitCoordinator(ctx, r.host, short, deadline, preLimit)
+	}
+	if errors.Is(err, errReservationClosed) {
+		return nil, err
+	}
+
+	// Renewal failed: spend from the local bucket, as Client.Wait does
+	// when the coordinator is unreachable.
+	reason := "lease renewal failed: " + err.Error()
+	if !preLimit {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		if lerr := c.waitLocal(waitCtx, r.host, short); lerr != nil {
+			out := &WaitResult{WaitedMs: time.Since(start).Milliseconds(), FellBack: true, Reason: reason}
+			c.emit(Event{Host: r.host, Weight: weight, Waited: time.Since(start), Allowed: false, FellBack: true, Leased: true, Reason: reason})
+			return out, lerr
+		}
+	}
+	out := &WaitResult{WaitedMs: time.Since(start).Milliseconds(), FellBack: true, Reason: reason}
+	c.emit(Event{Host: r.host, Weight: weight, Waited: time.Since(start), Allowed: true, FellBack: true, PreLimited: preLimit, Leased: true, Reason: reason})
+	return out, nil
+}
+
+// take spends weight tokens from the current lease, renewing it when
+// it is expired or runs dry — a lease smaller than weight is spent
+// whole and the rest taken from the next one. It returns the last
+// serving lease's size and spent count, or on error how many of weight
+// are still owed: tokens already spent from a lease are not undone.
+//
+// One caller at a time renews, without holding r.mu: the others wait
+// for that renewal under their own ctx and deadline, so a slow
+// coordinator blocks neither them past their own limits nor the
+// lease's expiry timer and Close.
+func (r *Reservation) take(ctx context.Context, weight int, deadline time.Time) (size, used, short int, err error) {
+	r.mu.Lock()
+	for need := weight; ; {
+		if r.closed {
+			r.mu.Unlock()
+			return 0, 0, need, errReservationClosed
+		}
+		now := r.c.now()
+		if l := r.cur; l != nil && now.Before(l.expires) {
+			n := min(need, l.size-l.used)
+			l.used += n
+			if need -= n; need == 0 {
+				r.mu.Unlock()
+				return l.size, l.used, 0, nil
+			}
+		}
+		if r.unsupported {
+			r.mu.Unlock()
+			return 0, 0, need, errLeaseUnsupported
+		}
+		if now.Before(r.retryAt) {
+			r.mu.Unlock()
+			return 0, 0, need, errors.New("ratecoord: coordinator recently unreachable")
+		}
+		if renewing := r.renewing; renewing != nil {
+			r.mu.Unlock()
+			if err := waitRenewal(ctx, renewing, deadline); err != nil {
+				return 0, 0, need, err
+			}
+			r.mu.Lock()
+			continue
+		}
+
+		ret := r.endLocked()
+		renewing := make(chan struct{})
+		r.renewing = renewing
+		r.mu.Unlock()
+		if ret != nil {
+			go ret()
+		}
+		l, err := r.c.leaseRemote(ctx, r.host, r.opts.Tokens, r.opts.TTL, time.Until(deadline))
+		r.mu.Lock()
+		r.renewing = nil
+		close(renewing)
+		switch {
+		case errors.Is(err, errLeaseUnsupported):
+			r.unsupported = true
+		case err != nil && ctx.Err() == nil:
+			// This caller giving up says nothing about the
+			// coordinator; only a real failure backs everyone off.
+			r.retryAt = r.c.now().Add(r.opts.TTL)
+		case err == nil && r.closed:
+			r.mu.Unlock()
+			go r.c.returnLease(l.id, l.size)
+			return 0, 0, need, errReservationClosed
+		}
+		if err != nil {
+			r.mu.Unlock()
+			return 0, 0, need, err
+		}
+		l.timer = time.AfterFunc(time.Until(l.expires), func() { r.expire(l) })
+		r.cur = l
+	}
+}
+
+// waitRenewal blocks until another caller's renewal finishes, ctx ends
+// or deadline passes.
+func waitRenewal(ctx context.Context, renewing <-chan struct{}, deadline time.Time) error {
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+	select {
+	case <-renewing:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return errors.New("ratecoord: lease renewal still in flight at MaxWait")
+	}
+}
+
+// expire ends l at its TTL if it is still the current lease.
+func (r *Reservation) expire(l *lease) {
+	r.mu.Lock()
+	var ret func()
+	if r.cur == l {
+		ret = r.endLocked()
+	}
+	r.mu.Unlock()
+	if ret != nil {
+		ret()
+	}
+}
+
+// endLocked retires the current lease and emits its closing event. The
+// returned func, if any, hands the unused tokens back to the
+// coordinator; callers run it without holding r.mu.
+func (r *Reservation) endLocked() func() {
+	l := r.cur
+	if l == nil {
+		return nil
+	}
+	r.cur = nil
+	l.timer.Stop()
+	r.c.emit(Event{Host: r.host, Leased: true, LeaseClosed: true, LeaseSize: l.size, LeaseUsed: l.used})
+	unused := l.size - l.used
+	if unused <= 0 {
+		return nil
+	}
+	return func() { r.c.returnLease(l.id, unused) }
+}
+
+// Close returns the current lease's unused tokens. Wait fails after
+// Close. Idempotent.
+func (r *Reservation) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	ret := r.endLocked()
+	r.mu.Unlock()
+	if ret != nil {
+		ret()
+	}
+	return nil
+}
+
+type leaseRequest struct {
+	Host      string `json:"host"`
+	Tokens    int    `json:"tokens"`
+	TimeoutMs int    `json:"timeout_ms"`
+	TTLMs     int    `json:"ttl_ms"`
+}
+
+type leaseResponse struct {
+	LeaseID  string `json:"lease_id"`
+	Granted  int    `json:"granted"`
+	TTLMs    int64  `json:"ttl_ms"`
+	WaitedMs int64  `json:"waited_ms"`
+}
+
+type returnRequest struct {
+	LeaseID string `json:"lease_id"`
+	Unused  int    `json:"unused"`
+}
+
+func (c *Client) leaseRemote(ctx context.Context, host string, tokens int, ttl, maxWait time.Duration) (*lease, error) {
+	var out leaseResponse
+	status, err := c.post(ctx, "/lease", leaseRequest{
+		Host:      host,
+		Tokens:    tokens,
+		TimeoutMs: int(maxWait.Milliseconds()),
+		TTLMs:     int(ttl.Milliseconds()),
+	}, &out)
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return nil, errLeaseUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out.LeaseID == "" || out.Granted <= 0 {
+		return nil, errors.New("ratecoord: empty lease")
+	}
+	if out.TTLMs > 0 {
+		ttl = time.Duration(out.TTLMs) * time.Millisecond
+	}
+	return &lease{id: out.LeaseID, size: out.Granted, expires: c.now().Add(ttl)}, nil
+}
+
+// returnLease hands unused tokens of lease id back, best effort: an
+// unreturned token only counts as spent.
+func (c *Client) returnLease(id string, unused int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = c.returnRemote(ctx, id, unused)
+}
+
+func (c *Client) returnRemote(ctx context.Context, id string, unused int) error {
+	_, err := c.post(ctx, "/lease/return", returnRequest{LeaseID: id, Unused: unused}, nil)
+	return err
+}
+
+// post sends a signed JSON POST to the coordinator and decodes a 200
+// answer into out (nil to discard). The status is returned alongside
+// any error so callers can tell a missing endpoint from an outage.
+func (c *Client) post(ctx context.Context, path string, in, out any) (int, error) {
+	if c.APIKey == "" {
+		return 0, errors.New("ratecoord: API key not set")
+	}
+	body, _ := json.Marshal(in)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("ratecoord: build req: %w", err)
+	}
+	if err := (coordauth.Credentials{Key: c.APIKey, Secret: c.SigningSecret}).Sign(req, body); err != nil {
+		return 0, fmt.Errorf("ratecoord: sign req: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ratecoord: do: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<14))
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("ratecoord: status %d: %s", resp.StatusCode, string(raw))
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("ratecoord: decode: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
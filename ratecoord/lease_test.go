@@ -0,0 +1,206 @@
+package ratecoord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReservationFallsBackWhenRenewalFails(t *testing.T) {
+	var leases atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leases.Add(1)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	t.Setenv("RATECOORD_URL", srv.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+
+	var mu sync.Mutex
+	var events []Event
+	c := New().SetObserver(observerFunc(func(ev Event) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	r := c.Reserve("example.com", ReserveOptions{Tokens: 5, TTL: time.Minute})
+	defer r.Close()
+
+	for i := 0; i < 3; i++ {
+		res, err := r.Wait(context.Background(), 1)
+		if err != nil || !res.FellBack {
+			t.Fatalf("wait %d: res=%+v err=%v", i, res, err)
+		}
+	}
+	if n := leases.Load(); n != 1 {
+		t.Fatalf("a failed renewal should not be retried within the TTL; %d lease calls", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ev := range events {
+		if !ev.Leased || !ev.FellBack || ev.LeaseSize != 0 {
+			t.Fatalf("fallback event = %+v", ev)
+		}
+	}
+}
+
+func TestReservationChargesOnlyTheShortfallElsewhere(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		leases  int
+		renewal = http.StatusBadGateway
+		weights []int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/lease":
+			if leases++; leases == 1 {
+				json.NewEncoder(w).Encode(leaseResponse{LeaseID: "L1", Granted: 3, TTLMs: 60000})
+				return
+			}
+			w.WriteHeader(renewal)
+		case "/wait":
+			var req waitRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			weights = append(weights, req.Weight)
+			json.NewEncoder(w).Encode(map[string]int64{"waited_ms": 0})
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("RATECOORD_URL", srv.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+	t.Setenv("RATECOORD_DEFAULT_RPS", "0.001")
+	t.Setenv("RATECOORD_DEFAULT_BURST", "8")
+
+	// A failed renewal charges the local bucket only the token the
+	// drained lease could not cover.
+	c := New()
+	r := c.Reserve("example.com", ReserveOptions{Tokens: 3, TTL: time.Minute})
+	defer r.Close()
+	for _, w := range []int{2, 2} {
+		if _, err := r.Wait(context.Background(), w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.fbMu.Lock()
+	left := c.budgetLocked("example.com").lim.Tokens()
+	c.fbMu.Unlock()
+	if left < 6.5 || left > 7.5 {
+		t.Fatalf("local bucket has %.2f tokens, want 7 (8 less the 1-token shortfall)", left)
+	}
+
+	// Without lease support the coordinator is asked for the shortfall.
+	mu.Lock()
+	leases, renewal = 0, http.StatusNotFound
+	mu.Unlock()
+	r2 := New().Reserve("example.org", ReserveOptions{Tokens: 3, TTL: time.Minute})
+	defer r2.Close()
+	for _, w := range []int{2, 2} {
+		if _, err := r2.Wait(context.Background(), w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(weights) != 1 || weights[0] != 1 {
+		t.Fatalf("/wait weights = %v, want [1]", weights)
+	}
+}
+
+func TestReservationUsesWaitWithoutLeaseSupport(t *testing.T) {
+	var waits, leases atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wait":
+			waits.Add(1)
+			json.NewEncoder(w).Encode(map[string]int64{"waited_ms": 0})
+		default:
+			leases.Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("RATECOORD_URL", srv.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+
+	r := New().Reserve("example.com", ReserveOptions{})
+	defer r.Close()
+	for i := 0; i < 3; i++ {
+		res, err := r.Wait(context.Background(), 1)
+		if err != nil || res.FellBack {
+			t.Fatalf("wait %d: res=%+v err=%v", i, res, err)
+		}
+	}
+	if leases.Load() != 1 || waits.Load() != 3 {
+		t.Fatalf("lease calls = %d, wait calls = %d; want 1 and 3", leases.Load(), waits.Load())
+	}
+}
+
+func TestReservationSlowRenewalBlocksOnlyTheRenewer(t *testing.T) {
+	release := make(chan struct{})
+	leasing := make(chan struct{}, 1)
+	returned := make(chan returnRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lease":
+			leasing <- struct{}{}
+			<-release
+			json.NewEncoder(w).Encode(leaseResponse{LeaseID: "L1", Granted: 5, TTLMs: 60000})
+		case "/lease/return":
+			var rr returnRequest
+			json.NewDecoder(r.Body).Decode(&rr)
+			returned <- rr
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	t.Setenv("RATECOORD_URL", srv.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+
+	r := New().Reserve("example.com", ReserveOptions{Tokens: 5, TTL: time.Minute, MaxWait: 10 * time.Second})
+	renewer := make(chan error, 1)
+	go func() {
+		_, err := r.Wait(context.Background(), 1)
+		renewer <- err
+	}()
+	<-leasing
+
+	// A second caller waits on the renewal under its own context.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r.Wait(ctx, 1)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("a waiter with a 50ms context was held %v by another caller's renewal", d)
+	}
+
+	// Close does not wait for the renewal either.
+	closed := make(chan struct{})
+	go func() { r.Close(); close(closed) }()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close blocked on an in-flight renewal")
+	}
+
+	// The lease granted after Close goes straight back.
+	release <- struct{}{}
+	if err := <-renewer; err != errReservationClosed {
+		t.Fatalf("renewer err = %v, want errReservationClosed", err)
+	}
+	select {
+	case rr := <-returned:
+		if rr.LeaseID != "L1" || rr.Unused != 5 {
+			t.Fatalf("returned %+v, want all of L1", rr)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the late lease was not returned")
+	}
+}
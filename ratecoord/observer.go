@@ -2,9 +2,9 @@ package ratecoord
 
 import "time"
 
-// Observer receives one event per Client.Wait call. Implementations
-// MUST NOT block — callbacks run inline on the request hot path of any
-// service fanning out HTTP traffic. The canonical implementation lives
+// Observer receives one event per Client.Wait or Reservation.Wait
+// call. Implementations MUST NOT block — callbacks run inline on the
+// request hot path of any service fanning out HTTP traffic. The canonical implementation lives
 // in go-common/promx.
 //
 // ratecoord deliberately defines the contract here rather than
@@ -18,13 +18,31 @@ type Observer interface {
 // Allowed is true when a token was acquired (whether remotely or via
 // the in-process fallback). FellBack records whether the local bucket
 // served the answer because the coordinator was unreachable.
+// PreLimited records that the host's policy bucket was charged before
+// the coordinator call; with Allowed false and FellBack false, that
+// local pre-limit is what refused the call. Weight includes the
+// policy's multiplier.
+//
+// Leased marks events from a Reservation. LeaseSize is the token count
+// the serving lease was granted and LeaseUsed how many of them are
+// spent, this call included; both are zero when the call fell back.
+// LeaseClosed marks the one extra event each lease emits when it ends
+// (expired, renewed or Close): Weight is zero and LeaseUsed/LeaseSize
+// is the lease's final utilization. Observers counting calls should
+// skip it.
 type Event struct {
-	Host     string
-	Weight   int
-	Waited   time.Duration
-	Allowed  bool
-	FellBack bool
-	Reason   string
+	Host       string
+	Weight     int
+	Waited     time.Duration
+	Allowed    bool
+	FellBack   bool
+	PreLimited bool
+	Reason     string
+
+	Leased      bool
+	LeaseSize   int
+	LeaseUsed   int
+	LeaseClosed bool
 }
 
 // SetObserver attaches an Observer to the client. Idempotent.
@@ -0,0 +1,124 @@
+package ratecoord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Policy is the local budget for one host pattern.
+//
+// Host is an exact hostname ("api.github.com"), a suffix wildcard
+// ("*.github.com", matching any subdomain but not github.com itself),
+// or "*" for every host without a more specific entry. An exact match
+// beats a wildcard; among wildcards the longest suffix wins.
+type Policy struct {
+	Host  string  `json:"host"`
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+	// Weight multiplies the weight passed to Wait for this host, for
+	// upstreams where one request costs several units of quota
+	// (search APIs, GraphQL). Applies to the coordinator call too.
+	// Default 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+// Policies is a parsed per-host policy table. The zero value (and nil)
+// matches nothing.
+type Policies struct {
+	exact    map[string]Policy
+	suffixes []Policy // longest suffix first
+	wildcard *Policy
+}
+
+// ParsePolicies parses a JSON array of Policy objects:
+//
+//	[
+//	  {"host": "api.github.com", "rps": 1, "burst": 5},
+//	  {"host": "*.googleapis.com", "rps": 10, "burst": 20, "weight": 2},
+//	  {"host": "*", "rps": 4, "burst": 8}
+//	]
+//
+// RPS must be positive; Burst defaults to ceil(RPS).
+func ParsePolicies(data []byte) (*Policies, error) {
+	var list []Policy
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("ratecoord: parse policies: %w", err)
+	}
+	ps := &Policies{exact: make(map[string]Policy, len(list))}
+	for i, p := range list {
+		p.Host = strings.ToLower(strings.TrimSpace(p.Host))
+		if p.Host == "" {
+			return nil, fmt.Errorf("ratecoord: policy %d: host required", i)
+		}
+		if p.RPS <= 0 {
+			return nil, fmt.Errorf("ratecoord: policy %q: rps must be > 0", p.Host)
+		}
+		if p.Burst <= 0 {
+			p.Burst = max(1, int(p.RPS+0.999))
+		}
+		if p.Weight <= 0 {
+			p.Weight = 1
+		}
+		switch {
+		case p.Host == "*":
+			ps.wildcard = &p
+		case strings.HasPrefix(p.Host, "*."):
+			ps.suffixes = append(ps.suffixes, p)
+		default:
+			ps.exact[p.Host] = p
+		}
+	}
+	sort.SliceStable(ps.suffixes, func(i, j int) bool {
+		return len(ps.suffixes[i].Host) > len(ps.suffixes[j].Host)
+	})
+	return ps, nil
+}
+
+// LoadPolicies reads a policy file in the ParsePolicies format.
+func LoadPolicies(path string) (*Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ratecoord: read policies: %w", err)
+	}
+	return ParsePolicies(data)
+}
+
+// policiesFromEnv loads RATECOORD_POLICY_FILE, else the inline JSON in
+// RATECOORD_POLICIES. Neither set returns nil, nil.
+func policiesFromEnv() (*Policies, error) {
+	if path := os.Getenv("RATECOORD_POLICY_FILE"); path != "" {
+		return LoadPolicies(path)
+	}
+	if raw := os.Getenv("RATECOORD_POLICIES"); raw != "" {
+		return ParsePolicies([]byte(raw))
+	}
+	return nil, nil
+}
+
+// Match returns the policy for host and whether one applied. host may
+// carry a port, which is ignored.
+func (ps *Policies) Match(host string) (Policy, bool) {
+	if ps == nil {
+		return Policy{}, false
+	}
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if p, ok := ps.exact[host]; ok {
+		return p, true
+	}
+	for _, p := range ps.suffixes {
+		if strings.HasSuffix(host, p.Host[1:]) {
+			return p, true
+		}
+	}
+	if ps.wildcard != nil {
+		return *ps.wildcard, true
+	}
+	return Policy{}, false
+}
@@ -0,0 +1,203 @@
+package ratecoord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoliciesMatch(t *testing.T) {
+	ps, err := ParsePolicies([]byte(`[
+		{"host": "api.github.com", "rps": 1},
+		{"host": "*.github.com", "rps": 2, "burst": 4},
+		{"host": "*.raw.github.com", "rps": 3},
+		{"host": "*", "rps": 9, "weight": 2}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]float64{
+		"api.github.com":     1, // exact beats wildcard
+		"API.GitHub.com:443": 1, // case and port ignored
+		"gist.github.com":    2,
+		"x.raw.github.com":   3, // longest suffix wins
+		"github.com":         9, // *.github.com does not match the apex
+		"unrelated.example":  9,
+	}
+	for host, want := range cases {
+		p, ok := ps.Match(host)
+		if !ok || p.RPS != want {
+			t.Errorf("Match(%q) = %+v, %v; want rps %v", host, p, ok, want)
+		}
+	}
+	if p, _ := ps.Match("api.github.com"); p.Burst != 1 || p.Weight != 1 {
+		t.Errorf("defaults not applied: %+v", p)
+	}
+}
+
+func TestParsePoliciesRejectsBadEntries(t *testing.T) {
+	for _, raw := range []string{`{}`, `[{"rps": 1}]`, `[{"host": "a.example", "rps": 0}]`} {
+		if _, err := ParsePolicies([]byte(raw)); err == nil {
+			t.Errorf("ParsePolicies(%s) should fail", raw)
+		}
+	}
+}
+
+func TestPolicyFileFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(path, []byte(`[{"host": "slow.example", "rps": 2, "burst": 1}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RATECOORD_URL", "http://127.0.0.1:1")
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+	t.Setenv("RATECOORD_POLICY_FILE", path)
+	t.Setenv("RATECOORD_DEFAULT_RPS", "1000")
+
+	c := New()
+	if _, err := c.Wait(context.Background(), "slow.example", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := c.Wait(context.Background(), "slow.example", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Fatalf("fallback should use the 2 rps policy, not the 1000 rps default; waited %v", elapsed)
+	}
+}
+
+func TestPreLimitBeforeCoordinator(t *testing.T) {
+	var calls atomic.Int32
+	var weights []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req waitRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		weights = append(weights, req.Weight)
+		_ = json.NewEncoder(w).Encode(map[string]int64{"waited_ms": 0})
+	}))
+	defer srv.Close()
+	t.Setenv("RATECOORD_URL", srv.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+
+	ps, _ := ParsePolicies([]byte(`[{"host": "quota.example", "rps": 0.1, "burst": 2, "weight": 2}]`))
+	var events []Event
+	c := New().SetPolicies(ps).SetObserver(observerFunc(func(ev Event) { events = append(events, ev) }))
+
+	if _, err := c.Wait(context.Background(), "quota.example", 1, 50*time.Millisecond); err != nil {
+		t.Fatalf("first call fits the burst: %v", err)
+	}
+	if _, err := c.Wait(context.Background(), "quota.example", 1, 50*time.Millisecond); err == nil {
+		t.Fatal("second call must be refused by the local pre-limit")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("coordinator calls = %d; a pre-limited refusal must not reach it", got)
+	}
+	if weights[0] != 2 {
+		t.Fatalf("coordinator weight = %d, want policy weight 2", weights[0])
+	}
+	last := events[len(events)-1]
+	if last.Allowed || last.FellBack || !last.PreLimited {
+		t.Fatalf("refusal event = %+v, want PreLimited denial", last)
+	}
+}
+
+func TestLearnFromUpstreamHeaders(t *testing.T) {
+	t.Setenv("RATECOORD_DEFAULT_RPS", "100")
+	c := New()
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "10")
+	resp.Header.Set("X-RateLimit-Reset", "100")
+	c.Learn("api.example", resp)
+	if got := c.fb["api.example"].lim.Limit(); got != 0.1 {
+		t.Fatalf("learned rate = %v, want remaining/reset = 0.1", got)
+	}
+
+	resp.Header.Set("X-RateLimit-Remaining", "0")
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+	c.Learn("api.example", resp)
+	if err := c.waitLocal(ctxWithTimeout(t, time.Second), "api.example", 1); err == nil {
+		t.Fatal("an exhausted quota must pause the host past the caller's deadline")
+	}
+
+	// Once the reset passes the configured rate comes back.
+	now = now.Add(2 * time.Hour)
+	c.fbMu.Lock()
+	b := c.budgetLocked("api.example")
+	c.fbMu.Unlock()
+	if b.lim.Limit() != 100 {
+		t.Fatalf("rate after reset = %v, want 100 restored", b.lim.Limit())
+	}
+}
+
+func TestLearnRetryAfterHalvesAndPauses(t *testing.T) {
+	t.Setenv("RATECOORD_DEFAULT_RPS", "8")
+	c := New()
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}
+	c.Learn("busy.example", resp)
+	b := c.fb["busy.example"]
+	if b.lim.Limit() != 4 {
+		t.Fatalf("rate = %v, want halved to 4", b.lim.Limit())
+	}
+	if until := time.Until(b.pausedUntil); until < 29*time.Second {
+		t.Fatalf("paused for %v, want ~30s", until)
+	}
+
+	// A burst of 429s halves the configured rate once, not per response.
+	for range 5 {
+		c.Learn("busy.example", resp)
+	}
+	if b.lim.Limit() != 4 {
+		t.Fatalf("rate after a 429 burst = %v, want 4", b.lim.Limit())
+	}
+
+	// An absurd Retry-After pauses for good rather than wrapping into
+	// the past.
+	c.Learn("huge.example", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"10000000000"}}})
+	if h := c.fb["huge.example"]; !h.pausedUntil.After(time.Now().Add(time.Hour)) || !h.restoreAt.After(h.pausedUntil) {
+		t.Fatalf("oversized Retry-After: paused until %v, restore at %v", h.pausedUntil, h.restoreAt)
+	}
+
+	// Retry-After on a 200 is not an instruction to back off.
+	c.Learn("fine.example", &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Retry-After": {"30"}}})
+	if b := c.fb["fine.example"]; b != nil && !b.pausedUntil.IsZero() {
+		t.Fatal("Retry-After on a 2xx must be ignored")
+	}
+}
+
+func TestTransportLearnsFromEveryResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer upstream.Close()
+	c := New()
+	resp, err := (&http.Client{Transport: c.Transport(nil)}).Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := c.waitLocal(ctxWithTimeout(t, time.Second), "127.0.0.1", 1); err == nil {
+		t.Fatal("a 429 seen by Transport should pause the host")
+	}
+}
+
+func ctxWithTimeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+type observerFunc func(Event)
+
+func (f observerFunc) ObserveRate(ev Event) { f(ev) }
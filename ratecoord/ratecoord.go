@@ -17,8 +17,24 @@
 //	RATECOORD_URL          default https://rate-coordinator.0exec.com
 //	RATECOORD_API_KEY      or FLEET_API_KEY (required for real coordinator;
 //	                        unused on the in-process fallback path)
+//	RATECOORD_SIGNING_SECRET optional; signs /wait (see coordauth)
 //	RATECOORD_DEFAULT_RPS  per-host fallback bucket rate (default 4)
 //	RATECOORD_DEFAULT_BURST per-host fallback bucket burst (default 8)
+//	RATECOORD_POLICY_FILE  per-host policy file (see ParsePolicies)
+//	RATECOORD_POLICIES     the same policy JSON inline, if no file is set
+//
+// A host matched by a policy gets that policy's bucket instead of the
+// default one, and the bucket is also applied as a local pre-limit
+// before the coordinator is consulted, so a host with a known hard
+// quota is never asked for faster than it allows even while the
+// coordinator is healthy. Client.Learn tightens a host's bucket from
+// the upstream's own X-RateLimit-* / Retry-After answers; Client.Transport
+// and safehttp.WithRateLearning feed it every response automatically.
+//
+// A caller sending many requests per second to one host should use
+// Client.Reserve instead of Wait: a Reservation leases a batch of
+// tokens in one round trip and spends them locally, returning what it
+// did not use when the lease expires.
 //
 // The in-process fallback intentionally uses a different (and stricter)
 // default than the coordinator's network policy: when coordination
@@ -27,12 +43,11 @@
 package ratecoord
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -40,6 +55,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/baditaflorin/go-common/coordauth"
 	"golang.org/x/time/rate"
 )
 
@@ -53,16 +69,22 @@ const (
 type Client struct {
 	BaseURL string
 	APIKey  string
-	HTTP    *http.Client
+	// SigningSecret, when set, HMAC-signs every /wait call (see
+	// coordauth). The key itself always travels in X-API-Key.
+	SigningSecret string
+	HTTP          *http.Client
 
-	// Fallback state. Lazily initialised per-host on first failure.
-	fbMu sync.Mutex
-	fb   map[string]*rate.Limiter
+	// Local budgets. Lazily initialised per host on first use — the
+	// first fallback, or the first pre-limited Wait.
+	fbMu     sync.Mutex
+	fb       map[string]*hostBudget
+	policies *Policies
 
 	fbRPS   float64
 	fbBurst int
 
 	observer Observer
+	now      func() time.Time
 }
 
 // emit fires the observer if one is attached. Internal helper so the
@@ -75,13 +97,11 @@ func (c *Client) emit(ev Event) {
 
 // New constructs a client from environment. Safe to call at package init.
 func New() *Client {
-	base := os.Getenv("RATECOORD_URL")
-	if base == "" {
-		base = DefaultURL
-	}
-	apiKey := os.Getenv("RATECOORD_API_KEY")
-	if apiKey == "" {
-		apiKey = os.Getenv("FLEET_API_KEY")
+	ep := coordauth.FromEnv("RATECOORD", DefaultURL)
+	if ep.Key == "" {
+		// The rate coordinator is a fleet service and has always taken
+		// the fleet key; coordauth.FromEnv never falls back to it itself.
+		ep.Key = os.Getenv("FLEET_API_KEY")
 	}
 	rps, _ := strconv.ParseFloat(os.Getenv("RATECOORD_DEFAULT_RPS"), 64)
 	if rps <= 0 {
@@ -91,16 +111,36 @@ func New() *Client {
 	if burst <= 0 {
 		burst = 8
 	}
+	policies, err := policiesFromEnv()
+	if err != nil {
+		// Fail open like the rest of the client: a bad policy file
+		// must not stop the service from starting.
+		log.Printf("%v; using default per-host budgets", err)
+	}
 	return &Client{
-		BaseURL: base,
-		APIKey:  apiKey,
-		HTTP:    &http.Client{Timeout: 15 * time.Second},
-		fb:      map[string]*rate.Limiter{},
-		fbRPS:   rps,
-		fbBurst: burst,
+		BaseURL:       ep.URL,
+		APIKey:        ep.Key,
+		SigningSecret: ep.Secret,
+		HTTP:          &http.Client{Timeout: 15 * time.Second},
+		fb:            map[string]*hostBudget{},
+		policies:      policies,
+		fbRPS:         rps,
+		fbBurst:       burst,
+		now:           time.Now,
 	}
 }
 
+// SetPolicies replaces the per-host policy table (nil clears it) and
+// resets every host's local budget, including anything Learn taught
+// it. Idempotent.
+func (c *Client) SetPolicies(ps *Policies) *Client {
+	c.fbMu.Lock()
+	c.policies = ps
+	c.fb = map[string]*hostBudget{}
+	c.fbMu.Unlock()
+	return c
+}
+
 // WaitResult conveys what happened during Wait.
 type WaitResult struct {
 	WaitedMs int64 `json:"waited_ms"`
@@ -127,47 +167,119 @@ func (c *Client) Wait(ctx context.Context, host string, weight int, maxWait time
 		maxWait = 5 * time.Second
 	}
 
-	// Try the coordinator first.
-	res, err := c.waitRemote(ctx, host, weight, maxWait)
+	deadline := time.Now().Add(maxWait)
+	pol, preLimit := c.policy(host)
+	if preLimit {
+		weight *= pol.Weight
+	}
+
+	// A host with a policy pays its local bucket first; if that alone
+	// takes longer than maxWait the coordinator is never asked.
+	if preLimit {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		start := time.Now()
+		err := c.waitLocal(waitCtx, host, weight)
+		cancel()
+		if err != nil {
+			out := &WaitResult{
+				WaitedMs: time.Since(start).Milliseconds(),
+				Reason:   "local pre-limit: " + err.Error(),
+			}
+			c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: false, FellBack: false, PreLimited: true, Reason: out.Reason})
+			return out, err
+		}
+	}
+
+	return c.waitCoordinator(ctx, host, weight, deadline, preLimit)
+}
+
+// waitCoordinator charges weight, policy weight already applied, to the
+// coordinator and falls back to the local bucket when it is unreachable.
+// preLimit means the local bucket was charged already.
+func (c *Client) waitCoordinator(ctx context.Context, host string, weight int, deadline time.Time, preLimit bool) (*WaitResult, error) {
+	// Try the coordinator.
+	res, err := c.waitRemote(ctx, host, weight, time.Until(deadline))
 	if err == nil {
-		c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(res.WaitedMs) * time.Millisecond, Allowed: true, FellBack: false, Reason: res.Reason})
+		c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(res.WaitedMs) * time.Millisecond, Allowed: true, FellBack: false, PreLimited: preLimit, Reason: res.Reason})
 		return res, nil
 	}
 
-	// Fall back to a per-process token bucket. We honour the same
-	// maxWait budget the caller asked for so callers stay bounded.
-	waitCtx, cancel := context.WithTimeout(ctx, maxWait)
-	defer cancel()
-
+	// Fall back to the per-process bucket. We honour the same maxWait
+	// budget the caller asked for so callers stay bounded. A
+	// pre-limited call already paid it.
 	start := time.Now()
-	lim := c.fallbackLimiter(host)
-	if err := lim.WaitN(waitCtx, weight); err != nil {
-		out := &WaitResult{
-			WaitedMs: time.Since(start).Milliseconds(),
-			FellBack: true,
-			Reason:   "coordinator unreachable: " + err.Error(),
+	if !preLimit {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		if err := c.waitLocal(waitCtx, host, weight); err != nil {
+			out := &WaitResult{
+				WaitedMs: time.Since(start).Milliseconds(),
+				FellBack: true,
+				Reason:   "coordinator unreachable: " + err.Error(),
+			}
+			c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: false, FellBack: true, Reason: out.Reason})
+			return out, err
 		}
-		c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: false, FellBack: true, Reason: out.Reason})
-		return out, err
 	}
 	out := &WaitResult{
 		WaitedMs: time.Since(start).Milliseconds(),
 		FellBack: true,
 		Reason:   "coordinator unreachable: " + err.Error(),
 	}
-	c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: true, FellBack: true, Reason: out.Reason})
+	c.emit(Event{Host: host, Weight: weight, Waited: time.Duration(out.WaitedMs) * time.Millisecond, Allowed: true, FellBack: true, PreLimited: preLimit, Reason: out.Reason})
 	return out, nil
 }
 
-func (c *Client) fallbackLimiter(host string) *rate.Limiter {
+// policy returns host's policy and whether one matched.
+func (c *Client) policy(host string) (Policy, bool) {
 	c.fbMu.Lock()
 	defer c.fbMu.Unlock()
-	if l, ok := c.fb[host]; ok {
-		return l
+	return c.policies.Match(host)
+}
+
+// budgetLocked returns host's local budget, creating it from the
+// matching policy (or the RATECOORD_DEFAULT_* bucket) on first use and
+// restoring its configured rate once a learned tightening lapses.
+func (c *Client) budgetLocked(host string) *hostBudget {
+	now := c.now()
+	b, ok := c.fb[host]
+	if !ok {
+		rps, burst := c.fbRPS, c.fbBurst
+		if p, ok := c.policies.Match(host); ok {
+			rps, burst = p.RPS, p.Burst
+		}
+		b = &hostBudget{lim: rate.NewLimiter(rate.Limit(rps), burst), base: rate.Limit(rps)}
+		c.fb[host] = b
+	}
+	if !b.restoreAt.IsZero() && !now.Before(b.restoreAt) {
+		b.lim.SetLimitAt(now, b.base)
+		b.restoreAt = time.Time{}
+	}
+	return b
+}
+
+// waitLocal takes n tokens from host's local budget, first sitting out
+// any pause Learn imposed. Fails at once if the pause outlasts ctx's
+// deadline. n above the bucket's burst is clamped to it: an oversize
+// call drains the bucket rather than failing forever.
+func (c *Client) waitLocal(ctx context.Context, host string, n int) error {
+	c.fbMu.Lock()
+	b := c.budgetLocked(host)
+	lim, pause := b.lim, b.pausedUntil.Sub(c.now())
+	c.fbMu.Unlock()
+	if pause > 0 {
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) < pause {
+			return fmt.Errorf("ratecoord: %s paused by upstream for %v", host, pause.Round(time.Millisecond))
+		}
+		t := time.NewTimer(pause)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
 	}
-	l := rate.NewLimiter(rate.Limit(c.fbRPS), c.fbBurst)
-	c.fb[host] = l
-	return l
+	return lim.WaitN(ctx, min(n, lim.Burst()))
 }
 
 type waitRequest struct {
@@ -182,32 +294,13 @@ type waitResponse struct {
 }
 
 func (c *Client) waitRemote(ctx context.Context, host string, weight int, maxWait time.Duration) (*WaitResult, error) {
-	if c.APIKey == "" {
-		return nil, errors.New("ratecoord: API key not set")
-	}
-	body, _ := json.Marshal(waitRequest{
+	var out waitResponse
+	if _, err := c.post(ctx, "/wait", waitRequest{
 		Host:      host,
 		Weight:    weight,
 		TimeoutMs: int(maxWait.Milliseconds()),
-	})
-	reqURL := strings.TrimRight(c.BaseURL, "/") + "/wait?api_key=" + c.APIKey
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("ratecoord: build req: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("ratecoord: do: %w", err)
-	}
-	defer resp.Body.Close()
-	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<14))
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ratecoord: status %d: %s", resp.StatusCode, string(raw))
-	}
-	var out waitResponse
-	if err := json.Unmarshal(raw, &out); err != nil {
-		return nil, fmt.Errorf("ratecoord: decode: %w", err)
+	}, &out); err != nil {
+		return nil, err
 	}
 	return &WaitResult{WaitedMs: out.WaitedMs}, nil
 }
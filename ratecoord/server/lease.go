@@ -0,0 +1,159 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// leaseGrace is how long past its TTL a lease still accepts a return:
+// the client returns unused tokens when it notices expiry, which is
+// necessarily a little after the fact.
+const leaseGrace = 5 * time.Second
+
+type leaseState struct {
+	host    string
+	granted int
+	expires time.Time
+}
+
+type leaseRequest struct {
+	Host      string `json:"host"`
+	Tokens    int    `json:"tokens"`
+	TimeoutMs int    `json:"timeout_ms"`
+	TTLMs     int    `json:"ttl_ms"`
+}
+
+type leaseResponse struct {
+	LeaseID  string `json:"lease_id,omitempty"`
+	Granted  int    `json:"granted,omitempty"`
+	TTLMs    int64  `json:"ttl_ms,omitempty"`
+	WaitedMs int64  `json:"waited_ms"`
+	Error    string `json:"error,omitempty"`
+}
+
+type returnRequest struct {
+	LeaseID string `json:"lease_id"`
+	Unused  int    `json:"unused"`
+}
+
+type returnResponse struct {
+	Returned int    `json:"returned"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (s *Server) handleLease(w http.ResponseWriter, r *http.Request) {
+	var req leaseRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.Host == "" {
+		writeJSON(w, http.StatusBadRequest, leaseResponse{Error: "body must be {host, tokens, timeout_ms, ttl_ms}"})
+		return
+	}
+	timeout := min(time.Duration(req.TimeoutMs)*time.Millisecond, s.opts.MaxWait)
+	ttl := time.Duration(req.TTLMs) * time.Millisecond
+	if ttl <= 0 || ttl > s.opts.MaxLeaseTTL {
+		ttl = s.opts.MaxLeaseTTL
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	s.sweepLocked(now)
+	h := s.hostLocked(req.Host)
+	want := min(max(req.Tokens, 1), h.lim.Burst())
+	granted := min(h.clampCredit(now), want)
+	h.credit -= granted
+	if n := min(want-granted, int(h.lim.TokensAt(now))); n > 0 {
+		h.lim.AllowN(now, n)
+		granted += n
+	}
+	var delay time.Duration
+	if granted == 0 {
+		res := h.lim.ReserveN(now, 1)
+		if delay = res.DelayFrom(now); delay > timeout {
+			res.CancelAt(now)
+			h.stats.Refused++
+			s.mu.Unlock()
+			writeJSON(w, http.StatusTooManyRequests, leaseResponse{Error: "no token within timeout_ms"})
+			return
+		}
+		granted = 1
+	}
+	id := newLeaseID()
+	s.leases[id] = &leaseState{host: req.Host, granted: granted, expires: now.Add(delay + ttl)}
+	h.stats.Allowed++
+	h.stats.Leases++
+	h.stats.Tokens += int64(granted)
+	s.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return // the lease expires unreturned; its token counts as spent
+		}
+	}
+	writeJSON(w, http.StatusOK, leaseResponse{LeaseID: id, Granted: granted, TTLMs: ttl.Milliseconds(), WaitedMs: delay.Milliseconds()})
+}
+
+func (s *Server) handleReturn(w http.ResponseWriter, r *http.Request) {
+	var req returnRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	l := s.leases[req.LeaseID]
+	if l == nil || now.After(l.expires.Add(leaseGrace)) {
+		writeJSON(w, http.StatusNotFound, returnResponse{Error: "unknown lease"})
+		return
+	}
+	delete(s.leases, req.LeaseID)
+	n := min(max(req.Unused, 0), l.granted)
+	h := s.hostLocked(l.host)
+	h.credit += n
+	h.clampCredit(now)
+	h.stats.Returned += int64(n)
+	writeJSON(w, http.StatusOK, returnResponse{Returned: n})
+}
+
+// sweepLocked forgets leases past their return grace.
+func (s *Server) sweepLocked(now time.Time) {
+	for id, l := range s.leases {
+		if now.After(l.expires.Add(leaseGrace)) {
+			delete(s.leases, id)
+		}
+	}
+}
+
+// clampCredit trims the host's returned credit to the tokens its bucket
+// is missing and returns what is left: a credit only stands in for a
+// token the bucket has not refilled yet, so the two together never
+// exceed the burst, and credit the bucket has since refilled past is
+// forfeited rather than banked.
+func (h *hostState) clampCredit(now time.Time) int {
+	room := h.lim.Burst() - int(h.lim.TokensAt(now))
+	h.credit = max(min(h.credit, room), 0)
+	return h.credit
+}
+
+// spendCredit takes n tokens from the host's credit if it holds that
+// many.
+func (h *hostState) spendCredit(n int, now time.Time) bool {
+	if h.clampCredit(now) < n {
+		return false
+	}
+	h.credit -= n
+	return true
+}
+
+func newLeaseID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
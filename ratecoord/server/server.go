@@ -0,0 +1,251 @@
+// Package server is an in-process implementation of the rate
+// coordinator that ratecoord.Client talks to: the same /wait and
+// /health endpoints, backed by one token bucket per host. Mount it
+// inside a service to coordinate a small deployment without running
+// go-pentest-rate-coordinator, or start it on an httptest.Server to
+// run real end-to-end tests against ratecoord instead of mocking its
+// HTTP calls:
+//
+//	coord := server.New(server.Options{APIKey: "test-key"})
+//	ts := httptest.NewServer(coord)
+//	defer ts.Close()
+//	t.Setenv("RATECOORD_URL", ts.URL)
+//	t.Setenv("RATECOORD_API_KEY", "test-key")
+//	c := ratecoord.New()
+//	...
+//	if got := coord.Stats("example.com").Allowed; got != 3 { ... }
+//
+// Wire protocol (as spoken by ratecoord.Client):
+//
+//	POST /wait   {"host": "h", "weight": 1, "timeout_ms": 5000}
+//	             200 {"waited_ms": 12}                  token granted
+//	             429 {"waited_ms": 0, "error": "..."}   not within timeout_ms
+//	             400 / 401 {"error": "..."}             bad request / bad key or signature
+//	POST /lease  {"host": "h", "tokens": 20, "timeout_ms": 5000, "ttl_ms": 1000}
+//	             200 {"lease_id": "...", "granted": 7, "ttl_ms": 1000, "waited_ms": 0}
+//	             429 {"error": "..."}                   not one token within timeout_ms
+//	POST /lease/return {"lease_id": "...", "unused": 3}
+//	             200 {"returned": 3}
+//	             404 {"error": "..."}                   unknown or long-expired lease
+//	GET  /health 200 {"status": "ok"}
+//
+// A refused /wait or /lease answers at once rather than holding the
+// connection for timeout_ms: the bucket already knows the token will
+// not be free in time.
+//
+// A lease grants every token the host's bucket holds right now, up to
+// the tokens asked for; an empty bucket grants one token once it is
+// free. Returned tokens are credited back to the host, up to the
+// bucket's burst, and are spent before the bucket by later /wait and
+// /lease calls. A lease nobody returns simply counts as spent.
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go-common/coordauth"
+	"github.com/baditaflorin/go-common/ratecoord"
+	"golang.org/x/time/rate"
+)
+
+// Options configures a Server. Zero values fall back to the defaults
+// noted on each field.
+type Options struct {
+	// APIKey, when set, is required on /wait as X-API-Key. Empty
+	// disables the key check.
+	APIKey string
+	// SigningSecret, when set, requires every /wait to carry a valid
+	// coordauth signature, rejecting stale and replayed requests.
+	SigningSecret string
+	// DefaultRPS and DefaultBurst size the bucket of a host without a
+	// policy. Defaults 10 and 20.
+	DefaultRPS   float64
+	DefaultBurst int
+	// Policies gives matched hosts their own bucket, with the same
+	// matching rules as the client (see ratecoord.ParsePolicies).
+	// Policy weights are applied by the client, not here.
+	Policies *ratecoord.Policies
+	// MaxWait caps timeout_ms. Default 30s.
+	MaxWait time.Duration
+	// MaxLeaseTTL caps ttl_ms on /lease. Default 10s.
+	MaxLeaseTTL time.Duration
+}
+
+// HostStats counts /wait and /lease decisions for one host.
+type HostStats struct {
+	Allowed  int64 // granted /wait calls and leases
+	Refused  int64
+	Tokens   int64 // sum of granted weights and lease tokens
+	Leases   int64
+	Returned int64 // lease tokens handed back via /lease/return
+}
+
+// Server is the in-process coordinator. It is an http.Handler; the
+// zero value is not usable — construct with New.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+	auth *coordauth.Verifier
+
+	mu     sync.Mutex
+	hosts  map[string]*hostState
+	leases map[string]*leaseState
+}
+
+type hostState struct {
+	lim    *rate.Limiter
+	credit int // returned lease tokens, spent before lim
+	stats  HostStats
+}
+
+// New constructs a Server.
+func New(opts Options) *Server {
+	if opts.DefaultRPS <= 0 {
+		opts.DefaultRPS = 10
+	}
+	if opts.DefaultBurst <= 0 {
+		opts.DefaultBurst = 20
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 30 * time.Second
+	}
+	if opts.MaxLeaseTTL <= 0 {
+		opts.MaxLeaseTTL = 10 * time.Second
+	}
+	s := &Server{
+		opts:   opts,
+		mux:    http.NewServeMux(),
+		auth:   coordauth.NewVerifier(opts.APIKey, opts.SigningSecret),
+		hosts:  map[string]*hostState{},
+		leases: map[string]*leaseState{},
+	}
+	s.mux.HandleFunc("POST /wait", s.handleWait)
+	s.mux.HandleFunc("POST /lease", s.handleLease)
+	s.mux.HandleFunc("POST /lease/return", s.handleReturn)
+	s.mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+// Stats returns the decision counters for host.
+func (s *Server) Stats(host string) HostStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h := s.hosts[host]; h != nil {
+		return h.stats
+	}
+	return HostStats{}
+}
+
+// Reset forgets every host's bucket and counters, and every lease.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.hosts = map[string]*hostState{}
+	s.leases = map[string]*leaseState{}
+	s.mu.Unlock()
+}
+
+type waitRequest struct {
+	Host      string `json:"host"`
+	Weight    int    `json:"weight"`
+	TimeoutMs int    `json:"timeout_ms"`
+}
+
+type waitResponse struct {
+	WaitedMs int64  `json:"waited_ms"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (s *Server) handleWait(w http.ResponseWriter, r *http.Request) {
+	var req waitRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.Host == "" {
+		writeJSON(w, http.StatusBadRequest, waitResponse{Error: "body must be {host, weight, timeout_ms}"})
+		return
+	}
+	req.Weight = max(req.Weight, 1)
+	timeout := min(time.Duration(req.TimeoutMs)*time.Millisecond, s.opts.MaxWait)
+
+	s.mu.Lock()
+	h := s.hostLocked(req.Host)
+	now := time.Now()
+	if h.spendCredit(req.Weight, now) {
+		h.stats.Allowed++
+		h.stats.Tokens += int64(req.Weight)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, waitResponse{})
+		return
+	}
+	res := h.lim.ReserveN(now, min(req.Weight, h.lim.Burst()))
+	delay := res.DelayFrom(now)
+	if delay > timeout {
+		res.CancelAt(now)
+		h.stats.Refused++
+		s.mu.Unlock()
+		writeJSON(w, http.StatusTooManyRequests, waitResponse{Error: "no token within timeout_ms"})
+		return
+	}
+	h.stats.Allowed++
+	h.stats.Tokens += int64(req.Weight)
+	s.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			res.Cancel()
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, waitResponse{WaitedMs: delay.Milliseconds()})
+}
+
+func (s *Server) hostLocked(host string) *hostState {
+	h := s.hosts[host]
+	if h == nil {
+		rps, burst := s.opts.DefaultRPS, s.opts.DefaultBurst
+		if p, ok := s.opts.Policies.Match(host); ok {
+			rps, burst = p.RPS, p.Burst
+		}
+		h = &hostState{lim: rate.NewLimiter(rate.Limit(rps), burst)}
+		s.hosts[host] = h
+	}
+	return h
+}
+
+// decode authenticates r and unmarshals its body into v, answering the
+// request itself and returning false on failure.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<14))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, waitResponse{Error: "body too large"})
+		return false
+	}
+	if err := s.auth.Verify(r, body); err != nil {
+		writeJSON(w, http.StatusUnauthorized, waitResponse{Error: err.Error()})
+		return false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeJSON(w, http.StatusBadRequest, waitResponse{Error: "invalid JSON body"})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
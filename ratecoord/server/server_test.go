@@ -0,0 +1,177 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/header"
+	"github.com/baditaflorin/go-common/ratecoord"
+	"github.com/baditaflorin/go-common/ratecoord/server"
+)
+
+func startCoordinator(t *testing.T, opts server.Options) (*server.Server, *ratecoord.Client) {
+	t.Helper()
+	coord := server.New(opts)
+	ts := httptest.NewServer(coord)
+	t.Cleanup(ts.Close)
+	t.Setenv("RATECOORD_URL", ts.URL)
+	t.Setenv("RATECOORD_API_KEY", "test-key")
+	t.Setenv("RATECOORD_DEFAULT_RPS", "1000")
+	return coord, ratecoord.New()
+}
+
+func TestClientAgainstServer_SharesBucket(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{APIKey: "test-key", DefaultRPS: 5, DefaultBurst: 1})
+	ctx := context.Background()
+
+	res, err := c.Wait(ctx, "example.com", 1, time.Second)
+	if err != nil || res.FellBack {
+		t.Fatalf("first wait: res=%+v err=%v", res, err)
+	}
+	start := time.Now()
+	res, err = c.Wait(ctx, "example.com", 1, time.Second)
+	if err != nil || res.FellBack {
+		t.Fatalf("second wait: res=%+v err=%v", res, err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Fatalf("the coordinator's 5 rps bucket should have delayed the second call; waited %v", waited)
+	}
+	if st := coord.Stats("example.com"); st.Allowed != 2 || st.Tokens != 2 {
+		t.Fatalf("stats = %+v, want 2 allowed", st)
+	}
+}
+
+func TestClientAgainstServer_RefusalFallsBack(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{APIKey: "test-key", DefaultRPS: 0.01, DefaultBurst: 1})
+	ctx := context.Background()
+	if _, err := c.Wait(ctx, "slow.example", 1, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Wait(ctx, "slow.example", 1, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.FellBack {
+		t.Fatalf("a refused /wait should send the client to its local bucket: %+v", res)
+	}
+	if st := coord.Stats("slow.example"); st.Refused != 1 {
+		t.Fatalf("stats = %+v, want 1 refused", st)
+	}
+}
+
+func TestServerRejectsBadKey(t *testing.T) {
+	coord := server.New(server.Options{APIKey: "right"})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/wait", strings.NewReader(`{"host":"h","weight":1,"timeout_ms":10}`))
+	r.Header.Set(header.APIKey, "wrong")
+	coord.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+}
+
+func TestServerIgnoresQueryKey(t *testing.T) {
+	coord := server.New(server.Options{APIKey: "right"})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/wait?api_key=right", strings.NewReader(`{"host":"h","weight":1,"timeout_ms":10}`))
+	coord.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("a key in the URL must not authenticate: status = %d, want 401", w.Code)
+	}
+}
+
+func TestClientAgainstServer_Signed(t *testing.T) {
+	t.Setenv("RATECOORD_SIGNING_SECRET", "s3cret")
+	coord, c := startCoordinator(t, server.Options{APIKey: "test-key", SigningSecret: "s3cret"})
+	res, err := c.Wait(context.Background(), "signed.example", 1, time.Second)
+	if err != nil || res.FellBack {
+		t.Fatalf("signed wait: res=%+v err=%v", res, err)
+	}
+	if st := coord.Stats("signed.example"); st.Allowed != 1 {
+		t.Fatalf("stats = %+v, want 1 allowed", st)
+	}
+
+	c.SigningSecret = "wrong"
+	res, err = c.Wait(context.Background(), "signed.example", 1, time.Second)
+	if err != nil || !res.FellBack {
+		t.Fatalf("a bad signature should be refused and fall back: res=%+v err=%v", res, err)
+	}
+}
+
+func TestServerHealthMatchesProbe(t *testing.T) {
+	_, c := startCoordinator(t, server.Options{})
+	if err := c.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe against in-process server: %v", err)
+	}
+}
+
+func TestReservationAgainstServer(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{APIKey: "test-key", DefaultRPS: 0.01, DefaultBurst: 10})
+	var closed []ratecoord.Event
+	var mu sync.Mutex
+	c.SetObserver(observerFunc(func(ev ratecoord.Event) {
+		if ev.LeaseClosed {
+			mu.Lock()
+			closed = append(closed, ev)
+			mu.Unlock()
+		}
+	}))
+	r := c.Reserve("batch.example", ratecoord.ReserveOptions{Tokens: 6, TTL: time.Minute})
+	for i := 0; i < 4; i++ {
+		res, err := r.Wait(context.Background(), 1)
+		if err != nil || res.FellBack {
+			t.Fatalf("wait %d: res=%+v err=%v", i, res, err)
+		}
+	}
+	st := coord.Stats("batch.example")
+	if st.Leases != 1 || st.Tokens != 6 {
+		t.Fatalf("four waits should cost one 6-token lease: %+v", st)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if st := coord.Stats("batch.example"); st.Returned != 2 {
+		t.Fatalf("Close should return the 2 unused tokens: %+v", st)
+	}
+	mu.Lock()
+	if len(closed) != 1 || closed[0].LeaseSize != 6 || closed[0].LeaseUsed != 4 {
+		t.Fatalf("closing events = %+v", closed)
+	}
+	mu.Unlock()
+
+	// The returned tokens are spendable again: the 10-token bucket had 4
+	// left, plus the 2 credited back.
+	r2 := c.Reserve("batch.example", ratecoord.ReserveOptions{Tokens: 10, TTL: time.Minute})
+	defer r2.Close()
+	if _, err := r2.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if st := coord.Stats("batch.example"); st.Tokens != 12 {
+		t.Fatalf("second lease should be granted 6 tokens (4 bucket + 2 credit): %+v", st)
+	}
+}
+
+func TestReservationReturnsOnExpiry(t *testing.T) {
+	coord, c := startCoordinator(t, server.Options{APIKey: "test-key"})
+	r := c.Reserve("ttl.example", ratecoord.ReserveOptions{Tokens: 5, TTL: 50 * time.Millisecond})
+	defer r.Close()
+	if _, err := r.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for coord.Stats("ttl.example").Returned != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expired lease was not returned: %+v", coord.Stats("ttl.example"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type observerFunc func(ratecoord.Event)
+
+func (f observerFunc) ObserveRate(ev ratecoord.Event) { f(ev) }
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is wrapped into the error Do returns when a retry
+// was warranted but the RetryBudget had no tokens left. errors.Is still
+// matches the last attempt's error too.
+var ErrBudgetExhausted = errors.New("retry: budget exhausted")
+
+// BudgetOptions configures a RetryBudget. Zero values fall back to the
+// defaults noted on each field.
+type BudgetOptions struct {
+	// Ratio is the fraction of first attempts that may be retried:
+	// each call deposits Ratio tokens, each retry withdraws one.
+	// Default 0.1 — retries add at most ~10% to upstream load.
+	Ratio float64
+	// MinPerSecond is a trickle of retries always allowed regardless
+	// of traffic, so a low-volume caller can still ride out a blip.
+	// Default 1. Negative disables the floor.
+	MinPerSecond float64
+	// Burst caps the bucket, bounding how many retries a quiet period
+	// can save up — this is what makes the ratio apply to *recent*
+	// requests. The bucket starts full. Default 10.
+	Burst float64
+}
+
+// RetryBudget is a token bucket shared by every Do call that passes it
+// via WithBudget. Attempt counts bound one call's retries; the budget
+// bounds the fleet-facing total, so during an outage N callers no
+// longer multiply upstream load by MaxAttempts. Safe for concurrent
+// use.
+type RetryBudget struct {
+	ratio     float64
+	perSecond float64
+	burst     float64
+
+	// now is the clock; tests swap it to control the refill.
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget builds a budget. Typical use is one per upstream,
+// stored next to the client that talks to it.
+func NewRetryBudget(opts BudgetOptions) *RetryBudget {
+	if opts.Ratio <= 0 {
+		opts.Ratio = 0.1
+	}
+	if opts.MinPerSecond == 0 {
+		opts.MinPerSecond = 1
+	}
+	if opts.MinPerSecond < 0 {
+		opts.MinPerSecond = 0
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = 10
+	}
+	return &RetryBudget{
+		ratio:     opts.Ratio,
+		perSecond: opts.MinPerSecond,
+		burst:     opts.Burst,
+		now:       time.Now,
+		tokens:    opts.Burst,
+	}
+}
+
+// Deposit records one first attempt. Do calls it; callers running
+// their own loops call it once per logical request.
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+	b.tokens = min(b.tokens+b.ratio, b.burst)
+}
+
+// Withdraw takes one retry token, reporting false when the budget is
+// spent.
+func (b *RetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns the retries currently available. For metrics and
+// tests.
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+	return b.tokens
+}
+
+func (b *RetryBudget) refillLocked() {
+	now := b.now()
+	if !b.last.IsZero() && b.perSecond > 0 {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.perSecond, b.burst)
+	}
+	b.last = now
+}
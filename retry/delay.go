@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delayer is implemented by errors that carry a server-specified wait
+// — a 429/503 Retry-After, a load-shed hint. When the error returned
+// by fn (or anything it wraps) is a Delayer reporting ok, Do waits that
+// long instead of the computed backoff. fleetfetch.RenderBusyError
+// implements it.
+type Delayer interface {
+	RetryDelay(now time.Time) (time.Duration, bool)
+}
+
+// DelayError attaches a server-specified delay to an error. Build one
+// with WithDelay or FromRetryAfter.
+type DelayError struct {
+	Cause error
+	Delay time.Duration
+}
+
+func (e *DelayError) Error() string { return e.Cause.Error() }
+func (e *DelayError) Unwrap() error { return e.Cause }
+
+// RetryDelay satisfies Delayer.
+func (e *DelayError) RetryDelay(time.Time) (time.Duration, bool) { return e.Delay, true }
+
+// WithDelay wraps err so Do waits d before the next attempt.
+func WithDelay(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &DelayError{Cause: err, Delay: max(d, 0)}
+}
+
+// FromRetryAfter wraps err with the delay from an HTTP Retry-After
+// header value. An empty or unparseable header returns err unchanged,
+// so Do falls back to its backoff.
+func FromRetryAfter(err error, header string) error {
+	d, ok := ParseRetryAfter(header, time.Now())
+	if !ok {
+		return err
+	}
+	return WithDelay(err, d)
+}
+
+// ParseRetryAfter converts a Retry-After value into a non-negative
+// delay. Both forms allowed by RFC 9110 are accepted: integer seconds
+// and an HTTP date (measured from now).
+func ParseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		// Clamp before scaling: a huge header must not wrap negative.
+		seconds = min(max(seconds, 0), math.MaxInt64/int64(time.Second))
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(when.Sub(now), 0), true
+}
+
+// serverDelay returns the delay carried by err, if any.
+func serverDelay(err error) (time.Duration, bool) {
+	var d Delayer
+	if !errors.As(err, &d) {
+		return 0, false
+	}
+	return d.RetryDelay(time.Now())
+}
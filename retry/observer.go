@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Phase buckets Transport attempt events.
+type Phase string
+
+const (
+	PhaseAttempt   Phase = "attempt"   // one round trip completed (any outcome), first attempt included
+	PhaseRetry     Phase = "retry"     // the attempt was retryable and another one is scheduled after Wait
+	PhaseExhausted Phase = "exhausted" // retryable, but attempts, deadline, or Retry-After cap ruled out another
+	PhaseBudget    Phase = "budget"    // retryable, but the RetryBudget had no token
+)
+
+// Observer receives Transport attempt events. Implementations MUST NOT
+// block. The canonical implementation lives in go-common/promx and
+// counts retries per host.
+type Observer interface {
+	ObserveRetry(Event)
+}
+
+// Event is the per-attempt payload handed to an Observer.
+//
+// Attempt is 1-based. StatusCode is 0 when the round trip returned an
+// error. Wait is set on PhaseRetry: the backoff, or the server's
+// Retry-After when FromServer is true.
+type Event struct {
+	Host       string
+	Method     string
+	Phase      Phase
+	Attempt    int
+	StatusCode int
+	Wait       time.Duration
+	FromServer bool
+}
+
+var defaultObserver atomic.Pointer[Observer]
+
+// SetDefaultObserver installs a process-wide observer. Pass nil to
+// disable. Wired by promx.AutoWire.
+func SetDefaultObserver(o Observer) {
+	if o == nil {
+		defaultObserver.Store(nil)
+		return
+	}
+	defaultObserver.Store(&o)
+}
+
+// DefaultObserver returns the current process-wide observer or nil.
+func DefaultObserver() Observer {
+	p := defaultObserver.Load()
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func emit(ev Event) {
+	if obs := DefaultObserver(); obs != nil {
+		obs.ObserveRetry(ev)
+	}
+}
@@ -16,11 +16,17 @@
 // The default strategy retries up to 3 times with exponential backoff
 // starting at 100 ms and full jitter. Context cancellation always
 // stops retries immediately.
+//
+// Two things bound retries beyond the attempt count: a shared
+// RetryBudget (WithBudget) caps retries to a fraction of recent calls
+// across every caller, and an error implementing Delayer (a server's
+// Retry-After) replaces the computed backoff for that wait.
 package retry
 
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"math/rand/v2"
 	"time"
@@ -44,6 +50,12 @@ type Options struct {
 	MaxAttempts int
 	Backoff     BackoffFunc
 	RetryIf     RetryIfFunc
+	// Budget, when set, must grant a token before every retry.
+	Budget *RetryBudget
+	// MaxServerDelay caps how long a server delay (see Delayer) may
+	// make Do wait; a longer one ends the retries. Default 30s, as
+	// Transport's MaxRetryAfter.
+	MaxServerDelay time.Duration
 }
 
 // Option is a functional option for Do.
@@ -72,20 +84,45 @@ func WithRetryIf(f RetryIfFunc) Option {
 	return func(o *Options) { o.RetryIf = f }
 }
 
+// WithBudget shares a RetryBudget across calls. Each Do deposits into
+// it once and withdraws one token per retry; when it is empty, Do
+// stops and returns the last error wrapped with ErrBudgetExhausted.
+func WithBudget(b *RetryBudget) Option {
+	return func(o *Options) { o.Budget = b }
+}
+
+// WithMaxServerDelay caps how long Do honours a server delay (a
+// Delayer, such as a Retry-After). When the server asks for longer, Do
+// returns the error instead of parking the caller — a Retry-After: 3600
+// without a ctx deadline would otherwise block for an hour. Values <= 0
+// keep the 30s default.
+func WithMaxServerDelay(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.MaxServerDelay = d
+		}
+	}
+}
+
 // Do calls fn up to MaxAttempts times, sleeping between failures
 // according to the Backoff strategy. It stops early when:
 //   - fn returns nil (success)
 //   - ctx is cancelled or deadline exceeded
 //   - RetryIf returns false for the returned error
 //   - MaxAttempts is exhausted
+//   - the RetryBudget has no token for another attempt
+//   - a server delay (see Delayer) would outlast ctx's deadline or
+//     exceed MaxServerDelay
 //
+// When the error is a Delayer, its delay is used instead of Backoff.
 // The error from the last attempt is returned. If the context was
 // cancelled, ctx.Err() is returned directly.
 func Do(ctx context.Context, fn Func, opts ...Option) error {
 	o := &Options{
-		MaxAttempts: 3,
-		Backoff:     ExponentialJitter(100 * time.Millisecond),
-		RetryIf:     AlwaysRetry,
+		MaxAttempts:    3,
+		Backoff:        ExponentialJitter(100 * time.Millisecond),
+		RetryIf:        AlwaysRetry,
+		MaxServerDelay: 30 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -94,6 +131,10 @@ func Do(ctx context.Context, fn Func, opts ...Option) error {
 		o.MaxAttempts = 1
 	}
 
+	if o.Budget != nil {
+		o.Budget.Deposit()
+	}
+
 	var lastErr error
 	for attempt := 1; attempt <= o.MaxAttempts; attempt++ {
 		if err := ctx.Err(); err != nil {
@@ -113,7 +154,18 @@ func Do(ctx context.Context, fn Func, opts ...Option) error {
 		if attempt == o.MaxAttempts {
 			break
 		}
-		wait := o.Backoff(attempt)
+		wait, fromServer := serverDelay(lastErr)
+		if !fromServer {
+			wait = o.Backoff(attempt)
+		} else if dl, ok := ctx.Deadline(); (ok && time.Until(dl) < wait) || wait > o.MaxServerDelay {
+			// The server told us when to come back and we can't (or
+			// won't) wait that long; hammering it sooner helps nobody.
+			return lastErr
+		}
+		// Withdraw only once the retry is certain to happen.
+		if o.Budget != nil && !o.Budget.Withdraw() {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, lastErr)
+		}
 		if wait <= 0 {
 			continue
 		}
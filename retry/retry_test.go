@@ -3,6 +3,7 @@ package retry_test
 import (
 	"context"
 	"errors"
+	"net/http"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -140,3 +141,139 @@ func TestDoMaxAttemptsOne(t *testing.T) {
 		t.Fatalf("MaxAttempts=1: expected 1 call, got %d", calls)
 	}
 }
+
+func TestDoBudgetCapsRetriesAcrossCalls(t *testing.T) {
+	budget := retry.NewRetryBudget(retry.BudgetOptions{Ratio: 0.1, Burst: 2, MinPerSecond: -1})
+	var calls atomic.Int32
+	fail := func(_ context.Context) error {
+		calls.Add(1)
+		return errors.New("down")
+	}
+	opts := []retry.Option{
+		retry.WithMaxAttempts(5),
+		retry.WithBackoff(retry.NoBackoff()),
+		retry.WithBudget(budget),
+	}
+	// The first call spends the two saved-up tokens; the 0.1 it
+	// deposited does not fund a third retry.
+	err := retry.Do(context.Background(), fail, opts...)
+	if !errors.Is(err, retry.ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("first call: %d attempts, want 3 (1 + 2 budgeted retries)", got)
+	}
+	// With the budget spent, later calls get exactly one attempt.
+	calls.Store(0)
+	_ = retry.Do(context.Background(), fail, opts...)
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("after exhaustion: %d attempts, want 1", got)
+	}
+}
+
+func TestRetryBudgetRefillsFromDeposits(t *testing.T) {
+	budget := retry.NewRetryBudget(retry.BudgetOptions{Ratio: 0.5, Burst: 1, MinPerSecond: -1})
+	if !budget.Withdraw() {
+		t.Fatal("budget should start full")
+	}
+	if budget.Withdraw() {
+		t.Fatal("empty budget must refuse")
+	}
+	budget.Deposit()
+	budget.Deposit()
+	if !budget.Withdraw() {
+		t.Fatal("two deposits at ratio 0.5 should fund one retry")
+	}
+}
+
+func TestDoServerDelayOverridesBackoff(t *testing.T) {
+	var calls atomic.Int32
+	start := time.Now()
+	err := retry.Do(context.Background(), func(_ context.Context) error {
+		if calls.Add(1) == 1 {
+			return retry.WithDelay(errors.New("busy"), 30*time.Millisecond)
+		}
+		return nil
+	},
+		retry.WithBackoff(retry.ConstantBackoff(time.Hour)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > 10*time.Second {
+		t.Fatalf("waited %v, want the server's 30ms rather than the 1h backoff", elapsed)
+	}
+}
+
+func TestDoServerDelayBeyondDeadlineAborts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	busy := retry.FromRetryAfter(errors.New("busy"), "120")
+	calls := 0
+	err := retry.Do(ctx, func(_ context.Context) error {
+		calls++
+		return busy
+	})
+	if !errors.Is(err, busy) || calls != 1 {
+		t.Fatalf("err=%v calls=%d, want the busy error after one attempt", err, calls)
+	}
+}
+
+func TestDoServerDelayAboveCapAborts(t *testing.T) {
+	for _, header := range []string{"3600", "10000000000"} {
+		busy := retry.FromRetryAfter(errors.New("busy"), header)
+		budget := retry.NewRetryBudget(retry.BudgetOptions{Ratio: 0.1, Burst: 1, MinPerSecond: -1})
+		calls := 0
+		start := time.Now()
+		err := retry.Do(context.Background(), func(_ context.Context) error {
+			calls++
+			return busy
+		}, retry.WithBudget(budget))
+		if !errors.Is(err, busy) || calls != 1 || time.Since(start) > 10*time.Second {
+			t.Fatalf("Retry-After %s: err=%v calls=%d after %v, want the busy error after one attempt", header, err, calls, time.Since(start))
+		}
+		// No retry happened, so none was paid for.
+		if got := budget.Tokens(); got < 1 {
+			t.Fatalf("Retry-After %s: budget %.2f, want the token unspent", header, got)
+		}
+	}
+
+	// The cap is configurable both ways.
+	calls := 0
+	err := retry.Do(context.Background(), func(_ context.Context) error {
+		if calls++; calls == 1 {
+			return retry.WithDelay(errors.New("busy"), 20*time.Millisecond)
+		}
+		return nil
+	}, retry.WithMaxServerDelay(10*time.Millisecond))
+	if err == nil || calls != 1 {
+		t.Fatalf("20ms delay over a 10ms cap: err=%v calls=%d, want an abort", err, calls)
+	}
+	calls = 0
+	err = retry.Do(context.Background(), func(_ context.Context) error {
+		if calls++; calls == 1 {
+			return retry.WithDelay(errors.New("busy"), 20*time.Millisecond)
+		}
+		return nil
+	}, retry.WithMaxServerDelay(time.Second))
+	if err != nil || calls != 2 {
+		t.Fatalf("20ms delay under a 1s cap: err=%v calls=%d, want a retry", err, calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d, ok := retry.ParseRetryAfter("7", now); !ok || d != 7*time.Second {
+		t.Fatalf("seconds form: %v %v", d, ok)
+	}
+	if d, ok := retry.ParseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now); !ok || d != 90*time.Second {
+		t.Fatalf("date form: %v %v", d, ok)
+	}
+	if _, ok := retry.ParseRetryAfter("soon", now); ok {
+		t.Fatal("garbage should not parse")
+	}
+	// An oversized header saturates instead of wrapping negative.
+	if d, ok := retry.ParseRetryAfter("10000000000", now); !ok || d < 24*time.Hour {
+		t.Fatalf("oversized seconds: %v %v", d, ok)
+	}
+}
@@ -0,0 +1,197 @@
+package retry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IdempotencyKeyHeader marks a non-idempotent request as safe to
+// replay: the server dedupes on the key (see middleware.IdempotencyKey).
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// TransportOptions configures a Transport. Zero values fall back to the
+// defaults noted on each field.
+type TransportOptions struct {
+	// MaxAttempts is the total attempts per request, first included.
+	// Default 3.
+	MaxAttempts int
+	// Backoff computes the wait between attempts when the server gave
+	// no Retry-After. Default ExponentialJitter(100ms).
+	Backoff BackoffFunc
+	// Budget, when set, must grant a token before every retry — share
+	// one with the retry.Do callers hitting the same upstream.
+	Budget *RetryBudget
+	// RetryOn decides whether an outcome is worth another attempt.
+	// Default DefaultRetryOn.
+	RetryOn func(*http.Response, error) bool
+	// MaxRetryAfter caps how long a server's Retry-After may make the
+	// transport wait. A longer hint is returned to the caller as-is
+	// rather than parking the request. Default 30s.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryOn retries transport errors (other than the caller's own
+// cancellation) and the statuses that mean "try again": 429, 502, 503,
+// and 504. A 500 is usually a bug that a replay will hit again.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return !isContextError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Transport is an http.RoundTripper that replays failed requests with
+// backoff. Only requests that are safe to repeat are retried: GET,
+// HEAD, and OPTIONS, or any method carrying an Idempotency-Key header.
+// A request with a body is retried only when GetBody can rewind it
+// (http.NewRequest sets it for bytes/strings readers). Everything else
+// makes exactly one attempt.
+//
+// On 429 and 503 a Retry-After header replaces the computed backoff.
+// The request context bounds the whole sequence: no wait is started
+// that would outlast its deadline.
+type Transport struct {
+	inner http.RoundTripper
+	opts  TransportOptions
+}
+
+// NewTransport wraps inner (http.DefaultTransport when nil).
+func NewTransport(inner http.RoundTripper, opts TransportOptions) *Transport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialJitter(100 * time.Millisecond)
+	}
+	if opts.RetryOn == nil {
+		opts.RetryOn = DefaultRetryOn
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = 30 * time.Second
+	}
+	return &Transport{inner: inner, opts: opts}
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !IsReplayable(req) {
+		return t.inner.RoundTrip(req)
+	}
+	if t.opts.Budget != nil {
+		t.opts.Budget.Deposit()
+	}
+	ctx := req.Context()
+	host := strings.ToLower(req.URL.Hostname())
+	cur := req
+	for attempt := 1; ; attempt++ {
+		resp, err := t.inner.RoundTrip(cur)
+		ev := Event{Host: host, Method: req.Method, Attempt: attempt}
+		if resp != nil {
+			ev.StatusCode = resp.StatusCode
+		}
+		ev.Phase = PhaseAttempt
+		emit(ev)
+
+		if !t.opts.RetryOn(resp, err) {
+			return resp, err
+		}
+		wait, fromServer := t.wait(resp, attempt)
+		if attempt >= t.opts.MaxAttempts || wait > t.opts.MaxRetryAfter || !fits(req, wait) {
+			ev.Phase = PhaseExhausted
+			emit(ev)
+			return resp, err
+		}
+		if t.opts.Budget != nil && !t.opts.Budget.Withdraw() {
+			ev.Phase = PhaseBudget
+			emit(ev)
+			return resp, err
+		}
+		next, rerr := rewind(req)
+		if rerr != nil {
+			// GetBody failed: the original outcome is more useful
+			// to the caller than the rewind error.
+			return resp, err
+		}
+		if resp != nil {
+			drain(resp.Body)
+		}
+		ev.Phase, ev.Wait, ev.FromServer = PhaseRetry, wait, fromServer
+		emit(ev)
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		cur = next
+	}
+}
+
+// wait picks the delay before the next attempt: Retry-After on 429 and
+// 503, otherwise the backoff.
+func (t *Transport) wait(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return d, true
+		}
+	}
+	return t.opts.Backoff(attempt), false
+}
+
+// IsReplayable reports whether req may be sent more than once: an
+// idempotent method (GET, HEAD, OPTIONS) or an Idempotency-Key header,
+// and a body that is absent or rewindable via GetBody. Shared with
+// other transports that duplicate requests (hedging).
+func IsReplayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if req.Header.Get(IdempotencyKeyHeader) == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// fits reports whether waiting d leaves time before req's deadline.
+func fits(req *http.Request, d time.Duration) bool {
+	dl, ok := req.Context().Deadline()
+	return !ok || time.Until(dl) > d
+}
+
+// rewind clones req with a fresh body for the next attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+// drain reads a little of a discarded response so the connection can
+// be reused, then closes it.
+func drain(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, body, 4<<10)
+	_ = body.Close()
+}
@@ -0,0 +1,150 @@
+package retry_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/retry"
+)
+
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if hits.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(append([]byte("ok:"), body...))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func fastTransport(opts retry.TransportOptions) *retry.Transport {
+	if opts.Backoff == nil {
+		opts.Backoff = retry.NoBackoff()
+	}
+	return retry.NewTransport(nil, opts)
+}
+
+func TestTransportRetriesIdempotentGet(t *testing.T) {
+	srv, hits := flakyServer(t, 2, http.StatusBadGateway, nil)
+	client := &http.Client{Transport: fastTransport(retry.TransportOptions{})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 3 {
+		t.Fatalf("status=%d hits=%d, want 200 after 3 attempts", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestTransportDoesNotReplayPost(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	client := &http.Client{Transport: fastTransport(retry.TransportOptions{})}
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Fatalf("plain POST must not be replayed: status=%d hits=%d", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestTransportReplaysPostWithIdempotencyKeyAndBody(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	client := &http.Client{Transport: fastTransport(retry.TransportOptions{})}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	req.Header.Set(retry.IdempotencyKeyHeader, "k1")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok:payload" || hits.Load() != 2 {
+		t.Fatalf("body=%q hits=%d, want the rewound body on attempt 2", body, hits.Load())
+	}
+}
+
+func TestTransportHonorsRetryAfter(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	var mu sync.Mutex
+	var events []retry.Event
+	retry.SetDefaultObserver(observerFunc(func(ev retry.Event) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	defer retry.SetDefaultObserver(nil)
+
+	client := &http.Client{Transport: retry.NewTransport(nil, retry.TransportOptions{Backoff: retry.ConstantBackoff(time.Hour)})}
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < time.Second || hits.Load() != 2 {
+		t.Fatalf("elapsed=%v hits=%d, want one retry after the server's 1s", elapsed, hits.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var retried bool
+	for _, ev := range events {
+		if ev.Phase == retry.PhaseRetry {
+			retried = ev.FromServer && ev.Wait == time.Second && ev.StatusCode == http.StatusTooManyRequests
+		}
+	}
+	if !retried {
+		t.Fatalf("expected a retry event sourced from Retry-After, got %+v", events)
+	}
+}
+
+func TestTransportRetryAfterBeyondDeadlineReturnsResponse(t *testing.T) {
+	srv, hits := flakyServer(t, 5, http.StatusServiceUnavailable, http.Header{"Retry-After": {"60"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := (&http.Client{Transport: fastTransport(retry.TransportOptions{})}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Fatalf("status=%d hits=%d, want the 503 returned without waiting", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestTransportBudget(t *testing.T) {
+	srv, hits := flakyServer(t, 100, http.StatusBadGateway, nil)
+	budget := retry.NewRetryBudget(retry.BudgetOptions{Burst: 1, MinPerSecond: -1})
+	client := &http.Client{Transport: fastTransport(retry.TransportOptions{MaxAttempts: 5, Budget: budget})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %d, want 2 (one budgeted retry)", hits.Load())
+	}
+}
+
+type observerFunc func(retry.Event)
+
+func (f observerFunc) ObserveRetry(ev retry.Event) { f(ev) }
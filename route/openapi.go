@@ -0,0 +1,88 @@
+package route
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/baditaflorin/go-common/loadshed"
+	"github.com/baditaflorin/go-common/openapi"
+)
+
+// Describer is implemented by handlers that document themselves —
+// server.JSON does, from its Go input and output types.
+// DescribeOperation adds what op lacks (parameters, request body,
+// responses) and leaves what is already set alone.
+type Describer interface {
+	DescribeOperation(op *openapi.Operation)
+}
+
+// OpenAPIOperation builds the operation published for route p served by
+// h. Besides the documentation in o and whatever h adds as a Describer,
+// it lists a path parameter per wildcard and the error responses the
+// route's own middleware can produce, so the spec cannot drift from
+// what the route enforces.
+func OpenAPIOperation(p Pattern, o Opts, h http.Handler) openapi.Operation {
+	var op openapi.Operation
+	if o.Operation != nil {
+		op = *o.Operation
+		op.Parameters = append([]openapi.Parameter(nil), op.Parameters...)
+	}
+	responses := make(map[string]openapi.Response, len(op.Responses)+4)
+	for code, r := range op.Responses {
+		responses[code] = r
+	}
+	op.Responses = responses
+	if op.Summary == "" {
+		op.Summary = o.Summary
+	}
+	if op.Description == "" {
+		op.Description = o.Description
+	}
+	if len(op.Tags) == 0 {
+		op.Tags = o.Tags
+	}
+	if d, ok := h.(Describer); ok {
+		d.DescribeOperation(&op)
+	}
+	for _, name := range p.Params() {
+		if !hasPathParam(op.Parameters, name) {
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+
+	if op.Responses == nil {
+		op.Responses = map[string]openapi.Response{}
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = openapi.Response{Description: "OK"}
+	}
+	addResponse := func(status int, desc string) {
+		if _, ok := op.Responses[strconv.Itoa(status)]; !ok {
+			op.Responses[strconv.Itoa(status)] = openapi.Response{Description: desc}
+		}
+	}
+	if o.Tier != "" {
+		addResponse(http.StatusForbidden, "Caller lacks the "+o.Tier+" access tier")
+	}
+	if o.MaxBodyBytes > 0 {
+		addResponse(http.StatusRequestEntityTooLarge, "Request body exceeds "+strconv.FormatInt(o.MaxBodyBytes, 10)+" bytes")
+	}
+	if o.Gate != nil {
+		addResponse(http.StatusServiceUnavailable, "Shed under load ("+loadshed.ShedErrorCode+"); retry after Retry-After")
+	}
+	if o.Timeout > 0 {
+		addResponse(http.StatusGatewayTimeout, "Exceeded the "+o.Timeout.String()+" route timeout")
+	}
+	return op
+}
+
+func hasPathParam(params []openapi.Parameter, name string) bool {
+	for _, p := range params {
+		if p.In == "path" && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern is a parsed Go 1.22 http.ServeMux pattern:
+// "[METHOD ][HOST]/[PATH]", where PATH segments may be wildcards —
+// "{name}", a trailing "{name...}", or "{$}" to anchor the end.
+type Pattern struct {
+	Method string // upper-case; "" matches every method
+	Host   string // "" matches every host
+	Path   string // starts with "/"
+}
+
+// ParsePattern parses pattern, taking the method from method when the
+// pattern does not carry one. A method given both ways must agree.
+func ParsePattern(method, pattern string) (Pattern, error) {
+	rest := strings.TrimSpace(pattern)
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		pm := rest[:i]
+		if method != "" && !strings.EqualFold(method, pm) {
+			return Pattern{}, fmt.Errorf("route: method %q conflicts with pattern %q", method, pattern)
+		}
+		method, rest = pm, strings.TrimLeft(rest[i:], " \t")
+	}
+	slash := strings.IndexByte(rest, '/')
+	if slash < 0 {
+		return Pattern{}, fmt.Errorf("route: pattern %q has no path", pattern)
+	}
+	p := Pattern{Method: strings.ToUpper(method), Host: rest[:slash], Path: rest[slash:]}
+	seen := map[string]bool{}
+	for _, name := range p.Params() {
+		if name == "" || seen[name] {
+			return Pattern{}, fmt.Errorf("route: pattern %q has an empty or repeated wildcard", pattern)
+		}
+		seen[name] = true
+	}
+	return p, nil
+}
+
+// String returns the pattern in the form http.ServeMux registers, and
+// reports back from ServeMux.Handler.
+func (p Pattern) String() string {
+	if p.Method == "" {
+		return p.Host + p.Path
+	}
+	return p.Method + " " + p.Host + p.Path
+}
+
+// Params returns the names of the path wildcards, in order.
+func (p Pattern) Params() []string {
+	var names []string
+	for _, seg := range strings.Split(p.Path, "/") {
+		if name, ok := wildcard(seg); ok && name != "$" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// OpenAPIPath returns the path in OpenAPI template form: "{name...}"
+// becomes "{name}" and a "{$}" anchor is dropped.
+func (p Pattern) OpenAPIPath() string {
+	segs := strings.Split(p.Path, "/")
+	for i, seg := range segs {
+		if name, ok := wildcard(seg); ok {
+			if name == "$" {
+				segs[i] = ""
+			} else {
+				segs[i] = "{" + name + "}"
+			}
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// wildcard reports whether seg is a wildcard segment and returns its
+// name, without braces or the "..." suffix.
+func wildcard(seg string) (string, bool) {
+	if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimSpace(seg[1:len(seg)-1]), "..."), true
+}
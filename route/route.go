@@ -0,0 +1,181 @@
+// Package route describes one HTTP route registered through
+// server.Server.Route: the middleware that belongs to it alone (access
+// tier, load-shed gate, body limit, timeout) and the documentation that
+// feeds the service's OpenAPI spec and GET /capabilities.
+//
+// Before it, a route's auth tier lived in a WithKeystoreAuthTier call,
+// its rate limit in a hand-wrapped gate.Guard, its body limit in the
+// server-wide default and its docs in an @openapi comment — four places
+// that drifted apart. A route.Opts keeps them on the registration line:
+//
+//	srv.Route("POST", "/scans/{id}/retry", http.HandlerFunc(retryScan), route.Opts{
+//	    Summary:      "Retry a failed scan",
+//	    Tier:         "vetted-pentest",
+//	    Gate:         renderGate,
+//	    MaxBodyBytes: 64 << 10,
+//	    Timeout:      20 * time.Second,
+//	})
+//
+// Patterns are Go 1.22 http.ServeMux patterns, wildcards included; the
+// method may be given separately or inside the pattern ("GET /x/{id}").
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/baditaflorin/go-common/loadshed"
+	"github.com/baditaflorin/go-common/middleware"
+	"github.com/baditaflorin/go-common/openapi"
+	"github.com/baditaflorin/go-common/response"
+)
+
+// TimeoutErrorCode is the error_code on the 504 envelope Handler writes
+// when Opts.Timeout elapses before the handler responded.
+const TimeoutErrorCode = "route_timeout"
+
+// Opts configures one route. The zero value adds no middleware and
+// documents the route by its pattern alone.
+type Opts struct {
+	// Summary, Description and Tags document the operation in the
+	// OpenAPI spec; Summary is also shown in /capabilities.
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Operation, when set, is the full OpenAPI operation to publish.
+	// Summary, Description and Tags fill its empty fields, and path
+	// parameters for the pattern's wildcards are added if missing.
+	Operation *openapi.Operation
+
+	// Tier is the access tier the caller must hold (see
+	// middleware.RequireTier). Requires keystore auth on the server.
+	Tier string
+
+	// Gate bounds the route's concurrency; excess requests are shed
+	// with the canonical 503 (see loadshed.Gate.Guard).
+	Gate *loadshed.Gate
+
+	// MaxBodyBytes caps the request body. On a server it replaces the
+	// server-wide WithMaxBodyBytes cap for this route, so it can raise
+	// the limit for uploads as well as lower it.
+	MaxBodyBytes int64
+
+	// Timeout bounds the request context. A handler that has written
+	// nothing by the time it returns after the deadline gets a 504;
+	// the handler itself must honour ctx to stop early.
+	Timeout time.Duration
+
+	// Middlewares run innermost, after the built-in ones above.
+	Middlewares []middleware.Middleware
+}
+
+// Handler wraps h in the middleware o describes, outermost first: tier,
+// gate, body limit, timeout, then o.Middlewares. The tier check runs
+// first so refused callers never take a gate slot.
+func Handler(h http.Handler, o Opts) http.Handler {
+	mws := []middleware.Middleware{middleware.RequireTier(o.Tier)}
+	if o.Gate != nil {
+		mws = append(mws, o.Gate.Guard(0, ""))
+	}
+	if o.MaxBodyBytes > 0 {
+		mws = append(mws, maxBody(o.MaxBodyBytes))
+	}
+	if o.Timeout > 0 {
+		mws = append(mws, timeout(o.Timeout))
+	}
+	mws = append(mws, o.Middlewares...)
+	return middleware.Chain(h, mws...)
+}
+
+func maxBody(n int64) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// timeout bounds the request context rather than using
+// http.TimeoutHandler, which buffers the response and so breaks
+// streaming handlers.
+func timeout(d time.Duration) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			tw := &trackingWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			if !tw.wrote && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				_ = json.NewEncoder(w).Encode(
+					response.NewError(http.StatusGatewayTimeout, TimeoutErrorCode, "request exceeded the route timeout of "+d.String()),
+				)
+			}
+		})
+	}
+}
+
+// trackingWriter records whether the handler started a response.
+type trackingWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *trackingWriter) WriteHeader(code int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working behind a timeout.
+func (w *trackingWriter) Flush() {
+	w.wrote = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *trackingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Info is the /capabilities entry for a route.
+type Info struct {
+	Method       string `json:"method,omitempty"`
+	Pattern      string `json:"pattern"`
+	Summary      string `json:"summary,omitempty"`
+	Tier         string `json:"tier,omitempty"`
+	Gate         string `json:"gate,omitempty"`
+	MaxBodyBytes int64  `json:"max_body_bytes,omitempty"`
+	TimeoutMS    int64  `json:"timeout_ms,omitempty"`
+}
+
+// NewInfo describes the route p registered with o.
+func NewInfo(p Pattern, o Opts) Info {
+	info := Info{
+		Method:       p.Method,
+		Pattern:      p.Host + p.Path,
+		Summary:      o.Summary,
+		Tier:         o.Tier,
+		MaxBodyBytes: o.MaxBodyBytes,
+		TimeoutMS:    o.Timeout.Milliseconds(),
+	}
+	if info.Summary == "" && o.Operation != nil {
+		info.Summary = o.Operation.Summary
+	}
+	if o.Gate != nil {
+		info.Gate = o.Gate.Name()
+	}
+	return info
+}
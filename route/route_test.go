@@ -0,0 +1,116 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/loadshed"
+	"github.com/baditaflorin/go-common/openapi"
+)
+
+func TestParsePattern(t *testing.T) {
+	cases := []struct {
+		method, pattern string
+		want            Pattern
+		mux, oapi       string
+		params          []string
+	}{
+		{"get", "/items/{id}", Pattern{"GET", "", "/items/{id}"}, "GET /items/{id}", "/items/{id}", []string{"id"}},
+		{"", "POST /files/{path...}", Pattern{"POST", "", "/files/{path...}"}, "POST /files/{path...}", "/files/{path}", []string{"path"}},
+		{"GET", "GET api.example.com/{$}", Pattern{"GET", "api.example.com", "/{$}"}, "GET api.example.com/{$}", "/", nil},
+		{"", "/static/", Pattern{"", "", "/static/"}, "/static/", "/static/", nil},
+	}
+	for _, c := range cases {
+		p, err := ParsePattern(c.method, c.pattern)
+		if err != nil {
+			t.Fatalf("%q %q: %v", c.method, c.pattern, err)
+		}
+		if p != c.want || p.String() != c.mux || p.OpenAPIPath() != c.oapi || strings.Join(p.Params(), ",") != strings.Join(c.params, ",") {
+			t.Errorf("%q %q: got %+v (mux %q, openapi %q, params %v)", c.method, c.pattern, p, p.String(), p.OpenAPIPath(), p.Params())
+		}
+	}
+	for _, bad := range [][2]string{{"GET", "POST /x"}, {"", "GET"}, {"", "/a/{id}/{id}"}, {"", "/a/{}"}} {
+		if _, err := ParsePattern(bad[0], bad[1]); err == nil {
+			t.Errorf("ParsePattern(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestHandler_Timeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() })
+	rec := httptest.NewRecorder()
+	Handler(slow, Opts{Timeout: 10 * time.Millisecond}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), TimeoutErrorCode) {
+		t.Fatalf("got %d %s, want a 504 %s envelope", rec.Code, rec.Body, TimeoutErrorCode)
+	}
+
+	// A handler that already answered keeps its response.
+	answered := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		<-r.Context().Done()
+	})
+	rec = httptest.NewRecorder()
+	Handler(answered, Opts{Timeout: 10 * time.Millisecond}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Fatalf("got %d %q, want the handler's 202 untouched", rec.Code, rec.Body)
+	}
+}
+
+func TestHandler_GateBodyLimitAndTier(t *testing.T) {
+	gate := loadshed.New("route-test", 1)
+	release, _ := gate.TryAcquire()
+	defer release()
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	Handler(echo, Opts{Gate: gate}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("saturated gate: got %d, want 503", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler(echo, Opts{MaxBodyBytes: 4}).ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("too long")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: got %d, want 413", rec.Code)
+	}
+
+	// The tier check runs before the gate, so a refused caller does
+	// not see (or take) the gate.
+	rec = httptest.NewRecorder()
+	Handler(echo, Opts{Tier: "pro", Gate: gate}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("caller without a tier: got %d, want 403", rec.Code)
+	}
+}
+
+func TestOpenAPIOperation(t *testing.T) {
+	p, _ := ParsePattern("GET", "/users/{uid}/files/{path...}")
+	op := OpenAPIOperation(p, Opts{
+		Summary: "Read a file",
+		Tier:    "pro",
+		Timeout: time.Second,
+		Operation: &openapi.Operation{
+			Parameters: []openapi.Parameter{{Name: "uid", In: "path", Required: true, Description: "user id"}},
+			Responses:  map[string]openapi.Response{"200": {Description: "the file"}},
+		},
+	}, nil)
+	if op.Summary != "Read a file" || len(op.Parameters) != 2 || op.Parameters[0].Description != "user id" || op.Parameters[1].Name != "path" {
+		t.Fatalf("operation = %+v", op)
+	}
+	for _, code := range []string{"200", "403", "504"} {
+		if _, ok := op.Responses[code]; !ok {
+			t.Errorf("missing response %s", code)
+		}
+	}
+	if _, ok := op.Responses["503"]; ok {
+		t.Error("a route without a gate should not document a 503")
+	}
+}
@@ -0,0 +1,50 @@
+package safehttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/circuitbreaker"
+	"github.com/baditaflorin/go-common/safehttp"
+)
+
+func TestWithCircuitBreaker_FailsFastAfterTrip(t *testing.T) {
+	allowLoopback(t)
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	var degraded []string
+	c := safehttp.NewClient(
+		safehttp.WithoutFetchCache(),
+		safehttp.WithDegradedSink(&degraded),
+		safehttp.WithCircuitBreaker(circuitbreaker.TransportOptions{
+			Breaker: circuitbreaker.Options{FailureThreshold: 3, OpenFor: time.Minute},
+		}),
+	)
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(upstream.URL)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	_, err := c.Get(upstream.URL)
+	if !errors.Is(err, circuitbreaker.ErrOpen) {
+		t.Fatalf("after trip: err = %v, want ErrOpen", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("upstream hits = %d, want 3", got)
+	}
+	if len(degraded) == 0 {
+		t.Fatal("degraded sink should record the failing host")
+	}
+}
@@ -0,0 +1,25 @@
+package safehttp
+
+import (
+	"github.com/baditaflorin/go-common/circuitbreaker"
+)
+
+// WithCircuitBreaker guards every upstream host with its own
+// circuitbreaker.Breaker (see circuitbreaker.Transport). After the
+// configured failures — transport errors, timeouts, 5xx, and 429 by
+// default — requests to that host fail fast with an error wrapping
+// circuitbreaker.ErrOpen instead of piling onto a dying upstream.
+//
+// The breaker sits inside the fleet hooks: the egress observer and
+// WithDegradedSink see the fail-fast as an error, and a fetch-cache hit
+// is still served while the origin's breaker is open. State changes are
+// reported through circuitbreaker's default observer, so promx.AutoWire
+// exports them as fleet_circuit_state{upstream="<host>"} with no extra
+// wiring.
+//
+//	cli := safehttp.NewClient(safehttp.WithCircuitBreaker(circuitbreaker.TransportOptions{
+//	    Breaker: circuitbreaker.Options{FailureRateThreshold: 0.5, OpenFor: 20 * time.Second},
+//	}))
+func WithCircuitBreaker(opts circuitbreaker.TransportOptions) Option {
+	return func(o *options) { o.circuitBreaker = &opts }
+}
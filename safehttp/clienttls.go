@@ -0,0 +1,98 @@
+package safehttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/baditaflorin/go-common/certreload"
+)
+
+// WithClientCert presents the PEM certificate and key in certFile and
+// keyFile to servers that ask for one — the client half of mutual TLS
+// against a server.WithTLS service with a ClientCAFile. The files are
+// re-read when they change, so a rotated certificate is used from the
+// next connection on.
+//
+// Files that fail to load do not panic NewClient: every request from
+// the client fails with the load error instead. A client with a client
+// certificate never routes through the process-wide default fetch-cache
+// delegate, which could not present it.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *options) { o.clientCertFile, o.clientKeyFile = certFile, keyFile }
+}
+
+// WithRootCAs verifies servers against the PEM CA bundle in caFile
+// instead of the system roots — for mesh hops whose certificates come
+// from a private CA. The bundle is re-read when it changes.
+func WithRootCAs(caFile string) Option {
+	return func(o *options) { o.rootCAFile = caFile }
+}
+
+// clientTLSConfig builds the transport TLS config for WithClientCert and
+// WithRootCAs, or returns nil when neither was used. With WithRootCAs it
+// also returns the reloading pool; cfg.RootCAs holds its current
+// contents.
+func clientTLSConfig(o *options) (*tls.Config, *certreload.CAPool, error) {
+	if o.clientCertFile == "" && o.clientKeyFile == "" && o.rootCAFile == "" {
+		return nil, nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.clientCertFile != "" || o.clientKeyFile != "" {
+		kp, err := certreload.LoadKeyPair(o.clientCertFile, o.clientKeyFile, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.GetClientCertificate = kp.GetClientCertificate
+	}
+	var ca *certreload.CAPool
+	if o.rootCAFile != "" {
+		var err error
+		if ca, err = certreload.LoadCAPool(o.rootCAFile, 0); err != nil {
+			return nil, nil, err
+		}
+		cfg.RootCAs = ca.Pool()
+	}
+	return cfg, ca, nil
+}
+
+// rootCATransport keeps the stdlib's server verification — against the
+// host actually dialed, IP literals included — while following a
+// reloading WithRootCAs bundle. An http.Transport reads RootCAs once per
+// connection, so a changed pool gets a freshly built transport and the
+// old one's idle connections are closed.
+type rootCATransport struct {
+	ca    *certreload.CAPool
+	build func(*x509.CertPool) http.RoundTripper
+
+	mu   sync.Mutex
+	pool *x509.CertPool
+	rt   http.RoundTripper
+}
+
+func (t *rootCATransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.current().RoundTrip(req)
+}
+
+func (t *rootCATransport) current() http.RoundTripper {
+	pool := t.ca.Pool()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rt == nil || pool != t.pool {
+		if c, ok := t.rt.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+		t.pool, t.rt = pool, t.build(pool)
+	}
+	return t.rt
+}
+
+// errTransport fails every request with err: a client whose TLS files
+// did not load.
+type errTransport struct{ err error }
+
+func (t errTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("safehttp: client TLS: %w", t.err)
+}
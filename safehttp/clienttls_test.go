@@ -0,0 +1,69 @@
+package safehttp
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go-common/testhelpers/testca"
+)
+
+func TestWithClientCert_LoadErrorFailsRequests(t *testing.T) {
+	c := NewClient(WithClientCert("/nonexistent.crt", "/nonexistent.key"))
+	_, err := c.Get("https://example.com/")
+	if err == nil || !strings.Contains(err.Error(), "safehttp: client TLS") {
+		t.Fatalf("err = %v, want the client TLS load error", err)
+	}
+}
+
+func TestClientTLSConfig_NilWithoutOptions(t *testing.T) {
+	cfg, ca, err := clientTLSConfig(&options{})
+	if cfg != nil || ca != nil || err != nil {
+		t.Fatalf("clientTLSConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+// tlsServer serves pair over TLS on 127.0.0.1.
+func tlsServer(t *testing.T, pair testca.Pair) string {
+	t.Helper()
+	cert, err := tls.X509KeyPair(pair.CertPEM, pair.KeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestWithRootCAs_VerifiesDialedIP(t *testing.T) {
+	SetAllowedPrivateIPs([]net.IP{net.ParseIP("127.0.0.1")})
+	t.Cleanup(func() { SetAllowedPrivateIPs(nil) })
+	ca := testca.New(t)
+	c := NewClient(WithRootCAs(ca.WriteBundle(t, t.TempDir())), WithoutProxy(), WithoutFetchCache())
+
+	// Signed by the trusted CA, but for another host: an IP-literal URL
+	// must still be checked against the certificate's SANs.
+	if resp, err := c.Get(tlsServer(t, ca.IssueFor(t, "other.example")) + "/"); err == nil {
+		resp.Body.Close()
+		t.Fatal("a certificate without the dialed IP should be refused")
+	}
+
+	resp, err := c.Get(tlsServer(t, ca.IssueFor(t, "127.0.0.1")) + "/")
+	if err != nil {
+		t.Fatalf("certificate for the dialed IP: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp, err := c.Get(tlsServer(t, testca.New(t).IssueFor(t, "127.0.0.1")) + "/"); err == nil {
+		resp.Body.Close()
+		t.Fatal("a certificate from another CA should be refused")
+	}
+}
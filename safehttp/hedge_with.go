@@ -0,0 +1,20 @@
+package safehttp
+
+import (
+	"github.com/baditaflorin/go-common/hedge"
+)
+
+// WithHedging hedges replayable requests (GET/HEAD/OPTIONS, or any
+// request with an Idempotency-Key and a rewindable body) through a
+// hedge.Transport: when a request has not answered within opts.Delay —
+// or the host's observed p95 — an identical second request is sent and
+// the first good answer wins. The loser is cancelled. Hedges are capped
+// at opts.MaxRate of traffic.
+//
+// The hedge layer wraps the fleet hooks, so both requests of a pair are
+// observed as egress, and sits inside WithRetry: each retry attempt may
+// be hedged. Hedges issued/won go to hedge's default observer, which
+// promx.AutoWire installs.
+func WithHedging(opts hedge.Options) Option {
+	return func(o *options) { o.hedge = &opts }
+}
@@ -0,0 +1,117 @@
+package safehttp
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WithH2C makes the client speak cleartext HTTP/2 ("h2c", prior
+// knowledge) to http:// URLs — the client half of server.WithH2C, for
+// mesh hops behind the gateway. NegotiatedProtocol reports "h2c" for
+// such responses.
+//
+// The client then speaks HTTP/2 only: h2c for http:// and h2 over TLS
+// for https://, so do not use it against origins that may be
+// HTTP/1.1-only. Like WithForceHTTP2, it opts the client out of the
+// process-wide default fetch-cache delegate, whose reconstructed
+// responses would hide the protocol.
+func WithH2C() Option { return func(o *options) { o.h2c = true } }
+
+// WithHTTP3 sends https:// requests over rt — an HTTP/3 (QUIC) round
+// tripper such as quic-go's *http3.Transport; go-common does not depend
+// on a QUIC implementation itself. NegotiatedProtocol reports "h3" for
+// responses it serves.
+//
+// QUIC dials UDP itself, outside the SSRF-guarded dialer, so the target
+// host is checked with GuardHost before each HTTP/3 attempt; set the
+// round tripper's own dial hook if DNS-rebind protection on the
+// connected address matters. Requests that go through an HTTP(S) proxy,
+// and http:// requests, stay on TCP.
+//
+// When an idempotent request (GET, HEAD, OPTIONS) fails over HTTP/3 —
+// typically UDP blocked on the path — it is retried over TCP and the
+// host skips HTTP/3 for h3FailureTTL, so a blocked network pays the
+// QUIC timeout once rather than on every call. Other methods return the
+// HTTP/3 error. Like WithH2C, this opts out of the default fetch-cache
+// delegate.
+func WithHTTP3(rt http.RoundTripper) Option { return func(o *options) { o.http3 = rt } }
+
+// h3FailureTTL is how long a host whose HTTP/3 attempt failed is sent
+// over TCP before HTTP/3 is tried again.
+const h3FailureTTL = 5 * time.Minute
+
+// http3Transport routes https:// requests through h3, falling back to
+// tcp as described on WithHTTP3.
+type http3Transport struct {
+	h3    http.RoundTripper
+	tcp   http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+	now   func() time.Time
+
+	mu     sync.Mutex
+	failed map[string]time.Time // host → time HTTP/3 may be tried again
+}
+
+func newHTTP3Transport(h3, tcp http.RoundTripper, proxy func(*http.Request) (*url.URL, error)) *http3Transport {
+	return &http3Transport{h3: h3, tcp: tcp, proxy: proxy, now: time.Now, failed: map[string]time.Time{}}
+}
+
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.useHTTP3(req) {
+		return t.tcp.RoundTrip(req)
+	}
+	if err := GuardHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := t.h3.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+	if req.Context().Err() != nil || !idempotent(req.Method) {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		nb, gerr := req.GetBody()
+		if gerr != nil {
+			return nil, err
+		}
+		req.Body = nb
+	}
+	t.mu.Lock()
+	t.failed[req.URL.Host] = t.now().Add(h3FailureTTL)
+	t.mu.Unlock()
+	return t.tcp.RoundTrip(req)
+}
+
+// useHTTP3 reports whether req should be attempted over HTTP/3.
+func (t *http3Transport) useHTTP3(req *http.Request) bool {
+	if req.URL.Scheme != "https" {
+		return false
+	}
+	if t.proxy != nil {
+		if u, err := t.proxy(req); err != nil || u != nil {
+			return false
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.failed[req.URL.Host]
+	if ok && t.now().After(until) {
+		delete(t.failed, req.URL.Host)
+		ok = false
+	}
+	return !ok
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
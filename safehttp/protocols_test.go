@@ -0,0 +1,119 @@
+package safehttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithH2C_NegotiatesH2C(t *testing.T) {
+	allowLoopbackForTest(t)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	resp, err := NewClient(WithH2C()).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/2.0" || NegotiatedProtocol(resp) != "h2c" {
+		t.Fatalf("server saw %q, NegotiatedProtocol = %q; want HTTP/2.0, h2c", body, NegotiatedProtocol(resp))
+	}
+
+	// Without the option the same server is spoken to over HTTP/1.1.
+	resp, err = NewClient().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if p := NegotiatedProtocol(resp); p != "" {
+		t.Fatalf("default client NegotiatedProtocol = %q, want empty", p)
+	}
+}
+
+func TestNegotiatedProtocol_H3(t *testing.T) {
+	resp := &http.Response{ProtoMajor: 3, TLS: &tls.ConnectionState{}}
+	if p := NegotiatedProtocol(resp); p != "h3" {
+		t.Fatalf("NegotiatedProtocol = %q, want h3", p)
+	}
+}
+
+// fakeRT records the requests it serves and answers with resp or err.
+type fakeRT struct {
+	calls int
+	proto int
+	err   error
+}
+
+func (f *fakeRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: 200, ProtoMajor: f.proto, Body: http.NoBody, Request: req}, nil
+}
+
+func TestHTTP3Transport_FallsBackAndRemembers(t *testing.T) {
+	allowLoopbackForTest(t)
+	h3 := &fakeRT{err: errors.New("quic: handshake timeout")}
+	tcp := &fakeRT{proto: 2}
+	tr := newHTTP3Transport(h3, tcp, nil)
+	now := time.Now()
+	tr.now = func() time.Time { return now }
+
+	get := func() int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "https://127.0.0.1/x", nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.ProtoMajor
+	}
+	if p := get(); p != 2 || h3.calls != 1 {
+		t.Fatalf("first GET: proto %d, h3 calls %d; want a TCP fallback after one h3 try", p, h3.calls)
+	}
+	get()
+	if h3.calls != 1 {
+		t.Fatal("a host whose HTTP/3 attempt failed should skip HTTP/3 within the TTL")
+	}
+	now = now.Add(h3FailureTTL + time.Second)
+	h3.err, h3.proto = nil, 3
+	if p := get(); p != 3 {
+		t.Fatalf("after the TTL: proto %d, want HTTP/3 again", p)
+	}
+
+	// A non-idempotent request is not replayed over TCP.
+	h3.err = errors.New("quic: stream reset")
+	req, _ := http.NewRequest(http.MethodPost, "https://127.0.0.1/x", strings.NewReader("{}"))
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Fatal("a failed POST over HTTP/3 should return the error")
+	}
+}
+
+func TestHTTP3Transport_GuardsAndSkipsCleartext(t *testing.T) {
+	h3, tcp := &fakeRT{proto: 3}, &fakeRT{proto: 1}
+	tr := newHTTP3Transport(h3, tcp, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://10.0.0.1/", nil)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("private target over HTTP/3: err = %v, want ErrBlocked", err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	tr.RoundTrip(req)
+	if h3.calls != 0 || tcp.calls != 1 {
+		t.Fatalf("http:// should stay on TCP: h3 %d, tcp %d", h3.calls, tcp.calls)
+	}
+}
@@ -0,0 +1,38 @@
+package safehttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/ratecoord"
+	"github.com/baditaflorin/go-common/safehttp"
+)
+
+func TestWithRateLearning_PausesHostOnRetryAfter(t *testing.T) {
+	allowLoopback(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer upstream.Close()
+	// No coordinator: Wait falls back to the local budget Learn tightened.
+	coord := httptest.NewServer(http.NotFoundHandler())
+	coord.Close()
+	t.Setenv("RATECOORD_URL", coord.URL)
+
+	rc := ratecoord.New()
+	c := safehttp.NewClient(safehttp.WithoutFetchCache(), safehttp.WithRateLearning(rc))
+	resp, err := c.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := rc.Wait(context.Background(), "127.0.0.1", 1, 100*time.Millisecond); err == nil {
+		t.Fatal("the 429 should have paused 127.0.0.1 past the 100ms wait")
+	}
+}
@@ -0,0 +1,17 @@
+package safehttp
+
+import (
+	"github.com/baditaflorin/go-common/ratecoord"
+)
+
+// WithRateLearning passes every upstream response to rc.Learn, so an
+// upstream's X-RateLimit-* and Retry-After answers tighten rc's local
+// budget for that host without the caller calling Learn itself. Every
+// attempt is seen, retries and hedges included; requests refused before
+// reaching the upstream (an open WithCircuitBreaker) teach nothing.
+//
+//	rc := ratecoord.New()
+//	cli := safehttp.NewClient(safehttp.WithRateLearning(rc))
+func WithRateLearning(rc *ratecoord.Client) Option {
+	return func(o *options) { o.rateLearner = rc }
+}
@@ -0,0 +1,67 @@
+package safehttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/circuitbreaker"
+	"github.com/baditaflorin/go-common/retry"
+	"github.com/baditaflorin/go-common/safehttp"
+)
+
+func TestWithRetry_ReplaysTransientFailure(t *testing.T) {
+	allowLoopback(t)
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	c := safehttp.NewClient(
+		safehttp.WithoutFetchCache(),
+		safehttp.WithRetry(retry.TransportOptions{Backoff: retry.NoBackoff()}),
+	)
+	resp, err := c.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Fatalf("status=%d hits=%d, want 200 on the second attempt", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestWithRetry_DoesNotRetryOpenBreaker(t *testing.T) {
+	allowLoopback(t)
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	c := safehttp.NewClient(
+		safehttp.WithoutFetchCache(),
+		safehttp.WithCircuitBreaker(circuitbreaker.TransportOptions{
+			Breaker: circuitbreaker.Options{FailureThreshold: 2, OpenFor: time.Minute},
+		}),
+		safehttp.WithRetry(retry.TransportOptions{MaxAttempts: 5, Backoff: retry.NoBackoff()}),
+	)
+	_, err := c.Get(upstream.URL)
+	if !errors.Is(err, circuitbreaker.ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen once the breaker trips mid-retry", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("upstream hits = %d, want 2", got)
+	}
+}
@@ -0,0 +1,53 @@
+package safehttp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/baditaflorin/go-common/circuitbreaker"
+	"github.com/baditaflorin/go-common/retry"
+)
+
+// WithRetry replays failed idempotent requests through a
+// retry.Transport: GET/HEAD/OPTIONS, or any request carrying an
+// Idempotency-Key header, with a body only when GetBody can rewind it.
+// Retry-After on 429/503 replaces the backoff, and the request context
+// bounds the whole sequence.
+//
+// The retry layer wraps the fleet hooks, so every attempt is seen by
+// the egress observer and consults WithBackoffCoordinator before going
+// out. Refusals that a replay cannot fix — SSRF/denylist/allowlist
+// blocks and an open WithCircuitBreaker — are never retried, whatever
+// opts.RetryOn says. Attempts are reported through retry's default
+// observer, so promx.AutoWire counts retries per host.
+//
+//	cli := safehttp.NewClient(safehttp.WithRetry(retry.TransportOptions{
+//	    MaxAttempts: 4,
+//	    Budget:      retry.NewRetryBudget(retry.BudgetOptions{}),
+//	}))
+func WithRetry(opts retry.TransportOptions) Option {
+	return func(o *options) { o.retry = &opts }
+}
+
+// newRetryTransport builds the retry layer with safehttp's own terminal
+// errors excluded from opts.RetryOn.
+func newRetryTransport(inner http.RoundTripper, opts retry.TransportOptions) http.RoundTripper {
+	retryOn := opts.RetryOn
+	if retryOn == nil {
+		retryOn = retry.DefaultRetryOn
+	}
+	opts.RetryOn = func(resp *http.Response, err error) bool {
+		if err != nil && isTerminalEgressError(err) {
+			return false
+		}
+		return retryOn(resp, err)
+	}
+	return retry.NewTransport(inner, opts)
+}
+
+func isTerminalEgressError(err error) bool {
+	return errors.Is(err, ErrBlocked) ||
+		errors.Is(err, ErrDomainDenied) ||
+		errors.Is(err, ErrEgressNotAllowed) ||
+		errors.Is(err, circuitbreaker.ErrOpen)
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/baditaflorin/go-common/circuitbreaker"
+	"github.com/baditaflorin/go-common/hedge"
+	"github.com/baditaflorin/go-common/ratecoord"
+	"github.com/baditaflorin/go-common/retry"
 	"net"
 	"net/http"
 	"net/url"
@@ -201,6 +205,11 @@ type options struct {
 	// WithForceHTTP2 for why a custom dialer otherwise disables it.
 	forceHTTP2 bool
 
+	// h2c and http3 select cleartext HTTP/2 and an HTTP/3 round tripper.
+	// See WithH2C / WithHTTP3.
+	h2c   bool
+	http3 http.RoundTripper
+
 	// maxIdleConnsPerHost caps Transport.MaxIdleConnsPerHost. The Go
 	// standard library defaults this to 2 (http.DefaultMaxIdleConnsPerHost),
 	// which throttles connection reuse for services that hammer a single
@@ -248,6 +257,28 @@ type options struct {
 	// least one of traceURL/backoffURL/degradedSink is set), since
 	// the state to persist lives on that transport.
 	breakerState *breakerStateConfig
+
+	// circuitBreaker, when set, wraps the base transport in a per-host
+	// circuitbreaker.Transport. See WithCircuitBreaker.
+	circuitBreaker *circuitbreaker.TransportOptions
+
+	// retry, when set, wraps the fleet hooks in a retry.Transport.
+	// See WithRetry.
+	retry *retry.TransportOptions
+
+	// hedge, when set, wraps the fleet hooks in a hedge.Transport
+	// (inside the retry layer). See WithHedging.
+	hedge *hedge.Options
+
+	// rateLearner, when set, sees every upstream response, inside the
+	// circuit breaker. See WithRateLearning.
+	rateLearner *ratecoord.Client
+
+	// Client TLS material — see WithClientCert / WithRootCAs. Empty
+	// means the Go defaults (system roots, no client certificate).
+	clientCertFile string
+	clientKeyFile  string
+	rootCAFile     string
 }
 
 // Option configures NewClient.
@@ -405,9 +436,22 @@ func newBaseTransport(o *options, proxyFn func(*http.Request) (*url.URL, error))
 		MaxIdleConns:          20,
 		MaxIdleConnsPerHost:   resolveMaxIdleConnsPerHost(o.maxIdleConnsPerHost),
 		IdleConnTimeout:       30 * time.Second,
+		Protocols:             transportProtocols(o),
 	}
 }
 
+// transportProtocols is the WithH2C protocol set — HTTP/2 only, over
+// TLS and in cleartext — or nil for the ForceAttemptHTTP2 default.
+func transportProtocols(o *options) *http.Protocols {
+	if !o.h2c {
+		return nil
+	}
+	p := new(http.Protocols)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
 // defaultMaxIdleConnsPerHost is safehttp's default cap on idle keep-alive
 // connections per upstream host. The Go std default is 2
 // (http.DefaultMaxIdleConnsPerHost); we raise it to 10 so high-throughput
@@ -428,8 +472,10 @@ func resolveMaxIdleConnsPerHost(n int) int {
 }
 
 // NegotiatedProtocol returns the TLS ALPN protocol the server selected
-// for resp (e.g. "h2" or "http/1.1"), or "" when resp carried no TLS
-// state (plain HTTP, or a nil/errored response). It is nil-safe.
+// for resp (e.g. "h2" or "http/1.1"), "h3" for an HTTP/3 response (see
+// WithHTTP3), "h2c" for cleartext HTTP/2 (see WithH2C), or "" when resp
+// carried no TLS state otherwise (plain HTTP/1.x, or a nil/errored
+// response). It is nil-safe.
 //
 // To get a reliable "h2" here the client MUST be built with
 // WithForceHTTP2 — the default safehttp transport installs a custom
@@ -450,7 +496,14 @@ func resolveMaxIdleConnsPerHost(n int) int {
 // WithFetchDelegate), an empty result here still means "unknown", not
 // "confirmed HTTP/1.1".
 func NegotiatedProtocol(resp *http.Response) string {
-	if resp == nil || resp.TLS == nil {
+	switch {
+	case resp == nil:
+		return ""
+	case resp.ProtoMajor == 3:
+		return "h3"
+	case resp.TLS == nil && resp.ProtoMajor == 2:
+		return "h2c"
+	case resp.TLS == nil:
 		return ""
 	}
 	return resp.TLS.NegotiatedProtocol
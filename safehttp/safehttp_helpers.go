@@ -2,8 +2,11 @@ package safehttp
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"github.com/baditaflorin/go-common/circuitbreaker"
 	"github.com/baditaflorin/go-common/graph"
+	"github.com/baditaflorin/go-common/hedge"
 	"net/http"
 	"net/url"
 	"os"
@@ -40,22 +43,40 @@ func NewClient(opts ...Option) *http.Client {
 	if o.withoutProxy {
 		proxyFn = nil
 	}
-	t := newBaseTransport(o, proxyFn)
-	// Mirror transport with TLS pinned to ≤ 1.2 — used only as a retry
-	// fallback when the default (TLS 1.3) handshake throws an "internal
-	// error". Some servers (e.g. older nginx + OpenSSL 3.x combos)
-	// negotiate TLS 1.3 ALPN and then send alert 80 mid-handshake; on
-	// macOS LibreSSL the same handshake succeeds, so what looks like
-	// "site is down" from Linux is actually a server-side TLS quirk.
-	// Falling back to 1.2 recovers the response in those cases.
-	t12 := t.Clone()
-	t12.TLSClientConfig = &tls.Config{MaxVersion: tls.VersionTLS12}
+	tlsCfg, rootCAs, tlsErr := clientTLSConfig(o)
 
 	// Wrap with the fleet-graph observer + TLS-fallback. No-op if
 	// GRAPH_ENABLED=false or no collector URL configured. Every outbound
 	// call from any fleet service flows through this transport, so this
 	// single line gives us fleet-wide outbound observation.
-	var rt http.RoundTripper = graph.RoundTripper(&tls12FallbackTransport{primary: t, fallback: t12})
+	var rt http.RoundTripper = newTCPTransport(o, proxyFn, tlsCfg)
+	if rootCAs != nil {
+		rt = &rootCATransport{ca: rootCAs, build: func(pool *x509.CertPool) http.RoundTripper {
+			cfg := tlsCfg.Clone()
+			cfg.RootCAs = pool
+			return newTCPTransport(o, proxyFn, cfg)
+		}}
+	}
+	if o.http3 != nil {
+		rt = newHTTP3Transport(o.http3, rt, proxyFn)
+	}
+	rt = graph.RoundTripper(rt)
+	if tlsErr != nil {
+		rt = errTransport{tlsErr}
+	}
+
+	// Upstream rate-limit headers teach the ratecoord client, attempt
+	// by attempt. Nil = no learning.
+	if o.rateLearner != nil {
+		rt = o.rateLearner.Transport(rt)
+	}
+
+	// Per-host circuit breakers — inside extrasTransport so the observer
+	// and degraded sink record the fail-fast, and fetch-cache hits
+	// bypass a tripped origin. Nil = no breaker; matches v0.15.0 chain.
+	if o.circuitBreaker != nil {
+		rt = circuitbreaker.NewTransport(rt, *o.circuitBreaker)
+	}
 
 	// If any of the auto-trace / auto-backoff / degraded-sink opt-ins
 	// were set, wrap the transport once more so those hooks run on
@@ -98,7 +119,10 @@ func NewClient(opts ...Option) *http.Client {
 	// effect. An explicit per-client WithFetchDelegate is a deliberate
 	// opt-in and still wins — only the *default* (process-wide) delegate is
 	// skipped here.
-	useDefaultFetchCache := !o.noFetchCache && !o.withoutProxy && !o.forceHTTP2
+	//
+	// A client certificate disqualifies it too: the cache fetches on its
+	// own connection and could not present the caller's identity.
+	useDefaultFetchCache := !o.noFetchCache && !o.withoutProxy && !o.forceHTTP2 && !o.h2c && o.http3 == nil && o.clientCertFile == ""
 
 	extras := &extrasTransport{
 		inner:                rt,
@@ -113,6 +137,18 @@ func NewClient(opts ...Option) *http.Client {
 	}
 	rt = extras
 
+	// Hedging duplicates slow requests below the retry layer, so each
+	// attempt may be hedged. Nil = no hedging.
+	if o.hedge != nil {
+		rt = hedge.NewTransport(rt, *o.hedge)
+	}
+
+	// Retries wrap the fleet hooks so each attempt is observed and
+	// consults the backoff coordinator. Nil = single attempt.
+	if o.retry != nil {
+		rt = newRetryTransport(rt, *o.retry)
+	}
+
 	// User-Agent injection — wraps the transport so EVERY outbound
 	// request carries the configured UA. Pre-v0.35.0 the WithUserAgent
 	// option only set the UA on redirect-follow requests via the
@@ -163,3 +199,24 @@ func NewClient(opts ...Option) *http.Client {
 	}
 	return client
 }
+
+// newTCPTransport is the guarded base transport with tlsCfg (nil for the
+// defaults), paired with its TLS 1.2 fallback.
+func newTCPTransport(o *options, proxyFn func(*http.Request) (*url.URL, error), tlsCfg *tls.Config) *tls12FallbackTransport {
+	t := newBaseTransport(o, proxyFn)
+	t.TLSClientConfig = tlsCfg
+	// Mirror transport with TLS pinned to ≤ 1.2 — used only as a retry
+	// fallback when the default (TLS 1.3) handshake throws an "internal
+	// error". Some servers (e.g. older nginx + OpenSSL 3.x combos)
+	// negotiate TLS 1.3 ALPN and then send alert 80 mid-handshake; on
+	// macOS LibreSSL the same handshake succeeds, so what looks like
+	// "site is down" from Linux is actually a server-side TLS quirk.
+	// Falling back to 1.2 recovers the response in those cases.
+	t12 := t.Clone()
+	t12.TLSClientConfig = &tls.Config{}
+	if tlsCfg != nil {
+		t12.TLSClientConfig = tlsCfg.Clone()
+	}
+	t12.TLSClientConfig.MaxVersion = tls.VersionTLS12
+	return &tls12FallbackTransport{primary: t, fallback: t12}
+}
@@ -38,3 +38,9 @@ func (t *tls12FallbackTransport) RoundTrip(req *http.Request) (*http.Response, e
 	}
 	return t.fallback.RoundTrip(req)
 }
+
+// CloseIdleConnections closes idle connections on both transports.
+func (t *tls12FallbackTransport) CloseIdleConnections() {
+	t.primary.CloseIdleConnections()
+	t.fallback.CloseIdleConnections()
+}
@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/baditaflorin/go-common/coordauth"
 	"github.com/baditaflorin/go-common/response"
 )
 
@@ -37,22 +38,36 @@ type Doer interface {
 // Client reads secrets from go-fleet-secrets.
 type Client struct {
 	baseURL string
-	apiKey  string
+	auth    coordauth.Credentials
 	http    Doer
 }
 
+// Option configures a Client.
+type Option func(*Client)
+
+// WithSigningSecret HMAC-signs every vault read with secret (see
+// coordauth), so a captured request cannot be replayed against the
+// vault. Empty leaves requests unsigned.
+func WithSigningSecret(secret string) Option {
+	return func(c *Client) { c.auth.Secret = secret }
+}
+
 // New builds a Client. baseURL is the vault root (e.g.
 // "https://fleet-secrets.0exec.com"); a trailing slash is trimmed.
 // apiKey is the caller's fleet API key — the gateway translates it into
 // the X-Auth-User principal the vault checks against each secret's
 // consumers allowlist. httpClient is the caller's configured transport
 // (see the transport note on the package doc).
-func New(baseURL, apiKey string, httpClient Doer) *Client {
-	return &Client{
+func New(baseURL, apiKey string, httpClient Doer, opts ...Option) *Client {
+	c := &Client{
 		baseURL: strings.TrimRight(baseURL, "/"),
-		apiKey:  apiKey,
+		auth:    coordauth.Credentials{Key: apiKey},
 		http:    httpClient,
 	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
 }
 
 // Get fetches a single secret's plaintext value by name, decoding the
@@ -78,8 +93,8 @@ func (c *Client) Get(ctx context.Context, name string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("secrets: build request for %q: %w", name, err)
 	}
-	if c.apiKey != "" {
-		req.Header.Set("X-API-Key", c.apiKey)
+	if err := c.auth.Sign(req, nil); err != nil {
+		return "", fmt.Errorf("secrets: sign request for %q: %w", name, err)
 	}
 	req.Header.Set("Accept", "application/json")
 
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/baditaflorin/go-common/coordauth"
 	"github.com/baditaflorin/go-common/response"
 )
 
@@ -42,6 +43,26 @@ func TestGet_WrappedEnvelope(t *testing.T) {
 	}
 }
 
+func TestGet_Signed(t *testing.T) {
+	v := coordauth.NewVerifier("test-key", "s3cret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r, nil); err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		_ = writeJSON(w, response.Success(map[string]any{"value": "tok-123"}))
+	}))
+	t.Cleanup(srv.Close)
+
+	if _, err := New(srv.URL, "test-key", srv.Client()).Get(context.Background(), "x"); err == nil {
+		t.Fatal("an unsigned read must be refused by a signing vault")
+	}
+	got, err := New(srv.URL, "test-key", srv.Client(), WithSigningSecret("s3cret")).Get(context.Background(), "x")
+	if err != nil || got != "tok-123" {
+		t.Fatalf("signed Get = %q, %v", got, err)
+	}
+}
+
 func TestGet_ErrorEnvelopeSurfaces(t *testing.T) {
 	c := newVault(t, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(403)
@@ -24,7 +24,8 @@
 //
 // Each registration appends; the final list is what /capabilities
 // returns. Use client.FetchCapabilities for the standard set
-// (use_js, use_network) — declaring them by hand drifts.
+// (use_js, use_network) — declaring them by hand drifts. Routes
+// registered with srv.Route are listed alongside, under "routes".
 
 package server
 
@@ -33,6 +34,7 @@ import (
 	"net/http"
 
 	"github.com/baditaflorin/go-common/client"
+	"github.com/baditaflorin/go-common/route"
 )
 
 // WithCapability appends one or more Capability entries to the
@@ -54,18 +56,22 @@ type capabilitiesPayload struct {
 	Version       string              `json:"version"`
 	SchemaVersion int                 `json:"schema_version"`
 	Capabilities  []client.Capability `json:"capabilities"`
+	Routes        []route.Info        `json:"routes,omitempty"`
 }
 
 // mountCapabilities wires GET /capabilities on the server's mux. Called
-// from New() after all options have been applied.
+// from New() after all options have been applied; the payload is built
+// per request so routes registered after New are included.
 func mountCapabilities(s *Server) {
-	body, _ := json.Marshal(capabilitiesPayload{
-		Service:       s.Config.AppName,
-		Version:       s.Config.Version,
-		SchemaVersion: s.SchemaVersion,
-		Capabilities:  append([]client.Capability{}, s.Capabilities...),
-	})
+	caps := append([]client.Capability{}, s.Capabilities...)
 	s.Mux.HandleFunc("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(capabilitiesPayload{
+			Service:       s.Config.AppName,
+			Version:       s.Config.Version,
+			SchemaVersion: s.SchemaVersion,
+			Capabilities:  caps,
+			Routes:        s.Routes(),
+		})
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write(body)
 	})
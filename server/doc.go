@@ -16,5 +16,11 @@
 // server.New wires the full fleet middleware stack (graph, requestID,
 // logging, body limit, metrics, promx), /health, /version, /capabilities,
 // /schema, /metrics, and /selftest default endpoints automatically.
-// Start() performs a graceful SIGTERM drain.
+// srv.Route registers a handler together with its per-route tier, gate,
+// body limit and timeout, and publishes it to OpenAPI and /capabilities;
+// server.JSON turns a typed func(ctx, In) (Out, error) into such a
+// handler, binding, validating and enveloping for it.
+// Start() performs a graceful SIGTERM drain. With WithTLS it serves HTTPS,
+// optionally mutual TLS, with certificates reloaded as they rotate;
+// WithH2C adds cleartext HTTP/2 and WithHTTP3 a pluggable QUIC listener.
 package server
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	fleetErrors "github.com/baditaflorin/go-common/errors"
+	"github.com/baditaflorin/go-common/middleware"
+	openapipkg "github.com/baditaflorin/go-common/openapi"
+	"github.com/baditaflorin/go-common/response"
+	"github.com/baditaflorin/go-common/validate"
+)
+
+// JSON adapts a typed function to an http.Handler, replacing the
+// validate.Bind → logic → response.Envelope → error-mapping sequence
+// every JSON handler repeats:
+//
+//	type GetItemIn struct {
+//	    ID      string `path:"id"`
+//	    Verbose bool   `query:"verbose"`
+//	    Tenant  string `header:"X-Tenant" validate:"required"`
+//	}
+//	type UpdateItemIn struct {
+//	    ID   string `path:"id"`
+//	    Name string `json:"name" validate:"required,max=64"` // from the body
+//	}
+//	srv.Route("GET", "/items/{id}", server.JSON(getItem), route.Opts{Summary: "Fetch one item"})
+//
+// Fields tagged `path`, `query` or `header` are bound from the
+// ServeMux wildcard, the query string or the request header of that
+// name (repeated values fill a slice); every other exported field is
+// decoded from the JSON body, where unknown keys are rejected. The
+// input is then checked against its `validate` tags. Binding and
+// validation failures answer 400 with the fleet error envelope.
+//
+// On success Out is written as response.Envelope(out,
+// CurrentSchemaVersion()) with status 200, or the status returned by
+// Out's HTTPStatus() int method when it has one. An error carrying an
+// *errors.Error answers with its status and code; a context deadline
+// answers 504; any other error is logged and answers a generic 500, so
+// internal messages never reach the caller.
+//
+// Registered through srv.Route, the handler documents itself: its
+// parameters, request body and envelope responses are derived from In
+// and Out by reflection (see openapi.SchemaOf).
+func JSON[In, Out any](fn func(ctx context.Context, in In) (Out, error)) http.Handler {
+	return &jsonHandler[In, Out]{fn: fn, plan: planInput(reflect.TypeFor[In]())}
+}
+
+type jsonHandler[In, Out any] struct {
+	fn   func(context.Context, In) (Out, error)
+	plan inputPlan
+}
+
+func (h *jsonHandler[In, Out]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var in In
+	if err := h.plan.bind(r, reflect.ValueOf(&in).Elem()); err != nil {
+		writeJSONError(w, r, err)
+		return
+	}
+	if h.plan.isStruct {
+		if verr := validate.Struct(&in); verr != nil {
+			writeJSONError(w, r, verr)
+			return
+		}
+	}
+	out, err := h.fn(r.Context(), in)
+	if err != nil {
+		writeJSONError(w, r, err)
+		return
+	}
+	status := http.StatusOK
+	if sc, ok := any(out).(interface{ HTTPStatus() int }); ok && sc.HTTPStatus() != 0 {
+		status = sc.HTTPStatus()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response.Envelope(out, CurrentSchemaVersion()))
+}
+
+// writeJSONError writes err as the fleet error envelope.
+func writeJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	fe, ok := fleetErrors.FromError(err)
+	if !ok {
+		if errors.Is(err, context.DeadlineExceeded) {
+			fe = fleetErrors.ErrTimeout
+		} else {
+			middleware.LoggerFromContext(r.Context()).Error("server.JSON handler failed", "error", err)
+			fe = fleetErrors.ErrInternal
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(fe.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(response.NewError(fe.HTTPStatus(), fe.Code, fe.Msg))
+}
+
+// inputPlan is how a JSON input type is bound, computed once per
+// handler.
+type inputPlan struct {
+	typ      reflect.Type
+	isStruct bool
+	params   []inputParam
+	body     bool // some field (or the whole non-struct input) comes from the body
+}
+
+// inputParam is a field bound from the path, query or a header.
+type inputParam struct {
+	index    []int
+	in       string // "path", "query" or "header"
+	name     string
+	jsonName string
+	typ      reflect.Type
+	required bool
+}
+
+func planInput(t reflect.Type) inputPlan {
+	p := inputPlan{typ: t, isStruct: t.Kind() == reflect.Struct}
+	if !p.isStruct {
+		p.body = true
+		return p
+	}
+	p.addFields(t, nil)
+	return p
+}
+
+func (p *inputPlan) addFields(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			p.addFields(f.Type, idx)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		bound := false
+		for _, in := range []string{"path", "query", "header"} {
+			if name := f.Tag.Get(in); name != "" {
+				p.params = append(p.params, inputParam{
+					index:    idx,
+					in:       in,
+					name:     name,
+					jsonName: jsonName,
+					typ:      f.Type,
+					required: in == "path" || hasRule(f.Tag.Get("validate"), "required"),
+				})
+				bound = true
+				break
+			}
+		}
+		if !bound && jsonName != "-" {
+			p.body = true
+		}
+	}
+}
+
+// hasRule reports whether the comma-separated validate tag holds rule.
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// bind fills v (an addressable In) from r.
+func (p *inputPlan) bind(r *http.Request, v reflect.Value) error {
+	if p.body && r.Body != nil && r.Body != http.NoBody {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v.Addr().Interface()); err != nil && !errors.Is(err, io.EOF) {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return fleetErrors.Newf(http.StatusRequestEntityTooLarge, "bad_request.too_large",
+					"request body exceeds %d bytes", tooLarge.Limit)
+			}
+			return fleetErrors.Wrap(err, http.StatusBadRequest, "bad_request.json", "JSON decode: "+err.Error())
+		}
+	}
+	var query map[string][]string
+	for _, prm := range p.params {
+		fv := v.FieldByIndex(prm.index)
+		fv.SetZero() // only the declared source may set a bound field
+		var vals []string
+		switch prm.in {
+		case "path":
+			if s := r.PathValue(prm.name); s != "" {
+				vals = []string{s}
+			}
+		case "query":
+			if query == nil {
+				query = r.URL.Query()
+			}
+			vals = query[prm.name]
+		case "header":
+			vals = r.Header.Values(prm.name)
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setParam(fv, vals); err != nil {
+			return fleetErrors.Newf(http.StatusBadRequest, "bad_request.param",
+				"%s parameter %q: %v", prm.in, prm.name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setParam converts the raw parameter values into v: every value for a
+// slice, the first otherwise.
+func setParam(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		return setParam(v.Elem(), vals)
+	}
+	if v.Kind() == reflect.Slice && !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, raw := range vals {
+			if err := setScalar(s.Index(i), raw); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setScalar(v, vals[0])
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported parameter type %s", v.Type())
+	}
+	return nil
+}
+
+// DescribeOperation implements route.Describer from In and Out.
+func (h *jsonHandler[In, Out]) DescribeOperation(op *openapipkg.Operation) {
+	for _, prm := range h.plan.params {
+		if !hasParameter(op.Parameters, prm.in, prm.name) {
+			op.Parameters = append(op.Parameters, openapipkg.Parameter{
+				Name:     prm.name,
+				In:       prm.in,
+				Required: prm.required,
+				Schema:   openapipkg.SchemaOf(prm.typ),
+			})
+		}
+	}
+	if h.plan.body && op.RequestBody == nil {
+		body := openapipkg.SchemaOf(h.plan.typ)
+		for _, prm := range h.plan.params {
+			delete(body.Properties, prm.jsonName)
+			body.Required = slices.DeleteFunc(body.Required, func(n string) bool { return n == prm.jsonName })
+		}
+		op.RequestBody = &openapipkg.RequestBody{
+			Content: map[string]openapipkg.MediaTypeObject{"application/json": {Schema: body}},
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = map[string]openapipkg.Response{}
+	}
+	status := http.StatusOK
+	var zero Out
+	if sc, ok := any(zero).(interface{ HTTPStatus() int }); ok && sc.HTTPStatus() != 0 {
+		status = sc.HTTPStatus()
+	}
+	setResponse(op, status, http.StatusText(status), envelopeSchema(openapipkg.SchemaOf(reflect.TypeFor[Out]())))
+	if len(h.plan.params) > 0 || h.plan.body {
+		setResponse(op, http.StatusBadRequest, "Invalid input (bad_request.*)", errorEnvelopeSchema())
+	}
+	setResponse(op, http.StatusInternalServerError, "Internal error", errorEnvelopeSchema())
+}
+
+func setResponse(op *openapipkg.Operation, status int, desc string, schema *openapipkg.Schema) {
+	code := strconv.Itoa(status)
+	if _, ok := op.Responses[code]; ok {
+		return
+	}
+	op.Responses[code] = openapipkg.Response{
+		Description: desc,
+		Content:     map[string]openapipkg.MediaTypeObject{"application/json": {Schema: schema}},
+	}
+}
+
+func hasParameter(params []openapipkg.Parameter, in, name string) bool {
+	for _, p := range params {
+		if p.In == in && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// envelopeSchema is the response.Envelope shape around a payload: an
+// object's properties are merged with the meta keys, anything else is
+// placed under "data".
+func envelopeSchema(payload *openapipkg.Schema) *openapipkg.Schema {
+	s := &openapipkg.Schema{Type: "object", Properties: map[string]*openapipkg.Schema{}}
+	if payload.Type == "object" && payload.Properties != nil {
+		for k, v := range payload.Properties {
+			s.Properties[k] = v
+		}
+	} else {
+		s.Properties["data"] = payload
+	}
+	s.Properties["_schema_version"] = &openapipkg.Schema{Type: "integer"}
+	s.Properties["_service"] = &openapipkg.Schema{Type: "string"}
+	s.Properties["_emitted_at"] = &openapipkg.Schema{Type: "string", Format: "date-time"}
+	return s
+}
+
+// errorEnvelopeSchema is the response.NewError shape.
+func errorEnvelopeSchema() *openapipkg.Schema {
+	return &openapipkg.Schema{Type: "object", Properties: map[string]*openapipkg.Schema{
+		"status": {Type: "string", Example: "error"},
+		"error": {Type: "object", Properties: map[string]*openapipkg.Schema{
+			"code":       {Type: "integer"},
+			"error_code": {Type: "string"},
+			"message":    {Type: "string"},
+		}},
+	}}
+}
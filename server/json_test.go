@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go-common/config"
+	fleetErrors "github.com/baditaflorin/go-common/errors"
+	"github.com/baditaflorin/go-common/openapi"
+	"github.com/baditaflorin/go-common/route"
+)
+
+type updateItemIn struct {
+	ID     string   `path:"id"`
+	Tags   []string `query:"tag"`
+	Tenant string   `header:"X-Tenant" validate:"required"`
+	Name   string   `json:"name" validate:"required"`
+	Count  int      `json:"count"`
+}
+
+type itemOut struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Tenant string   `json:"tenant"`
+	Tags   []string `json:"tags"`
+}
+
+type createdOut struct {
+	ID string `json:"id"`
+}
+
+func (createdOut) HTTPStatus() int { return http.StatusCreated }
+
+func newJSONTestServer(t *testing.T) (*httptest.Server, *Server) {
+	t.Helper()
+	cfg := &config.Config{AppName: "go_json_test", Version: "1.0.0"}
+	srv := New(cfg, WithOpenAPI(openapi.New(cfg.AppName, cfg.Version)))
+	srv.Route("PUT", "/items/{id}", JSON(func(_ context.Context, in updateItemIn) (itemOut, error) {
+		switch in.Name {
+		case "missing":
+			return itemOut{}, fleetErrors.New(http.StatusNotFound, "not_found.item", "no such item")
+		case "boom":
+			return itemOut{}, errors.New("db password rejected")
+		}
+		return itemOut{ID: in.ID, Name: in.Name, Tenant: in.Tenant, Tags: in.Tags}, nil
+	}), route.Opts{Summary: "Update one item"})
+	srv.Route("POST", "/items", JSON(func(context.Context, struct{}) (createdOut, error) {
+		return createdOut{ID: "new"}, nil
+	}), route.Opts{})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, srv
+}
+
+func doJSON(t *testing.T, method, url, body string, hdr map[string]string) (int, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var m map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		t.Fatalf("%s %s: decoding response: %v", method, url, err)
+	}
+	return resp.StatusCode, m
+}
+
+func errorCode(m map[string]any) string {
+	e, _ := m["error"].(map[string]any)
+	code, _ := e["error_code"].(string)
+	return code
+}
+
+func TestJSON_BindsAndEnvelopes(t *testing.T) {
+	ts, _ := newJSONTestServer(t)
+	tenant := map[string]string{"X-Tenant": "acme"}
+
+	status, m := doJSON(t, "PUT", ts.URL+"/items/42?tag=a&tag=b", `{"name":"widget"}`, tenant)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, m)
+	}
+	if m["id"] != "42" || m["name"] != "widget" || m["tenant"] != "acme" || m["_emitted_at"] == nil {
+		t.Fatalf("envelope = %v", m)
+	}
+	if tags, _ := m["tags"].([]any); len(tags) != 2 || tags[1] != "b" {
+		t.Fatalf("tags = %v", m["tags"])
+	}
+
+	status, m = doJSON(t, "POST", ts.URL+"/items", "", nil)
+	if status != http.StatusCreated || m["id"] != "new" {
+		t.Fatalf("HTTPStatus(): got %d %v, want 201", status, m)
+	}
+}
+
+func TestJSON_Errors(t *testing.T) {
+	ts, _ := newJSONTestServer(t)
+	tenant := map[string]string{"X-Tenant": "acme"}
+	cases := []struct {
+		name, body string
+		hdr        map[string]string
+		status     int
+		code       string
+	}{
+		{"missing header", `{"name":"x"}`, nil, 400, "bad_request.validation"},
+		{"missing body field", `{}`, tenant, 400, "bad_request.validation"},
+		{"unknown body field", `{"name":"x","nope":1}`, tenant, 400, "bad_request.json"},
+		{"bad body type", `{"name":"x","count":"many"}`, tenant, 400, "bad_request.json"},
+		{"fleet error", `{"name":"missing"}`, tenant, 404, "not_found.item"},
+		{"plain error", `{"name":"boom"}`, tenant, 500, "internal"},
+	}
+	for _, tc := range cases {
+		status, m := doJSON(t, "PUT", ts.URL+"/items/42", tc.body, tc.hdr)
+		if status != tc.status || errorCode(m) != tc.code {
+			t.Errorf("%s: got %d %q, want %d %q", tc.name, status, errorCode(m), tc.status, tc.code)
+		}
+		if strings.Contains(m["error"].(map[string]any)["message"].(string), "password") {
+			t.Errorf("%s: internal error message leaked: %v", tc.name, m)
+		}
+	}
+}
+
+func TestJSON_BadParam(t *testing.T) {
+	cfg := &config.Config{AppName: "go_json_test", Version: "1.0.0"}
+	srv := New(cfg)
+	type in struct {
+		Limit int `query:"limit"`
+	}
+	srv.Route("GET", "/list", JSON(func(_ context.Context, in in) (map[string]int, error) {
+		return map[string]int{"limit": in.Limit}, nil
+	}), route.Opts{})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status, m := doJSON(t, "GET", ts.URL+"/list?limit=ten", "", nil); status != 400 || errorCode(m) != "bad_request.param" {
+		t.Fatalf("got %d %v, want 400 bad_request.param", status, m)
+	}
+	if status, m := doJSON(t, "GET", ts.URL+"/list?limit=10", "", nil); status != 200 || m["limit"] != float64(10) {
+		t.Fatalf("got %d %v", status, m)
+	}
+}
+
+func TestJSON_DescribesOpenAPI(t *testing.T) {
+	ts, _ := newJSONTestServer(t)
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var spec openapi.Spec
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Paths["/items/{id}"].Put
+	if op == nil || op.Summary != "Update one item" {
+		t.Fatalf("PUT /items/{id} = %+v", op)
+	}
+	params := map[string]openapi.Parameter{}
+	for _, p := range op.Parameters {
+		params[p.In+":"+p.Name] = p
+	}
+	if p := params["path:id"]; !p.Required || p.Schema.Type != "string" {
+		t.Errorf("path id = %+v", p)
+	}
+	if p := params["query:tag"]; p.Required || p.Schema.Type != "array" {
+		t.Errorf("query tag = %+v", p)
+	}
+	if p := params["header:X-Tenant"]; !p.Required {
+		t.Errorf("header X-Tenant = %+v", p)
+	}
+	if op.RequestBody == nil {
+		t.Fatal("no requestBody")
+	}
+	body := op.RequestBody.Content["application/json"].Schema
+	if body.Properties["name"] == nil || body.Properties["count"].Type != "integer" {
+		t.Errorf("request body = %+v", body)
+	}
+	if _, ok := body.Properties["ID"]; ok {
+		t.Error("path-bound fields should not appear in the request body")
+	}
+	ok := op.Responses["200"].Content["application/json"].Schema
+	if ok == nil || ok.Properties["name"] == nil || ok.Properties["_emitted_at"] == nil {
+		t.Errorf("200 schema = %+v", ok)
+	}
+	for _, code := range []string{"400", "500"} {
+		if _, has := op.Responses[code]; !has {
+			t.Errorf("missing %s response", code)
+		}
+	}
+	post := spec.Paths["/items"].Post
+	if _, has := post.Responses["201"]; !has {
+		t.Error("POST /items should document its HTTPStatus() 201")
+	}
+	if _, has := post.Responses["400"]; has || post.RequestBody != nil {
+		t.Error("an input without fields should not document a body or a 400")
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// WithH2C makes Start accept cleartext HTTP/2 ("h2c", prior knowledge)
+// beside HTTP/1.1 on the plain-HTTP listener — for mesh traffic behind
+// the gateway, where TLS terminates upstream but streaming endpoints
+// such as /mcp should not queue behind HTTP/1.1 head-of-line blocking.
+// Clients opt in with safehttp.WithH2C; HTTP/1.1 clients are unaffected.
+// With WithTLS, HTTP/2 is already negotiated over TLS and this is a
+// no-op for TLS connections.
+func WithH2C() Option {
+	return func(s *Server) { s.h2c = true }
+}
+
+// HTTP3Server is an HTTP/3 (QUIC) listener that WithHTTP3 runs beside
+// the TCP one. quic-go's *http3.Server satisfies it; go-common does not
+// depend on a QUIC implementation itself.
+type HTTP3Server interface {
+	ListenAndServe() error
+	Close() error
+}
+
+// WithHTTP3 makes Start also serve HTTP/3 on the same port over UDP,
+// and advertise it to TLS clients with an Alt-Svc response header.
+// newServer builds the listener from the address, the fully-wrapped
+// handler and the server's TLS config (a clone; the QUIC side sets its
+// own ALPN):
+//
+//	server.WithHTTP3(func(addr string, h http.Handler, cfg *tls.Config) server.HTTP3Server {
+//	    return &http3.Server{Addr: addr, Handler: h, TLSConfig: http3.ConfigureTLSConfig(cfg)}
+//	})
+//
+// HTTP/3 is TLS-only, so Start returns an error unless WithTLS is also
+// applied. The HTTP/3 listener is closed when the TCP one stops; a
+// failure of the UDP listener alone is logged and leaves TCP serving.
+func WithHTTP3(newServer func(addr string, h http.Handler, tlsConfig *tls.Config) HTTP3Server) Option {
+	return func(s *Server) {
+		s.http3 = newServer
+		s.Middlewares = append(s.Middlewares, s.altSvcMiddleware)
+	}
+}
+
+// errHTTP3WithoutTLS is returned by Start for WithHTTP3 without WithTLS.
+var errHTTP3WithoutTLS = errors.New("server: WithHTTP3 requires WithTLS")
+
+// protocols is the http.Server protocol set for WithH2C, or nil for the
+// stdlib default.
+func (s *Server) protocols() *http.Protocols {
+	if !s.h2c {
+		return nil
+	}
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
+// altSvcMiddleware advertises the HTTP/3 listener on responses to TLS
+// requests; a cleartext Alt-Svc for h3 would point nowhere reachable.
+func (s *Server) altSvcMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && r.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", `h3=":`+s.Config.Port+`"; ma=86400`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startHTTP3 runs the WithHTTP3 listener for httpSrv in the background
+// and returns the function that closes it.
+func (s *Server) startHTTP3(httpSrv *http.Server) func() {
+	h3 := s.http3(httpSrv.Addr, httpSrv.Handler, httpSrv.TLSConfig.Clone())
+	go func() {
+		if err := h3.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("server: HTTP/3 listener stopped: %v", err)
+		}
+	}()
+	return func() { h3.Close() }
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/config"
+	"github.com/baditaflorin/go-common/safehttp"
+	"github.com/baditaflorin/go-common/testhelpers/testca"
+)
+
+func TestWithH2C_ServesCleartextHTTP2(t *testing.T) {
+	s := New(&config.Config{AppName: "go_h2c_test", Version: "1.0.0"}, WithH2C())
+	hs := s.buildHTTPServer("", s.Handler())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go hs.Serve(ln)
+	t.Cleanup(func() { hs.Close() })
+
+	safehttp.SetAllowedPrivateIPs([]net.IP{net.ParseIP("127.0.0.1")})
+	t.Cleanup(func() { safehttp.SetAllowedPrivateIPs(nil) })
+
+	resp, err := safehttp.NewClient(safehttp.WithH2C()).Get("http://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if p := safehttp.NegotiatedProtocol(resp); p != "h2c" {
+		t.Fatalf("NegotiatedProtocol = %q, want h2c", p)
+	}
+
+	// HTTP/1.1 clients keep working against the same listener.
+	resp, err = http.Get("http://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 {
+		t.Fatalf("plain client got %s", resp.Proto)
+	}
+
+	if New(&config.Config{AppName: "go_h2c_test"}).buildHTTPServer("", nil).Protocols != nil {
+		t.Fatal("without WithH2C the stdlib protocol default should be kept")
+	}
+}
+
+// fakeHTTP3 records what WithHTTP3 handed it.
+type fakeHTTP3 struct {
+	addr    string
+	handler http.Handler
+	tls     *tls.Config
+	served  chan struct{}
+	closed  chan struct{}
+}
+
+func (f *fakeHTTP3) ListenAndServe() error {
+	close(f.served)
+	<-f.closed
+	return http.ErrServerClosed
+}
+
+func (f *fakeHTTP3) Close() error {
+	close(f.closed)
+	return nil
+}
+
+func TestWithHTTP3_RunsListenerAndAdvertises(t *testing.T) {
+	ca := testca.New(t)
+	certFile, keyFile := ca.Issue(t, "").Write(t, t.TempDir(), "server")
+	h3 := &fakeHTTP3{served: make(chan struct{}), closed: make(chan struct{})}
+	s := New(&config.Config{AppName: "go_h3_test", Version: "1.0.0", Port: "8443"},
+		WithTLS(TLSOptions{CertFile: certFile, KeyFile: keyFile}),
+		WithHTTP3(func(addr string, h http.Handler, cfg *tls.Config) HTTP3Server {
+			h3.addr, h3.handler, h3.tls = addr, h, cfg
+			return h3
+		}))
+
+	cfg, err := s.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := s.buildHTTPServer(":8443", s.Handler())
+	hs.TLSConfig = cfg
+	stop := s.startHTTP3(hs)
+	select {
+	case <-h3.served:
+	case <-time.After(time.Second):
+		t.Fatal("the HTTP/3 listener was not started")
+	}
+	if h3.addr != ":8443" || h3.handler == nil || h3.tls == nil || h3.tls == cfg {
+		t.Fatalf("listener got addr %q, handler %v, tls %p (server's %p); want the address, handler and a cloned config",
+			h3.addr, h3.handler != nil, h3.tls, cfg)
+	}
+	stop()
+	select {
+	case <-h3.closed:
+	default:
+		t.Fatal("stop should close the HTTP/3 listener")
+	}
+
+	// TLS responses carry Alt-Svc for the same port.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go hs.ServeTLS(ln, "", "")
+	t.Cleanup(func() { hs.Close() })
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.Pool()}}}
+	resp, err := c.Get("https://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Alt-Svc"); got != `h3=":8443"; ma=86400` {
+		t.Fatalf("Alt-Svc = %q", got)
+	}
+}
+
+func TestWithHTTP3_RequiresTLS(t *testing.T) {
+	s := New(&config.Config{AppName: "go_h3_test", Version: "1.0.0"},
+		WithHTTP3(func(string, http.Handler, *tls.Config) HTTP3Server { return nil }))
+	if err := s.Start(); !errors.Is(err, errHTTP3WithoutTLS) {
+		t.Fatalf("err = %v, want errHTTP3WithoutTLS", err)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/baditaflorin/go-common/middleware"
+	"github.com/baditaflorin/go-common/route"
+)
+
+// registeredRoute is one srv.Route registration.
+type registeredRoute struct {
+	pattern route.Pattern
+	opts    route.Opts
+}
+
+// Route registers h on s.Mux for method and pattern (a Go 1.22 ServeMux
+// pattern; method may be "" when the pattern carries it, or to match
+// every method), wrapped in the per-route middleware o describes. The
+// route is also:
+//
+//   - published in the WithOpenAPI spec, with its path parameters and
+//     the error responses its middleware can produce (routes without a
+//     method have no OpenAPI operation to publish);
+//   - labelled by its pattern, not the raw path, in the http_requests_*
+//     Prometheus series;
+//   - listed under "routes" on GET /capabilities.
+//
+// Like http.ServeMux.Handle, Route panics on an invalid or conflicting
+// pattern — a registration bug, caught at startup.
+//
+//	srv.Route("GET", "/items/{id}", http.HandlerFunc(getItem), route.Opts{
+//	    Summary: "Fetch one item",
+//	    Tier:    "pro",
+//	    Timeout: 5 * time.Second,
+//	})
+func (s *Server) Route(method, pattern string, h http.Handler, o route.Opts) {
+	p, err := route.ParsePattern(method, pattern)
+	if err != nil {
+		panic("server: " + err.Error())
+	}
+	s.Mux.Handle(p.String(), route.Handler(h, o))
+
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+	if s.routes == nil {
+		s.routes = make(map[string]*registeredRoute)
+	}
+	s.routes[p.String()] = &registeredRoute{pattern: p, opts: o}
+	s.routeInfo = append(s.routeInfo, route.NewInfo(p, o))
+	if s.openapi != nil && p.Method != "" {
+		s.openapi.AddRoute(p.Method, p.OpenAPIPath(), route.OpenAPIOperation(p, o, h))
+		s.openapiJSON = nil
+	}
+	s.hasRoutes.Store(true)
+}
+
+// routeFor returns the srv.Route registration r will be dispatched to,
+// or nil when it goes to a handler registered on s.Mux directly.
+func (s *Server) routeFor(r *http.Request) *registeredRoute {
+	if !s.hasRoutes.Load() {
+		return nil
+	}
+	_, pattern := s.Mux.Handler(r)
+	s.routesMu.RLock()
+	defer s.routesMu.RUnlock()
+	return s.routes[pattern]
+}
+
+// routeLabel is the Prometheus route label: the pattern, without its
+// method, for srv.Route routes, and the raw path — the promx default —
+// for everything else, so existing series keep their labels.
+func (s *Server) routeLabel(r *http.Request) string {
+	if rt := s.routeFor(r); rt != nil {
+		return rt.pattern.Host + rt.pattern.Path
+	}
+	return r.URL.Path
+}
+
+// routeBodyLimit is the server-wide body limit, except on a srv.Route
+// route with its own MaxBodyBytes, whose handler applies that instead.
+func (s *Server) routeBodyLimit() middleware.Middleware {
+	global := bodyLimitMiddleware(s.maxBodyBytes)
+	return func(next http.Handler) http.Handler {
+		limited := global(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rt := s.routeFor(r); rt != nil && rt.opts.MaxBodyBytes > 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Routes returns the srv.Route registrations, in registration order.
+func (s *Server) Routes() []route.Info {
+	s.routesMu.RLock()
+	defer s.routesMu.RUnlock()
+	return append([]route.Info(nil), s.routeInfo...)
+}
+
+// openAPIJSON returns the WithOpenAPI spec serialised, re-encoding it
+// only after Route has changed it.
+func (s *Server) openAPIJSON() ([]byte, error) {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+	if s.openapiJSON == nil {
+		data, err := s.openapi.JSON()
+		if err != nil {
+			return nil, err
+		}
+		s.openapiJSON = data
+	}
+	return s.openapiJSON, nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go-common/config"
+	"github.com/baditaflorin/go-common/openapi"
+	"github.com/baditaflorin/go-common/route"
+)
+
+func TestRoute_RegistersEverywhere(t *testing.T) {
+	cfg := &config.Config{AppName: "go_route_test", Version: "1.0.0"}
+	srv := New(cfg, WithOpenAPI(openapi.New(cfg.AppName, cfg.Version)), WithMaxBodyBytes(8))
+	srv.Route("GET", "/items/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.PathValue("id"))
+	}), route.Opts{Summary: "Fetch one item", Tags: []string{"items"}})
+	srv.Route("", "POST /uploads", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}), route.Opts{MaxBodyBytes: 1 << 10})
+	srv.Mux.HandleFunc("POST /legacy", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if got := get("/items/42"); got != "42" {
+		t.Fatalf("GET /items/42 = %q", got)
+	}
+
+	var spec openapi.Spec
+	json.Unmarshal([]byte(get("/openapi.json")), &spec)
+	op := spec.Paths["/items/{id}"].Get
+	if op == nil || op.Summary != "Fetch one item" || len(op.Parameters) != 1 || op.Parameters[0].In != "path" {
+		t.Fatalf("openapi operation = %+v", op)
+	}
+	if spec.Paths["/uploads"].Post == nil {
+		t.Fatal("POST /uploads missing from the spec")
+	}
+
+	var caps capabilitiesPayload
+	json.Unmarshal([]byte(get("/capabilities")), &caps)
+	if len(caps.Routes) != 2 || caps.Routes[0].Pattern != "/items/{id}" || caps.Routes[1].MaxBodyBytes != 1<<10 {
+		t.Fatalf("capabilities routes = %+v", caps.Routes)
+	}
+
+	// The route's own body limit replaces the 8-byte server cap; plain
+	// mux handlers keep the server cap.
+	body := strings.Repeat("x", 100)
+	for path, want := range map[string]int{"/uploads": http.StatusOK, "/legacy": http.StatusRequestEntityTooLarge} {
+		resp, err := http.Post(ts.URL+path, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST %s: %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestRoute_PrometheusLabel(t *testing.T) {
+	srv := New(&config.Config{AppName: "go_route_test", Version: "1.0.0"})
+	srv.Mux.HandleFunc("/raw/{id}", func(http.ResponseWriter, *http.Request) {})
+	if got := srv.routeLabel(httptest.NewRequest("GET", "/raw/1", nil)); got != "/raw/1" {
+		t.Fatalf("before any Route: label %q, want the raw path", got)
+	}
+	srv.Route("DELETE", "/items/{id}", http.NotFoundHandler(), route.Opts{})
+	if got := srv.routeLabel(httptest.NewRequest("DELETE", "/items/7", nil)); got != "/items/{id}" {
+		t.Fatalf("label %q, want the pattern", got)
+	}
+	if got := srv.routeLabel(httptest.NewRequest("GET", "/raw/1", nil)); got != "/raw/1" {
+		t.Fatalf("mux handler label %q, want the raw path", got)
+	}
+}
+
+func TestRoute_PanicsOnBadPattern(t *testing.T) {
+	srv := New(&config.Config{AppName: "go_route_test", Version: "1.0.0"})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a conflicting method should panic")
+		}
+	}()
+	srv.Route("GET", "POST /x", http.NotFoundHandler(), route.Opts{})
+}
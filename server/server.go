@@ -199,11 +199,11 @@ func New(cfg *config.Config, opts ...Option) *Server {
 		middleware.Logging,
 	)
 	if srv.maxBodyBytes > 0 {
-		defaultMWs = append(defaultMWs, bodyLimitMiddleware(srv.maxBodyBytes))
+		defaultMWs = append(defaultMWs, srv.routeBodyLimit())
 	}
 	defaultMWs = append(defaultMWs,
 		middleware.Metrics(stats),
-		httpColl.Middleware(),
+		httpColl.WithRouteFunc(srv.routeLabel).Middleware(),
 	)
 	srv.Middlewares = append(defaultMWs, srv.Middlewares...)
 
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,12 +12,16 @@ import (
 	"github.com/baditaflorin/go-common/depcheck"
 	"github.com/baditaflorin/go-common/metrics"
 	"github.com/baditaflorin/go-common/middleware"
+	openapipkg "github.com/baditaflorin/go-common/openapi"
 	"github.com/baditaflorin/go-common/promx"
+	"github.com/baditaflorin/go-common/route"
 	"github.com/baditaflorin/go-common/safehttp"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -94,6 +99,28 @@ type Server struct {
 	// that serves GET /agent.json. Zero value if neither option was used.
 	AgentContract agent.Contract
 
+	// tls is set by WithTLS; nil serves plain HTTP. Start loads the
+	// files through TLSConfig.
+	tls *TLSOptions
+
+	// h2c is set by WithH2C: the plain listener also accepts cleartext
+	// HTTP/2. http3 is the WithHTTP3 listener constructor, nil without.
+	h2c   bool
+	http3 func(addr string, h http.Handler, tlsConfig *tls.Config) HTTP3Server
+
+	// openapi is the WithOpenAPI spec, nil without; srv.Route adds its
+	// operations. openapiJSON caches its encoding until the next Route.
+	openapi     *openapipkg.Spec
+	openapiJSON []byte
+
+	// routes indexes srv.Route registrations by ServeMux pattern, and
+	// routeInfo lists them for /capabilities. hasRoutes lets the
+	// per-request lookups skip the mux match on servers without any.
+	routesMu  sync.RWMutex
+	routes    map[string]*registeredRoute
+	routeInfo []route.Info
+	hasRoutes atomic.Bool
+
 	// mcpEnabled is set by WithMCP; the /mcp mount happens in New(),
 	// after every option has run, so WithAgent/WithAgentFromEmbed and
 	// WithMCP can be passed in either order.
@@ -123,6 +150,7 @@ func (s *Server) buildHTTPServer(addr string, h http.Handler) *http.Server {
 		ReadTimeout:  resolveTimeout(s.readTimeout, "SERVER_READ_TIMEOUT_SECONDS", DefaultReadTimeout),
 		WriteTimeout: resolveTimeout(s.writeTimeout, "SERVER_WRITE_TIMEOUT_SECONDS", DefaultWriteTimeout),
 		IdleTimeout:  resolveTimeout(s.idleTimeout, "SERVER_IDLE_TIMEOUT_SECONDS", DefaultIdleTimeout),
+		Protocols:    s.protocols(),
 	}
 }
 
@@ -142,6 +170,9 @@ func (s *Server) Start() error {
 	finalHandler := s.wrapDefaults(middleware.Chain(s.Mux, s.Middlewares...))
 
 	httpSrv := s.buildHTTPServer(addr, finalHandler)
+	if err := s.prepareTLS(httpSrv); err != nil {
+		return fmt.Errorf("server: tls: %w", err)
+	}
 
 	if s.drain != nil {
 		// WithGracefulDrain path: wire the shutdown closure so BeginDrain
@@ -151,7 +182,7 @@ func (s *Server) Start() error {
 		stopSignals := s.installSignalHandler()
 		defer stopSignals()
 
-		err := httpSrv.ListenAndServe()
+		err := s.listenAndServe(httpSrv)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("server: ListenAndServe error: %v", err)
 		}
@@ -167,7 +198,7 @@ func (s *Server) Start() error {
 
 	errCh := make(chan error, 1)
 	go func() {
-		if err := httpSrv.ListenAndServe(); err != nil {
+		if err := s.listenAndServe(httpSrv); err != nil {
 			errCh <- err
 		}
 	}()
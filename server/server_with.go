@@ -202,9 +202,10 @@ func WithDependencies(r *depcheck.Registry) Option {
 }
 
 // WithOpenAPI registers a GET /openapi.json handler that serves spec as JSON.
-// The spec is serialised once at startup — mutations to spec after this call
-// are not reflected.  Build the spec with openapi.New() and optionally enrich
-// it with openapi.ScanDir() before passing it here.
+// Operations registered afterwards with srv.Route are added to spec and
+// served; other mutations to spec after this call are not reflected.
+// Build the spec with openapi.New() and optionally enrich it with
+// openapi.ScanDir() before passing it here.
 //
 //	spec := openapi.New(cfg.AppName, cfg.Version)
 //	srv := server.New(cfg, server.WithOpenAPI(spec))
@@ -213,12 +214,17 @@ func WithDependencies(r *depcheck.Registry) Option {
 // included by openapi.New() — services do not need to add them manually.
 func WithOpenAPI(spec *openapipkg.Spec) Option {
 	return func(s *Server) {
-		data, err := spec.JSON()
-		if err != nil {
+		s.openapi = spec
+		if _, err := s.openAPIJSON(); err != nil {
 			// spec is invalid JSON — panic early rather than serve garbage.
 			panic("openapi spec serialization failed: " + err.Error())
 		}
 		s.Mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+			data, err := s.openAPIJSON()
+			if err != nil {
+				http.Error(w, "openapi spec serialization failed", http.StatusInternalServerError)
+				return
+			}
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write(data) //nolint:errcheck // client disconnect is not actionable
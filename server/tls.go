@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/baditaflorin/go-common/certreload"
+	"github.com/baditaflorin/go-common/middleware"
+)
+
+// TLSOptions configures WithTLS.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM server certificate (chain) and
+	// private key. Required.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile, when set, turns on mutual TLS: clients must present
+	// a certificate that chains to a CA in this PEM bundle, and the
+	// verified identity is available to handlers via
+	// PeerIdentityFromContext.
+	ClientCAFile string
+
+	// ClientCertOptional relaxes mTLS to "verify if given": a client
+	// without a certificate is still served (with no PeerIdentity), one
+	// with a bad certificate is still refused. Use while migrating a
+	// service's callers, or when probes without certificates must keep
+	// reaching /health.
+	ClientCertOptional bool
+
+	// ReloadInterval is how often the certificate, key and CA files are
+	// checked for change. Default certreload.DefaultInterval.
+	ReloadInterval time.Duration
+}
+
+// WithTLS makes Start serve HTTPS (HTTP/2 and HTTP/1.1) instead of
+// plain HTTP, on the same PORT. The certificate and key — and the
+// client CA bundle with mTLS — are re-read when their files change, so
+// a rotated certificate takes effect on the next handshake without a
+// restart. Files that fail to load make Start return an error.
+//
+//	srv := server.New(cfg, server.WithTLS(server.TLSOptions{
+//	    CertFile:     "/run/tls/tls.crt",
+//	    KeyFile:      "/run/tls/tls.key",
+//	    ClientCAFile: "/run/tls/ca.crt", // optional: mTLS
+//	}))
+func WithTLS(o TLSOptions) Option {
+	return func(s *Server) {
+		s.tls = &o
+		s.Middlewares = append(s.Middlewares, peerIdentityMiddleware)
+	}
+}
+
+// TLSConfig builds the *tls.Config Start serves with, or returns nil
+// when WithTLS was not applied. Exposed for callers that run their own
+// listener (or httptest.NewUnstartedServer) around Handler(). Each call
+// loads the files afresh.
+func (s *Server) TLSConfig() (*tls.Config, error) {
+	if s.tls == nil {
+		return nil, nil
+	}
+	kp, err := certreload.LoadKeyPair(s.tls.CertFile, s.tls.KeyFile, s.tls.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: kp.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if s.tls.ClientCAFile == "" {
+		return base, nil
+	}
+	ca, err := certreload.LoadCAPool(s.tls.ClientCAFile, s.tls.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	base.ClientAuth = tls.RequireAndVerifyClientCert
+	if s.tls.ClientCertOptional {
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	base.ClientCAs = ca.Pool()
+	// ClientCAs is read per handshake from the config this returns, so
+	// a reloaded bundle applies to the next connection.
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.ClientCAs = ca.Pool()
+		return c, nil
+	}
+	return base, nil
+}
+
+// prepareTLS loads the WithTLS files into httpSrv. Start calls it before
+// choosing a shutdown path, so a bad certificate or key is an error from
+// Start on every path.
+func (s *Server) prepareTLS(httpSrv *http.Server) error {
+	if s.tls == nil {
+		if s.http3 != nil {
+			return errHTTP3WithoutTLS
+		}
+		return nil
+	}
+	cfg, err := s.TLSConfig()
+	if err != nil {
+		return err
+	}
+	httpSrv.TLSConfig = cfg
+	return nil
+}
+
+// listenAndServe serves httpSrv over TLS when WithTLS was applied, and
+// plain HTTP otherwise, with the WithHTTP3 listener alongside. prepareTLS
+// must have succeeded first.
+func (s *Server) listenAndServe(httpSrv *http.Server) error {
+	if s.tls == nil {
+		return httpSrv.ListenAndServe()
+	}
+	if s.http3 != nil {
+		defer s.startHTTP3(httpSrv)()
+	}
+	return httpSrv.ListenAndServeTLS("", "")
+}
+
+// PeerIdentity is the verified identity of an mTLS client.
+type PeerIdentity struct {
+	// SPIFFEID is the leaf certificate's spiffe:// URI SAN
+	// ("spiffe://<trust-domain>/<path>"), or "" when it carries none.
+	SPIFFEID string
+	// CommonName is the leaf certificate's subject CN.
+	CommonName string
+	// Certificate is the verified leaf certificate.
+	Certificate *x509.Certificate
+}
+
+type peerIdentityKey struct{}
+
+// PeerIdentityFromContext returns the identity of the mTLS client that
+// sent the request. ok is false without mTLS, or when the client sent
+// no certificate under TLSOptions.ClientCertOptional.
+func PeerIdentityFromContext(ctx context.Context) (PeerIdentity, bool) {
+	id, ok := ctx.Value(peerIdentityKey{}).(PeerIdentity)
+	return id, ok
+}
+
+// peerIdentityMiddleware records the verified client certificate's
+// identity in the request context. Only a verified chain counts.
+var peerIdentityMiddleware middleware.Middleware = func(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, err := peerIdentity(r); err == nil {
+			r = r.WithContext(context.WithValue(r.Context(), peerIdentityKey{}, id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func peerIdentity(r *http.Request) (PeerIdentity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return PeerIdentity{}, errors.New("no verified client certificate")
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	id := PeerIdentity{CommonName: leaf.Subject.CommonName, Certificate: leaf}
+	for _, u := range leaf.URIs {
+		if u.Scheme == "spiffe" {
+			id.SPIFFEID = u.String()
+			break
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,159 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go-common/config"
+	"github.com/baditaflorin/go-common/safehttp"
+	"github.com/baditaflorin/go-common/server"
+	"github.com/baditaflorin/go-common/testhelpers/testca"
+)
+
+// startTLS serves srv with its own TLSConfig through http.Server.ServeTLS,
+// as Start does, and returns the base URL.
+func startTLS(t *testing.T, srv *server.Server) string {
+	t.Helper()
+	cfg, err := srv.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := &http.Server{Handler: srv.Handler(), TLSConfig: cfg}
+	go hs.ServeTLS(ln, "", "")
+	t.Cleanup(func() { hs.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func whoami(w http.ResponseWriter, r *http.Request) {
+	id, ok := server.PeerIdentityFromContext(r.Context())
+	if !ok {
+		io.WriteString(w, "anonymous")
+		return
+	}
+	io.WriteString(w, id.SPIFFEID)
+}
+
+func TestWithTLS_MutualTLSWithSafehttpClient(t *testing.T) {
+	ca := testca.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.Issue(t, "spiffe://fleet.test/svc/api").Write(t, dir, "server")
+	clientCert, clientKey := ca.Issue(t, "spiffe://fleet.test/svc/caller").Write(t, dir, "client")
+	bundle := ca.WriteBundle(t, dir)
+
+	srv := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0"},
+		server.WithTLS(server.TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: bundle}))
+	srv.Mux.HandleFunc("/whoami", whoami)
+	base := startTLS(t, srv)
+
+	safehttp.SetAllowedPrivateIPs([]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")})
+	t.Cleanup(func() { safehttp.SetAllowedPrivateIPs(nil) })
+
+	c := safehttp.NewClient(safehttp.WithClientCert(clientCert, clientKey), safehttp.WithRootCAs(bundle), safehttp.WithForceHTTP2())
+	resp, err := c.Get(base + "/whoami")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "spiffe://fleet.test/svc/caller" {
+		t.Fatalf("identity = %q", body)
+	}
+	if p := safehttp.NegotiatedProtocol(resp); p != "h2" {
+		t.Fatalf("negotiated %q, want h2", p)
+	}
+
+	// Without a client certificate the handshake is refused.
+	anon := safehttp.NewClient(safehttp.WithRootCAs(bundle))
+	if resp, err := anon.Get(base + "/whoami"); err == nil {
+		resp.Body.Close()
+		t.Fatal("a client without a certificate should be refused")
+	}
+
+	// A certificate from another CA is refused too.
+	rogueCert, rogueKey := testca.New(t).Issue(t, "spiffe://evil.test/x").Write(t, dir, "rogue")
+	rogue := safehttp.NewClient(safehttp.WithClientCert(rogueCert, rogueKey), safehttp.WithRootCAs(bundle))
+	if resp, err := rogue.Get(base + "/whoami"); err == nil {
+		resp.Body.Close()
+		t.Fatal("a certificate from an untrusted CA should be refused")
+	}
+}
+
+func TestWithTLS_ClientCertOptional(t *testing.T) {
+	ca := testca.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.Issue(t, "").Write(t, dir, "server")
+	srv := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0"},
+		server.WithTLS(server.TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.WriteBundle(t, dir), ClientCertOptional: true}))
+	srv.Mux.HandleFunc("/whoami", whoami)
+	base := startTLS(t, srv)
+
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.Pool()}}}
+	resp, err := c.Get(base + "/whoami")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "anonymous" {
+		t.Fatalf("body = %q, want anonymous", body)
+	}
+}
+
+func TestWithTLS_ReloadsRotatedCertificate(t *testing.T) {
+	ca := testca.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.Issue(t, "spiffe://fleet.test/v1").Write(t, dir, "server")
+	srv := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0"},
+		server.WithTLS(server.TLSOptions{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Millisecond}))
+	base := startTLS(t, srv)
+
+	leafURI := func() string {
+		t.Helper()
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.Pool()}, DisableKeepAlives: true}
+		resp, err := (&http.Client{Transport: tr}).Get(base + "/health")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].URIs[0].String()
+	}
+	if got := leafURI(); got != "spiffe://fleet.test/v1" {
+		t.Fatalf("leaf = %q", got)
+	}
+	ca.Issue(t, "spiffe://fleet.test/v2").Write(t, dir, "server")
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(certFile, future, future)
+	time.Sleep(5 * time.Millisecond)
+	if got := leafURI(); got != "spiffe://fleet.test/v2" {
+		t.Fatalf("after rotation leaf = %q", got)
+	}
+}
+
+func TestWithTLS_BadFiles(t *testing.T) {
+	srv := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0"},
+		server.WithTLS(server.TLSOptions{CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"}))
+	if _, err := srv.TLSConfig(); err == nil {
+		t.Fatal("missing files should fail TLSConfig")
+	}
+	// Start returns the load error on the drain path too, instead of
+	// exiting the process.
+	drain := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0", Port: "0"},
+		server.WithTLS(server.TLSOptions{CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"}),
+		server.WithGracefulDrain(time.Millisecond, time.Second))
+	if err := drain.Start(); err == nil {
+		t.Fatal("missing files should fail Start with WithGracefulDrain")
+	}
+	plain := server.New(&config.Config{AppName: "go_tls_test", Version: "1.0.0"})
+	if cfg, err := plain.TLSConfig(); cfg != nil || err != nil {
+		t.Fatalf("without WithTLS: %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package testca mints throwaway certificate authorities and leaf
+// certificates in-process for TLS and mTLS tests, so no test needs
+// checked-in key material or an openssl step.
+//
+// Usage:
+//
+//	ca := testca.New(t)
+//	srvPair := ca.Issue(t, "spiffe://fleet.test/ns/prod/sa/api")
+//	certFile, keyFile := srvPair.Write(t, t.TempDir(), "server")
+//	bundle := ca.WriteBundle(t, t.TempDir())
+//
+// Every leaf is valid for localhost, 127.0.0.1 and ::1, for both server
+// and client auth, so one pair can stand in for either end.
+package testca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// CA is an in-memory certificate authority.
+type CA struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// Pair is a PEM-encoded leaf certificate and its private key.
+type Pair struct {
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// New returns a fresh self-signed CA valid for one day.
+func New(t testing.TB) *CA {
+	t.Helper()
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial(t),
+		Subject:               pkix.Name{CommonName: "testca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("testca: create CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("testca: parse CA: %v", err)
+	}
+	return &CA{Cert: cert, CertPEM: pemBlock("CERTIFICATE", der), key: key}
+}
+
+// Pool returns a pool trusting only this CA.
+func (ca *CA) Pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.Cert)
+	return p
+}
+
+// Issue signs a leaf certificate. A non-empty spiffeID ("spiffe://...")
+// becomes a URI SAN and the subject common name.
+func (ca *CA) Issue(t testing.TB, spiffeID string) Pair {
+	t.Helper()
+	return ca.issue(t, spiffeID, []string{"localhost", "127.0.0.1", "::1"})
+}
+
+// IssueFor signs a leaf certificate valid only for hosts — DNS names or
+// IP literals — for tests that need a certificate the dialed host does
+// not match.
+func (ca *CA) IssueFor(t testing.TB, hosts ...string) Pair {
+	t.Helper()
+	return ca.issue(t, "", hosts)
+}
+
+func (ca *CA) issue(t testing.TB, spiffeID string, hosts []string) Pair {
+	t.Helper()
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial(t),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	if len(hosts) > 0 {
+		tmpl.Subject.CommonName = hosts[0]
+	}
+	if spiffeID != "" {
+		u, err := url.Parse(spiffeID)
+		if err != nil {
+			t.Fatalf("testca: spiffe id: %v", err)
+		}
+		tmpl.URIs = []*url.URL{u}
+		tmpl.Subject.CommonName = spiffeID
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("testca: issue: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("testca: marshal key: %v", err)
+	}
+	return Pair{CertPEM: pemBlock("CERTIFICATE", der), KeyPEM: pemBlock("PRIVATE KEY", keyDER)}
+}
+
+// Write stores the pair as <name>.crt and <name>.key in dir and returns
+// the two paths. Writing again over the same name rotates the files.
+func (p Pair) Write(t testing.TB, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	writeFile(t, certFile, p.CertPEM)
+	writeFile(t, keyFile, p.KeyPEM)
+	return certFile, keyFile
+}
+
+// WriteBundle stores the CA certificate as ca.crt in dir and returns
+// its path.
+func (ca *CA) WriteBundle(t testing.TB, dir string) string {
+	t.Helper()
+	f := filepath.Join(dir, "ca.crt")
+	writeFile(t, f, ca.CertPEM)
+	return f
+}
+
+func writeFile(t testing.TB, path string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("testca: write %s: %v", path, err)
+	}
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("testca: generate key: %v", err)
+	}
+	return key
+}
+
+func serial(t testing.TB) *big.Int {
+	t.Helper()
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		t.Fatalf("testca: serial: %v", err)
+	}
+	return n
+}
+
+func pemBlock(typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
@@ -9,4 +9,10 @@
 //	    pool.Submit(func() { process(item) })
 //	}
 //	pool.Wait()
+//
+// Tasks that return values or errors go through Map (results in input
+// order) or a Group (errgroup-style); the first error cancels the rest
+// and a panic comes back as a *PanicError carrying the stack:
+//
+//	titles, err := workpool.Map(ctx, pool, urls, fetchTitle)
 package workpool
@@ -0,0 +1,140 @@
+package workpool
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is a task panic recovered by Group or Map. Value is what
+// was passed to panic; Stack is the panicking goroutine's stack.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workpool: task panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap exposes the panic value when it is itself an error, so
+// errors.Is / errors.As see through a panic(err).
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Group runs error-returning tasks on a Pool with errgroup semantics:
+// the first task to fail cancels the Group's context, so tasks that
+// honor it stop early and tasks not yet started are never run; Wait
+// returns that first error. A panicking task fails the Group with a
+// *PanicError instead of crashing the process.
+//
+// A Group is single-use: call Go any number of times, then Wait once.
+type Group struct {
+	pool   *Pool
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// Group returns a Group bound to p and the context its tasks receive,
+// derived from ctx.
+func (p *Pool) Group(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{pool: p, ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go blocks until the pool has a free slot, then runs task in it. Once
+// the Group has failed (or its parent context ended) Go returns
+// without running task; a skip caused by the parent context fails the
+// Group with that context's error, as does an error submitting to the
+// pool (ErrClosed).
+func (g *Group) Go(task func(ctx context.Context) error) {
+	if g.ctx.Err() != nil {
+		g.fail(context.Cause(g.ctx))
+		return
+	}
+	g.wg.Add(1)
+	err := g.pool.SubmitCtx(g.ctx, func() {
+		defer g.wg.Done()
+		if g.ctx.Err() != nil {
+			// Failed while we waited for the slot.
+			g.fail(context.Cause(g.ctx))
+			return
+		}
+		if err := g.pool.call(g.ctx, task); err != nil {
+			g.fail(err)
+		}
+	})
+	if err != nil {
+		g.wg.Done()
+		if g.ctx.Err() != nil {
+			err = context.Cause(g.ctx)
+		}
+		g.fail(err)
+	}
+}
+
+// Wait blocks until every started task has returned, then returns the
+// first error: a task's error, a *PanicError, or the reason a task was
+// skipped.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
+
+func (g *Group) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel(err)
+	})
+}
+
+// call runs task, converting a panic into a *PanicError and reporting
+// it as PhasePanicked.
+func (p *Pool) call(ctx context.Context, task func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+			p.mu.Lock()
+			ev := p.eventLocked(PhasePanicked, "")
+			p.mu.Unlock()
+			ev.Panic = v
+			emit(ev)
+		}
+	}()
+	return task(ctx)
+}
+
+// Map applies fn to every element of in on pool p and returns the
+// results in input order. The first error (or recovered panic) cancels
+// the context passed to the remaining calls and is returned; results
+// for elements that did not complete are left as R's zero value.
+//
+//	pool := workpool.New("resolver", 16)
+//	ips, err := workpool.Map(ctx, pool, hosts, func(ctx context.Context, h string) ([]net.IP, error) {
+//	    return resolver.LookupIP(ctx, "ip", h)
+//	})
+func Map[T, R any](ctx context.Context, p *Pool, in []T, fn func(context.Context, T) (R, error)) ([]R, error) {
+	out := make([]R, len(in))
+	g, _ := p.Group(ctx)
+	for i, v := range in {
+		g.Go(func(ctx context.Context) error {
+			r, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			out[i] = r
+			return nil
+		})
+	}
+	return out, g.Wait()
+}
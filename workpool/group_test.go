@@ -0,0 +1,119 @@
+package workpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapPreservesOrder(t *testing.T) {
+	p := New("map", 3)
+	in := []int{5, 1, 4, 2, 3}
+	out, err := Map(context.Background(), p, in, func(_ context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(n) * time.Millisecond) // finish out of order
+		return n * 10, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range in {
+		if out[i] != n*10 {
+			t.Fatalf("out = %v, want input order preserved", out)
+		}
+	}
+}
+
+func TestGroupFirstErrorCancelsRest(t *testing.T) {
+	p := New("errgroup", 1)
+	boom := errors.New("boom")
+	var ran atomic.Int32
+	g, ctx := p.Group(context.Background())
+	g.Go(func(context.Context) error { ran.Add(1); return boom })
+	for i := 0; i < 5; i++ {
+		g.Go(func(context.Context) error { ran.Add(1); return nil })
+	}
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait = %v, want boom", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("group context must be cancelled after the first error")
+	}
+	if got := ran.Load(); got != 1 {
+		t.Fatalf("%d tasks ran; tasks submitted after the failure must be skipped", got)
+	}
+}
+
+func TestGroupParentCancelSkipsWork(t *testing.T) {
+	p := New("parent", 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Map(ctx, p, []int{1, 2}, func(context.Context, int) (int, error) {
+		t.Error("task must not run under a cancelled parent")
+		return 0, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestGroupRecoversPanic(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	SetDefaultObserver(observerFunc(func(ev Event) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	defer SetDefaultObserver(nil)
+
+	p := New("panicky", 2)
+	_, err := Map(context.Background(), p, []string{"ok", "bad"}, func(_ context.Context, s string) (int, error) {
+		if s == "bad" {
+			panic("kaboom")
+		}
+		return len(s), nil
+	})
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "kaboom" {
+		t.Fatalf("err = %v, want *PanicError(kaboom)", err)
+	}
+	if !strings.Contains(string(pe.Stack), "group_test.go") {
+		t.Fatalf("stack should point at the panicking task:\n%s", pe.Stack)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var panicked, finished int
+	for _, ev := range events {
+		switch ev.Phase {
+		case PhasePanicked:
+			panicked++
+			if ev.Panic != "kaboom" {
+				t.Fatalf("panic event carries %v", ev.Panic)
+			}
+		case PhaseFinished:
+			finished++
+			if ev.Duration <= 0 {
+				t.Fatalf("finished event without a duration: %+v", ev)
+			}
+		}
+	}
+	if panicked != 1 || finished != 2 {
+		t.Fatalf("panicked=%d finished=%d, want 1 and 2", panicked, finished)
+	}
+}
+
+func TestPanicErrorUnwrapsErrorValues(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	g, _ := New("unwrap", 1).Group(context.Background())
+	g.Go(func(context.Context) error { panic(sentinel) })
+	if err := g.Wait(); !errors.Is(err, sentinel) {
+		t.Fatalf("err = %v, want to unwrap to sentinel", err)
+	}
+}
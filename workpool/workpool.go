@@ -8,12 +8,20 @@
 // Semantics:
 //
 //   - New(name, size) returns a pool with `size` worker slots.
+//     Resize(n) changes that at runtime (e.g. on a config reload).
 //   - Submit(task) blocks until a slot is free, then runs task in a
 //     goroutine. SubmitCtx blocks bounded by ctx.
+//   - SubmitKey(ctx, key, task) queues task under key (a host, a
+//     caller); freed slots round-robin between keys, and WithKeyLimit
+//     caps how many tasks one key may run at once, so one tenant's
+//     50k-item batch cannot starve everyone else.
 //   - TrySubmit(task) returns false immediately if no slot is free —
 //     useful for shedding load.
 //   - Wait() blocks until every submitted task has finished.
 //   - Close() prevents further submits and waits for in-flight tasks.
+//   - Group / Map run tasks that return errors or values, with
+//     errgroup-style first-error cancellation and panics recovered
+//     into *PanicError.
 //
 // The pool is intentionally minimal: no priority queue, no
 // per-task timeout, no retries — those are the caller's job. The
@@ -23,48 +31,104 @@ package workpool
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Pool is a bounded-concurrency worker pool. Construct with New;
 // safe for concurrent Submit calls.
 type Pool struct {
-	name string
-	sem  chan struct{}
-	wg   sync.WaitGroup
+	name     string
+	keyLimit int // 0 => keys share the pool freely
+	wg       sync.WaitGroup
 
-	closedMu sync.Mutex
-	closed   bool
+	mu      sync.Mutex
+	size    int
+	closed  bool
+	running int
+	keys    map[string]*keyState
+	// ring lists the keys with queued waiters in round-robin order;
+	// next is the position of the key served next.
+	ring []string
+	next int
 
 	inFlight   atomic.Int64
 	queueDepth atomic.Int64 // callers parked in Submit waiting for a slot
 }
 
+// keyState is one key's share of the pool. Unkeyed submissions use
+// key "". Dropped once the key has nothing running or queued.
+type keyState struct {
+	running int
+	queue   []*waiter
+}
+
+// waiter is one parked Submit. granted is guarded by Pool.mu; ready is
+// closed when it is set.
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
 // ErrClosed is returned by Submit/TrySubmit after Close has been called.
 var ErrClosed = errors.New("workpool: closed")
 
+// Option configures a Pool at construction.
+type Option func(*Pool)
+
+// WithKeyLimit caps how many tasks submitted under one key (SubmitKey)
+// may run at once. n <= 0 means no per-key cap. Unkeyed tasks are not
+// capped.
+func WithKeyLimit(n int) Option {
+	return func(p *Pool) { p.keyLimit = max(n, 0) }
+}
+
 // New constructs a pool with `size` concurrent slots. Name is the
 // pool slug ("crawler", "fanout-resolver") used as a metric label —
 // keep it short and stable.
-func New(name string, size int) *Pool {
+func New(name string, size int, opts ...Option) *Pool {
 	if size <= 0 {
 		size = 1
 	}
 	if name == "" {
 		name = "_unnamed"
 	}
-	return &Pool{
+	p := &Pool{
 		name: name,
-		sem:  make(chan struct{}, size),
+		size: size,
+		keys: make(map[string]*keyState),
+	}
+	for _, o := range opts {
+		o(p)
 	}
+	return p
 }
 
 // Name returns the pool slug.
 func (p *Pool) Name() string { return p.name }
 
 // Size returns the configured concurrency limit.
-func (p *Pool) Size() int { return cap(p.sem) }
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Resize changes the concurrency limit to n (minimum 1). Growing starts
+// queued tasks at once; shrinking lets running tasks finish and holds
+// new ones back until the pool is under the new size. Safe to call at
+// any time, e.g. from a config-reload hook.
+func (p *Pool) Resize(n int) {
+	n = max(n, 1)
+	p.mu.Lock()
+	p.size = n
+	p.dispatchLocked()
+	ev := p.eventLocked(PhaseResized, "")
+	p.mu.Unlock()
+	emit(ev)
+}
 
 // InFlight returns the current number of running tasks.
 func (p *Pool) InFlight() int64 { return p.inFlight.Load() }
@@ -73,6 +137,17 @@ func (p *Pool) InFlight() int64 { return p.inFlight.Load() }
 // waiting for a slot.
 func (p *Pool) QueueDepth() int64 { return p.queueDepth.Load() }
 
+// KeyQueueDepth returns the number of callers blocked in SubmitKey
+// for key.
+func (p *Pool) KeyQueueDepth(key string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ks := p.keys[key]; ks != nil {
+		return int64(len(ks.queue))
+	}
+	return 0
+}
+
 // Submit blocks until a slot is available, then starts task in a
 // goroutine. Returns ErrClosed if the pool has been closed.
 func (p *Pool) Submit(task func()) error {
@@ -82,66 +157,237 @@ func (p *Pool) Submit(task func()) error {
 // SubmitCtx is Submit bounded by ctx. If ctx fires while waiting for
 // a slot, ctx.Err() is returned and task is not started.
 func (p *Pool) SubmitCtx(ctx context.Context, task func()) error {
+	return p.SubmitKey(ctx, "", task)
+}
+
+// SubmitKey is SubmitCtx for a task belonging to key. When the pool is
+// full, waiting tasks are started one key at a time in round-robin
+// order (FIFO within a key), and a key already running its
+// WithKeyLimit share waits even when slots are free. Unkeyed tasks
+// (key "") take part in the round-robin as one more key.
+func (p *Pool) SubmitKey(ctx context.Context, key string, task func()) error {
 	if task == nil {
 		return nil
 	}
-	if p.isClosed() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
 		return ErrClosed
 	}
+	ks := p.keyLocked(key)
+	if len(ks.queue) == 0 && p.fitsLocked(key, ks) {
+		p.grantLocked(ks)
+		p.mu.Unlock()
+		p.start(key, task)
+		return nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(ks.queue) == 0 {
+		p.ring = append(p.ring, key)
+	}
+	ks.queue = append(ks.queue, w)
 	p.queueDepth.Add(1)
-	emit(Event{Pool: p.name, Phase: PhaseQueued, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
+	ev := p.eventLocked(PhaseQueued, key)
+	p.mu.Unlock()
+	emit(ev)
+
 	select {
-	case p.sem <- struct{}{}:
+	case <-w.ready:
 	case <-ctx.Done():
-		p.queueDepth.Add(-1)
-		emit(Event{Pool: p.name, Phase: PhaseCanceled, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
+	}
+
+	p.mu.Lock()
+	if !w.granted {
+		p.dequeueLocked(key, ks, w)
+		ev := p.eventLocked(PhaseCanceled, key)
+		p.mu.Unlock()
+		emit(ev)
 		return ctx.Err()
 	}
-	p.queueDepth.Add(-1)
-	if p.isClosed() {
-		<-p.sem
+	if p.closed {
+		p.releaseLocked(key, ks)
+		p.mu.Unlock()
+		p.wg.Done()
 		return ErrClosed
 	}
-	p.wg.Add(1)
-	p.inFlight.Add(1)
-	emit(Event{Pool: p.name, Phase: PhaseStarted, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
-	go func() {
-		defer func() {
-			<-p.sem
-			p.inFlight.Add(-1)
-			p.wg.Done()
-			emit(Event{Pool: p.name, Phase: PhaseFinished, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
-		}()
-		task()
-	}()
+	p.mu.Unlock()
+	p.start(key, task)
 	return nil
 }
 
 // TrySubmit starts task only if a slot is immediately available.
 // Returns true on success, false if the pool is full or closed.
 func (p *Pool) TrySubmit(task func()) bool {
-	if task == nil || p.isClosed() {
+	return p.TrySubmitKey("", task)
+}
+
+// TrySubmitKey is TrySubmit for a task belonging to key; it also fails
+// when key is at its WithKeyLimit cap or already has tasks queued.
+func (p *Pool) TrySubmitKey(key string, task func()) bool {
+	if task == nil {
 		return false
 	}
-	select {
-	case p.sem <- struct{}{}:
-	default:
-		emit(Event{Pool: p.name, Phase: PhaseShed, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
 		return false
 	}
-	p.wg.Add(1)
-	p.inFlight.Add(1)
-	emit(Event{Pool: p.name, Phase: PhaseStarted, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
+	ks := p.keyLocked(key)
+	if len(ks.queue) > 0 || !p.fitsLocked(key, ks) {
+		ev := p.eventLocked(PhaseShed, key)
+		p.dropKeyLocked(key, ks)
+		p.mu.Unlock()
+		emit(ev)
+		return false
+	}
+	p.grantLocked(ks)
+	p.mu.Unlock()
+	p.start(key, task)
+	return true
+}
+
+// start runs task in its own goroutine in a slot already granted to
+// key, and reports its duration on release.
+func (p *Pool) start(key string, task func()) {
+	p.mu.Lock()
+	ev := p.eventLocked(PhaseStarted, key)
+	p.mu.Unlock()
+	emit(ev)
 	go func() {
+		begin := time.Now()
 		defer func() {
-			<-p.sem
-			p.inFlight.Add(-1)
+			p.mu.Lock()
+			p.releaseLocked(key, p.keys[key])
+			ev := p.eventLocked(PhaseFinished, key)
+			p.mu.Unlock()
+			ev.Duration = time.Since(begin)
 			p.wg.Done()
-			emit(Event{Pool: p.name, Phase: PhaseFinished, InFlight: p.inFlight.Load(), QueueDepth: p.queueDepth.Load()})
+			emit(ev)
 		}()
 		task()
 	}()
-	return true
+}
+
+// keyLocked returns key's state, creating it on first use.
+func (p *Pool) keyLocked(key string) *keyState {
+	ks := p.keys[key]
+	if ks == nil {
+		ks = &keyState{}
+		p.keys[key] = ks
+	}
+	return ks
+}
+
+// dropKeyLocked forgets key once it has nothing running or queued, so
+// a stream of one-off keys (hosts) doesn't grow the map forever.
+func (p *Pool) dropKeyLocked(key string, ks *keyState) {
+	if ks.running == 0 && len(ks.queue) == 0 {
+		delete(p.keys, key)
+	}
+}
+
+// fitsLocked reports whether a task for key may start right now.
+func (p *Pool) fitsLocked(key string, ks *keyState) bool {
+	if p.running >= p.size {
+		return false
+	}
+	return key == "" || p.keyLimit == 0 || ks.running < p.keyLimit
+}
+
+// grantLocked takes a slot for ks. The WaitGroup is bumped here, under
+// mu and before the releasing task's Done, so Wait never sees a zero
+// count while a handed-over slot is between tasks.
+func (p *Pool) grantLocked(ks *keyState) {
+	p.wg.Add(1)
+	p.running++
+	ks.running++
+	p.inFlight.Add(1)
+}
+
+// releaseLocked frees a slot held by key and hands it on.
+func (p *Pool) releaseLocked(key string, ks *keyState) {
+	p.running--
+	ks.running--
+	p.inFlight.Add(-1)
+	p.dispatchLocked()
+	p.dropKeyLocked(key, ks)
+}
+
+// dispatchLocked starts queued waiters while there is room, taking the
+// head of each key's queue in ring order and skipping keys at their
+// cap.
+func (p *Pool) dispatchLocked() {
+	for p.running < p.size && len(p.ring) > 0 {
+		served := false
+		for i := range len(p.ring) {
+			idx := (p.next + i) % len(p.ring)
+			key := p.ring[idx]
+			ks := p.keys[key]
+			if !p.fitsLocked(key, ks) {
+				continue
+			}
+			w := ks.queue[0]
+			ks.queue = ks.queue[1:]
+			p.queueDepth.Add(-1)
+			p.grantLocked(ks)
+			w.granted = true
+			close(w.ready)
+			if len(ks.queue) == 0 {
+				p.ring = slices.Delete(p.ring, idx, idx+1)
+				p.next = idx // the following key slid into idx
+			} else {
+				p.next = idx + 1
+			}
+			served = true
+			break
+		}
+		if !served {
+			break
+		}
+	}
+	if len(p.ring) > 0 {
+		p.next %= len(p.ring)
+	} else {
+		p.next = 0
+	}
+}
+
+// dequeueLocked removes a waiter whose ctx fired before it was granted.
+func (p *Pool) dequeueLocked(key string, ks *keyState, w *waiter) {
+	if i := slices.Index(ks.queue, w); i >= 0 {
+		ks.queue = slices.Delete(ks.queue, i, i+1)
+		p.queueDepth.Add(-1)
+	}
+	if len(ks.queue) == 0 {
+		if i := slices.Index(p.ring, key); i >= 0 {
+			p.ring = slices.Delete(p.ring, i, i+1)
+			if i < p.next {
+				p.next--
+			}
+			if len(p.ring) > 0 {
+				p.next %= len(p.ring)
+			} else {
+				p.next = 0
+			}
+		}
+	}
+	p.dropKeyLocked(key, ks)
+}
+
+// eventLocked snapshots the pool for an observer event about key.
+func (p *Pool) eventLocked(phase Phase, key string) Event {
+	ev := Event{
+		Pool:       p.name,
+		Phase:      phase,
+		Size:       p.size,
+		InFlight:   p.inFlight.Load(),
+		QueueDepth: p.queueDepth.Load(),
+		Key:        key,
+	}
+	if ks := p.keys[key]; ks != nil {
+		ev.KeyQueueDepth = int64(len(ks.queue))
+	}
+	return ev
 }
 
 // Wait blocks until every submitted task has finished. Does NOT close
@@ -153,18 +399,12 @@ func (p *Pool) Wait() {
 // Close prevents further submits and waits for in-flight tasks to
 // finish. Safe to call multiple times.
 func (p *Pool) Close() {
-	p.closedMu.Lock()
+	p.mu.Lock()
 	p.closed = true
-	p.closedMu.Unlock()
+	p.mu.Unlock()
 	p.wg.Wait()
 }
 
-func (p *Pool) isClosed() bool {
-	p.closedMu.Lock()
-	defer p.closedMu.Unlock()
-	return p.closed
-}
-
 // Phase buckets the observer event types.
 type Phase string
 
@@ -174,6 +414,8 @@ const (
 	PhaseFinished Phase = "finished" // a task returned and released its slot
 	PhaseCanceled Phase = "canceled" // a Submit caller's ctx fired before getting a slot
 	PhaseShed     Phase = "shed"     // TrySubmit refused because no slot was free
+	PhasePanicked Phase = "panicked" // a Group/Map task panicked; the panic was recovered into a *PanicError
+	PhaseResized  Phase = "resized"  // Resize changed the pool size; Event.Size is the new value
 )
 
 // Observer receives one event per Submit / TrySubmit lifecycle phase.
@@ -182,12 +424,21 @@ type Observer interface {
 	ObserveWorkpool(Event)
 }
 
-// Event is the per-phase payload handed to an Observer.
+// Event is the per-phase payload handed to an Observer. Size is the
+// pool's current limit. Key is the SubmitKey key ("" for unkeyed
+// tasks) and KeyQueueDepth that key's waiting callers. Duration is the
+// task's run time, set on PhaseFinished. Panic is the recovered value,
+// set on PhasePanicked.
 type Event struct {
-	Pool       string
-	Phase      Phase
-	InFlight   int64
-	QueueDepth int64
+	Pool          string
+	Phase         Phase
+	Size          int
+	InFlight      int64
+	QueueDepth    int64
+	Key           string
+	KeyQueueDepth int64
+	Duration      time.Duration
+	Panic         any
 }
 
 var defaultObserver atomic.Pointer[Observer]
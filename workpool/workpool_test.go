@@ -86,6 +86,123 @@ func TestObserverFires(t *testing.T) {
 	}
 }
 
+func TestResizeGrowsAndShrinks(t *testing.T) {
+	p := New("resize", 1)
+	release := make(chan struct{})
+	var started atomic.Int32
+	for i := 0; i < 3; i++ {
+		go func() {
+			_ = p.Submit(func() { started.Add(1); <-release })
+		}()
+	}
+	waitUntil(t, func() bool { return started.Load() == 1 && p.QueueDepth() == 2 })
+
+	p.Resize(3)
+	waitUntil(t, func() bool { return started.Load() == 3 })
+	if p.Size() != 3 {
+		t.Fatalf("Size = %d, want 3", p.Size())
+	}
+
+	p.Resize(1)
+	if p.TrySubmit(func() {}) {
+		t.Fatal("shrunk pool still running 3 tasks must not admit more")
+	}
+	close(release)
+	p.Wait()
+	if !p.TrySubmit(func() {}) {
+		t.Fatal("drained pool must admit again")
+	}
+	p.Wait()
+}
+
+func TestSubmitKeyRoundRobin(t *testing.T) {
+	p := New("rr", 1)
+	gate := make(chan struct{})
+	_ = p.Submit(func() { <-gate })
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(k string) func() {
+		return func() { mu.Lock(); order = append(order, k); mu.Unlock() }
+	}
+	// A big tenant queues first; a small one arrives later.
+	for i := 0; i < 3; i++ {
+		go func() { _ = p.SubmitKey(context.Background(), "big", record("big")) }()
+		waitUntil(t, func() bool { return p.KeyQueueDepth("big") == int64(i+1) })
+	}
+	go func() { _ = p.SubmitKey(context.Background(), "small", record("small")) }()
+	waitUntil(t, func() bool { return p.KeyQueueDepth("small") == 1 })
+
+	close(gate)
+	p.Wait()
+	waitUntil(t, func() bool { mu.Lock(); defer mu.Unlock(); return len(order) == 4 })
+	mu.Lock()
+	defer mu.Unlock()
+	if order[1] != "small" {
+		t.Fatalf("order = %v; small must not wait behind all of big's backlog", order)
+	}
+}
+
+func TestKeyLimitCapsOneKey(t *testing.T) {
+	p := New("capped", 4, WithKeyLimit(2))
+	release := make(chan struct{})
+	defer func() { close(release); p.Wait() }()
+	for i := 0; i < 2; i++ {
+		if !p.TrySubmitKey("host-a", func() { <-release }) {
+			t.Fatalf("host-a task %d should start", i)
+		}
+	}
+	if p.TrySubmitKey("host-a", func() {}) {
+		t.Fatal("host-a is at its per-key cap")
+	}
+	if !p.TrySubmitKey("host-b", func() { <-release }) {
+		t.Fatal("host-b must still get a free slot")
+	}
+}
+
+func TestObserverSeesKeyQueueDepth(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	SetDefaultObserver(observerFunc(func(ev Event) { mu.Lock(); events = append(events, ev); mu.Unlock() }))
+	defer SetDefaultObserver(nil)
+
+	p := New("keyed-obs", 1)
+	release := make(chan struct{})
+	_ = p.Submit(func() { <-release })
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- p.SubmitKey(ctx, "tenant", func() {}) }()
+	waitUntil(t, func() bool { return p.KeyQueueDepth("tenant") == 1 })
+	cancel()
+	<-errc
+	close(release)
+	p.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ev := range events {
+		if ev.Phase == PhaseQueued && ev.Key == "tenant" && ev.KeyQueueDepth == 1 && ev.Size == 1 {
+			return
+		}
+	}
+	t.Fatalf("no queued event for tenant with KeyQueueDepth=1: %+v", events)
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 2s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 type observerFunc func(Event)
 
 func (f observerFunc) ObserveWorkpool(ev Event) { f(ev) }